@@ -2,10 +2,12 @@ package eventstream
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/9ssi7/bank/internal/domain/account"
 	"github.com/9ssi7/bank/internal/domain/auth"
+	"github.com/9ssi7/bank/internal/domain/schedule"
 	"github.com/9ssi7/bank/internal/domain/user"
 	"github.com/9ssi7/bank/internal/eventhandler"
 	"github.com/9ssi7/bank/internal/infra/eventer"
@@ -15,21 +17,40 @@ import (
 )
 
 type srv struct {
-	eventer eventer.Srv
+	eventer *eventer.Srv
 	cnf     Config
 
 	subs []*nats.Subscription
 }
 
 type Config struct {
-	Eventer eventer.Srv
+	Eventer *eventer.Srv
 	Tracer  trace.Tracer
 
+	// Stream is the JetStream stream the durable consumers are bound to.
+	Stream string
+
+	// Durable prefixes the consumer name of every subject.
+	Durable string
+
+	// MaxDeliver is how many times a message is delivered before it is dead lettered.
+	MaxDeliver int
+
+	// Backoff is the delay before the next delivery of a failed message, by delivery count.
+	// The last value is used once the deliveries outnumber it.
+	Backoff []time.Duration
+
 	AuthHandler    *eventhandler.AuthHandler
 	AccountHandler *eventhandler.AccountHandler
 }
 
 func New(cnf Config) server.Listener {
+	if cnf.MaxDeliver == 0 {
+		cnf.MaxDeliver = 5
+	}
+	if len(cnf.Backoff) == 0 {
+		cnf.Backoff = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second, 2 * time.Minute}
+	}
 	return &srv{
 		eventer: cnf.Eventer,
 		cnf:     cnf,
@@ -42,9 +63,17 @@ func (s *srv) Listen() error {
 	err := s.addSub(
 		ctx,
 		eventHandler{auth.SubjectLoginStarted, s.cnf.AuthHandler.OnLoginStart},
+		eventHandler{auth.SubjectRefreshTokenReused, s.cnf.AuthHandler.OnRefreshTokenReused},
+		eventHandler{auth.SubjectSessionReverify, s.cnf.AuthHandler.OnSessionReverify},
+		eventHandler{auth.SubjectTotpEnrollStarted, s.cnf.AuthHandler.OnTotpEnrollStarted},
+		eventHandler{auth.SubjectTotpEnrolled, s.cnf.AuthHandler.OnTotpEnrolled},
+		eventHandler{auth.SubjectPasskeyStarted, s.cnf.AuthHandler.OnPasskeyStarted},
+		eventHandler{auth.SubjectPasskeyAdded, s.cnf.AuthHandler.OnPasskeyAdded},
 		eventHandler{user.SubjectCreated, s.cnf.AuthHandler.OnUserCreated},
+		eventHandler{account.SubjectTransferPending, s.cnf.AccountHandler.OnTransferPending},
 		eventHandler{account.SubjectTransferIncoming, s.cnf.AccountHandler.OnTransferIncome},
 		eventHandler{account.SubjectTransferOutgoing, s.cnf.AccountHandler.OnTransferOutcome},
+		eventHandler{schedule.SubjectTransferFailed, s.cnf.AccountHandler.OnScheduledTransferFailed},
 	)
 	if err != nil {
 		return err
@@ -54,10 +83,20 @@ func (s *srv) Listen() error {
 
 func (s *srv) Shutdown(ctx context.Context) error {
 	for _, sub := range s.subs {
-		sub.Unsubscribe()
+		// drain keeps the durable consumer on the server, only this subscription goes away
+		sub.Drain()
 	}
-	s.eventer.Disconnect(ctx)
-	return nil
+	// let the handlers in flight ack their messages before the connection is closed
+	for _, sub := range s.subs {
+		for sub.IsValid() {
+			select {
+			case <-ctx.Done():
+				return s.eventer.Disconnect(ctx)
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}
+	return s.eventer.Disconnect(ctx)
 }
 
 type eventHandler struct {
@@ -67,14 +106,30 @@ type eventHandler struct {
 
 func (s *srv) addSub(ctx context.Context, handlers ...eventHandler) error {
 	for _, p := range handlers {
-		sub, err := s.eventer.GetClient().Subscribe(p.subject, func(msg *nats.Msg) {
+		durable := s.durable(p.subject)
+		// the queue group spreads the messages of a durable consumer over every worker instance
+		sub, err := s.eventer.GetClient().QueueSubscribe(p.subject, durable, func(msg *nats.Msg) {
 			ctx, span := s.cnf.Tracer.Start(ctx, msg.Subject, trace.WithTimestamp(time.Now()))
 			defer span.End()
 			err := p.handler(ctx, msg)
-			if err != nil {
+			if err == nil {
+				if err := msg.Ack(); err != nil {
+					span.RecordError(err)
+				}
+				return
+			}
+			span.RecordError(err)
+			if err := s.retry(ctx, msg, err); err != nil {
 				span.RecordError(err)
 			}
-		})
+		},
+			nats.BindStream(s.cnf.Stream),
+			nats.Durable(durable),
+			nats.ManualAck(),
+			nats.AckExplicit(),
+			// one extra delivery gives a message whose dead lettering failed another chance
+			nats.MaxDeliver(s.cnf.MaxDeliver+1),
+		)
 		if err != nil {
 			return err
 		}
@@ -82,3 +137,34 @@ func (s *srv) addSub(ctx context.Context, handlers ...eventHandler) error {
 	}
 	return nil
 }
+
+// retry schedules a failed message for redelivery, or dead letters it on its last delivery.
+func (s *srv) retry(ctx context.Context, msg *nats.Msg, reason error) error {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return err
+	}
+	if meta.NumDelivered >= uint64(s.cnf.MaxDeliver) {
+		if err := s.eventer.DeadLetter(ctx, msg, reason); err != nil {
+			return msg.Nak()
+		}
+		return msg.Term()
+	}
+	return msg.NakWithDelay(s.backoff(meta.NumDelivered))
+}
+
+func (s *srv) backoff(delivered uint64) time.Duration {
+	i := int(delivered) - 1
+	if i >= len(s.cnf.Backoff) {
+		i = len(s.cnf.Backoff) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return s.cnf.Backoff[i]
+}
+
+// durable names the consumer of a subject, consumer names can not contain dots.
+func (s *srv) durable(subject string) string {
+	return s.cnf.Durable + "_" + strings.ReplaceAll(subject, ".", "_")
+}
@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/9ssi7/bank/pkg/server"
+)
+
+// Check reports whether a dependency of the process is usable.
+type Check func(ctx context.Context) error
+
+type srv struct {
+	cnf  Config
+	http *http.Server
+}
+
+type Config struct {
+	Host   string
+	Port   string
+	Checks map[string]Check
+}
+
+// New returns a listener serving /health for liveness and /ready for the checks of the process.
+func New(cnf Config) server.Listener {
+	s := &srv{cnf: cnf}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.health)
+	mux.HandleFunc("/ready", s.ready)
+	s.http = &http.Server{
+		Addr:              net.JoinHostPort(cnf.Host, cnf.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+func (s *srv) Listen() error {
+	if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *srv) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+func (s *srv) health(w http.ResponseWriter, r *http.Request) {
+	s.write(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+}
+
+func (s *srv) ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	status := http.StatusOK
+	checks := make(map[string]string, len(s.cnf.Checks))
+	for name, check := range s.cnf.Checks {
+		if err := check(ctx); err != nil {
+			status = http.StatusServiceUnavailable
+			checks[name] = err.Error()
+			continue
+		}
+		checks[name] = "ok"
+	}
+	s.write(w, status, map[string]interface{}{"status": http.StatusText(status), "checks": checks})
+}
+
+func (s *srv) write(w http.ResponseWriter, status int, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
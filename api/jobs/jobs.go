@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/9ssi7/bank/internal/usecase"
+	"github.com/9ssi7/bank/pkg/server"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type srv struct {
+	cnf    Config
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type Config struct {
+	Tracer trace.Tracer
+
+	OutboxUseCase    *usecase.OutboxUseCase
+	RelayInterval    time.Duration
+	RelayBatchSize   int
+	RelayMaxAttempts int
+
+	ScheduledTransferUseCase *usecase.ScheduledTransferUseCase
+	ScheduleInterval         time.Duration
+	ScheduleBatchSize        int
+
+	AccountUseCase *usecase.AccountUseCase
+	HoldInterval   time.Duration
+	HoldBatchSize  int
+
+	AuthUseCase *usecase.AuthUseCase
+	KeyInterval time.Duration
+}
+
+// New returns a listener running the background jobs of the application on their intervals.
+// Listen blocks until Shutdown is called.
+func New(cnf Config) server.Listener {
+	if cnf.RelayInterval == 0 {
+		cnf.RelayInterval = time.Second
+	}
+	if cnf.RelayBatchSize == 0 {
+		cnf.RelayBatchSize = 100
+	}
+	if cnf.RelayMaxAttempts == 0 {
+		cnf.RelayMaxAttempts = 10
+	}
+	if cnf.ScheduleInterval == 0 {
+		cnf.ScheduleInterval = 30 * time.Second
+	}
+	if cnf.ScheduleBatchSize == 0 {
+		cnf.ScheduleBatchSize = 50
+	}
+	if cnf.HoldInterval == 0 {
+		cnf.HoldInterval = time.Minute
+	}
+	if cnf.HoldBatchSize == 0 {
+		cnf.HoldBatchSize = 100
+	}
+	if cnf.KeyInterval == 0 {
+		cnf.KeyInterval = time.Minute
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &srv{
+		cnf:    cnf,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (s *srv) Listen() error {
+	s.start(
+		job{"Jobs.OutboxRelay", s.cnf.RelayInterval, s.relay},
+		job{"Jobs.ScheduledTransfers", s.cnf.ScheduleInterval, s.runScheduledTransfers},
+		job{"Jobs.ExpiredHolds", s.cnf.HoldInterval, s.releaseExpiredHolds},
+		job{"Jobs.TokenKeys", s.cnf.KeyInterval, s.rotateKeys},
+	)
+	s.wg.Wait()
+	return nil
+}
+
+func (s *srv) Shutdown(ctx context.Context) error {
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+}
+
+func (s *srv) start(jobs ...job) {
+	for _, j := range jobs {
+		s.wg.Add(1)
+		go func(j job) {
+			defer s.wg.Done()
+			ticker := time.NewTicker(j.interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-s.ctx.Done():
+					return
+				case <-ticker.C:
+					s.runOnce(j)
+				}
+			}
+		}(j)
+	}
+}
+
+func (s *srv) runOnce(j job) {
+	ctx, span := s.cnf.Tracer.Start(s.ctx, j.name, trace.WithTimestamp(time.Now()))
+	defer span.End()
+	if err := j.run(ctx); err != nil {
+		span.RecordError(err)
+	}
+}
+
+func (s *srv) relay(ctx context.Context) error {
+	_, err := s.cnf.OutboxUseCase.Relay(ctx, s.cnf.Tracer, usecase.OutboxRelayOpts{Limit: s.cnf.RelayBatchSize, MaxAttempts: s.cnf.RelayMaxAttempts})
+	return err
+}
+
+func (s *srv) runScheduledTransfers(ctx context.Context) error {
+	_, err := s.cnf.ScheduledTransferUseCase.RunDue(ctx, s.cnf.Tracer, usecase.ScheduledTransferRunDueOpts{Limit: s.cnf.ScheduleBatchSize})
+	return err
+}
+
+func (s *srv) releaseExpiredHolds(ctx context.Context) error {
+	_, err := s.cnf.AccountUseCase.ReleaseExpired(ctx, s.cnf.Tracer, usecase.AccountReleaseExpiredOpts{Limit: s.cnf.HoldBatchSize})
+	return err
+}
+
+func (s *srv) rotateKeys(ctx context.Context) error {
+	return s.cnf.AuthUseCase.RotateKeys(ctx, s.cnf.Tracer)
+}
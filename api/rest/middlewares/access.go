@@ -45,7 +45,7 @@ func NewAccessInitialize(authUseCase *usecase.AuthUseCase, trc trace.Tracer, isU
 		if err != nil {
 			return err
 		}
-		c.Locals("user", res.User)
+		c.Locals("user", res)
 		c.Locals("access_token", t)
 		return c.Next()
 	}
@@ -1,17 +1,13 @@
 package middlewares
 
 import (
-	"context"
 	"time"
 
+	"github.com/9ssi7/bank/pkg/state"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
-type ContextKey string
-
-const DeviceIDKey ContextKey = "deviceId"
-
 func NewDeviceId(domain string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		deviceId := c.Cookies("device_id")
@@ -27,7 +23,7 @@ func NewDeviceId(domain string) fiber.Handler {
 		}
 		c.Locals("deviceId", deviceId)
 
-		c.SetUserContext(context.WithValue(c.UserContext(), DeviceIDKey, deviceId))
+		c.SetUserContext(state.SetDeviceId(c.UserContext(), deviceId))
 		return c.Next()
 	}
 }
@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/9ssi7/bank/internal/domain/idempotency"
+	"github.com/9ssi7/bank/internal/usecase"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const idempotencyKeyMaxLen = 255
+
+// NewIdempotency replays the first response of a request sent again with the same
+// Idempotency-Key header. It must run after the access middlewares.
+func NewIdempotency(idempotencyUseCase *usecase.IdempotencyUseCase, trc trace.Tracer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get("Idempotency-Key")
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > idempotencyKeyMaxLen {
+			return idempotency.KeyInvalid(errors.New("idempotency key too long"))
+		}
+		userId := AccessMustParse(c).User.ID
+		hash := idempotencyHash(c)
+		record, err := idempotencyUseCase.Begin(c.UserContext(), trc, usecase.IdempotencyBeginOpts{
+			UserId:   userId,
+			Key:      key,
+			BodyHash: hash,
+		})
+		if err != nil {
+			return err
+		}
+		if record != nil {
+			c.Set("Idempotent-Replayed", "true")
+			if record.ContentType != "" {
+				c.Set(fiber.HeaderContentType, record.ContentType)
+			}
+			return c.Status(record.Status).Send(record.Body)
+		}
+		if err := c.Next(); err != nil || c.Response().StatusCode() >= fiber.StatusInternalServerError {
+			_ = idempotencyUseCase.Release(c.UserContext(), trc, usecase.IdempotencyReleaseOpts{UserId: userId, Key: key})
+			return err
+		}
+		err = idempotencyUseCase.Complete(c.UserContext(), trc, usecase.IdempotencyCompleteOpts{
+			UserId:      userId,
+			Key:         key,
+			BodyHash:    hash,
+			Status:      c.Response().StatusCode(),
+			ContentType: string(c.Response().Header.ContentType()),
+			Body:        append([]byte(nil), c.Response().Body()...),
+		})
+		if err != nil {
+			// The request went through, failing it now would have the client retry a done request.
+			// Complete already retried and shortened the key, retries answer InProgress until it expires.
+			trace.SpanFromContext(c.UserContext()).RecordError(err)
+		}
+		return nil
+	}
+}
+
+func idempotencyHash(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method()))
+	h.Write([]byte(c.Path()))
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -39,7 +39,7 @@ func NewRefreshInitialize(authUseCase *usecase.AuthUseCase, trc trace.Tracer) fi
 		if err != nil {
 			return err
 		}
-		c.Locals("user_refresh", res.User)
+		c.Locals("user_refresh", res)
 		c.Locals("refresh_token", t)
 		return c.Next()
 	}
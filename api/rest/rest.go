@@ -24,8 +24,9 @@ type Server struct {
 	meter         metric.Meter
 	validationSrv *validation.Srv
 
-	authUseCase    *usecase.AuthUseCase
-	accountUseCase *usecase.AccountUseCase
+	authUseCase              *usecase.AuthUseCase
+	accountUseCase           *usecase.AccountUseCase
+	scheduledTransferUseCase *usecase.ScheduledTransferUseCase
 
 	app *fiber.App
 	srv *restsrv.Srv
@@ -52,33 +53,37 @@ type Config struct {
 	Meter         metric.Meter
 	ValidationSrv *validation.Srv
 
-	AuthUseCase    *usecase.AuthUseCase
-	AccountUseCase *usecase.AccountUseCase
+	AuthUseCase              *usecase.AuthUseCase
+	AccountUseCase           *usecase.AccountUseCase
+	IdempotencyUseCase       *usecase.IdempotencyUseCase
+	ScheduledTransferUseCase *usecase.ScheduledTransferUseCase
 }
 
 func New(cnf Config) *Server {
 	restsrv := restsrv.New(restsrv.Config{
-		AuthUseCase:      cnf.AuthUseCase,
-		Tracer:           cnf.Tracer,
-		Locales:          cnf.Locales,
-		TurnstileSecret:  cnf.TurnstileSecret,
-		TurnstileSkip:    cnf.TurnstileSkip,
-		Domain:           cnf.Domain,
-		AllowedMethods:   cnf.AllowedMethods,
-		AllowedHeaders:   cnf.AllowedHeaders,
-		AllowedOrigins:   cnf.AllowedOrigins,
-		ExposeHeaders:    cnf.ExposeHeaders,
-		AllowCredentials: cnf.AllowCredentials,
+		AuthUseCase:        cnf.AuthUseCase,
+		IdempotencyUseCase: cnf.IdempotencyUseCase,
+		Tracer:             cnf.Tracer,
+		Locales:            cnf.Locales,
+		TurnstileSecret:    cnf.TurnstileSecret,
+		TurnstileSkip:      cnf.TurnstileSkip,
+		Domain:             cnf.Domain,
+		AllowedMethods:     cnf.AllowedMethods,
+		AllowedHeaders:     cnf.AllowedHeaders,
+		AllowedOrigins:     cnf.AllowedOrigins,
+		ExposeHeaders:      cnf.ExposeHeaders,
+		AllowCredentials:   cnf.AllowCredentials,
 	})
 	return &Server{
-		host:           cnf.Host,
-		port:           cnf.Port,
-		domain:         cnf.Domain,
-		tracer:         cnf.Tracer,
-		meter:          cnf.Meter,
-		validationSrv:  cnf.ValidationSrv,
-		authUseCase:    cnf.AuthUseCase,
-		accountUseCase: cnf.AccountUseCase,
+		host:                     cnf.Host,
+		port:                     cnf.Port,
+		domain:                   cnf.Domain,
+		tracer:                   cnf.Tracer,
+		meter:                    cnf.Meter,
+		validationSrv:            cnf.ValidationSrv,
+		authUseCase:              cnf.AuthUseCase,
+		accountUseCase:           cnf.AccountUseCase,
+		scheduledTransferUseCase: cnf.ScheduledTransferUseCase,
 		app: fiber.New(fiber.Config{
 			ErrorHandler:   restsrv.ErrorHandler(),
 			AppName:        "banking",
@@ -115,8 +120,15 @@ func (s *Server) Listen() error {
 		AccountUseCase: s.accountUseCase,
 		Rest:           s.srv,
 	}
+	scheduledTransfer := routes.ScheduledTransferRoutes{
+		Tracer:                   s.tracer,
+		ValidationSrv:            s.validationSrv,
+		ScheduledTransferUseCase: s.scheduledTransferUseCase,
+		Rest:                     s.srv,
+	}
 	auth.Register(s.app)
 	account.Register(s.app)
+	scheduledTransfer.Register(s.app)
 	return s.app.Listen(fmt.Sprintf("%v:%v", s.host, s.port))
 }
 
@@ -22,9 +22,10 @@ type Srv struct {
 }
 
 type Config struct {
-	AuthUseCase *usecase.AuthUseCase
-	Tracer      trace.Tracer
-	Locales     []string
+	AuthUseCase        *usecase.AuthUseCase
+	IdempotencyUseCase *usecase.IdempotencyUseCase
+	Tracer             trace.Tracer
+	Locales            []string
 
 	TurnstileSecret string
 	TurnstileSkip   bool
@@ -140,6 +141,10 @@ func (h Srv) RefreshRequired() fiber.Handler {
 	return middlewares.RefreshRequired
 }
 
+func (h Srv) Idempotency() fiber.Handler {
+	return middlewares.NewIdempotency(h.cnf.IdempotencyUseCase, h.cnf.Tracer)
+}
+
 func (h Srv) VerifyTokenRequired() fiber.Handler {
 	return middlewares.VerifyRequired
 }
@@ -1,11 +1,19 @@
 package routes
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/9ssi7/bank/api/rest/middlewares"
 	"github.com/9ssi7/bank/api/rest/restsrv"
 	"github.com/9ssi7/bank/internal/domain/account"
 	"github.com/9ssi7/bank/internal/usecase"
 	"github.com/9ssi7/bank/pkg/list"
+	"github.com/9ssi7/bank/pkg/pain"
+	"github.com/9ssi7/bank/pkg/statement"
 	"github.com/9ssi7/bank/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -26,11 +34,20 @@ func (r *AccountRoutes) Register(router fiber.Router) {
 	group.Patch("/:id/freeze", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.freeze))
 	group.Patch("/:id/suspend", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.Suspent))
 	group.Patch("/:id/lock", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.lock))
-	group.Post("/:id/credit", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.credit))
-	group.Post("/:id/debit", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.debit))
-	group.Post("/:id/transfer", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.transferMoney))
+	group.Post("/:id/credit", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Idempotency(), r.Rest.Timeout(r.credit))
+	group.Post("/:id/debit", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Idempotency(), r.Rest.Timeout(r.debit))
+	group.Post("/:id/holds", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Idempotency(), r.Rest.Timeout(r.authorize))
+	group.Post("/:id/holds/:hold_id/capture", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Idempotency(), r.Rest.Timeout(r.capture))
+	group.Post("/:id/holds/:hold_id/void", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.void))
+	group.Post("/:id/transfer", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Idempotency(), r.Rest.Timeout(r.transferMoney))
+	group.Post("/transfers/confirm", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Idempotency(), r.Rest.Timeout(r.confirmTransfer))
+	group.Post("/:id/transactions/:transaction_id/reverse", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Idempotency(), r.Rest.Timeout(r.reverse))
+	group.Post("/bulk-transfers", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Idempotency(), r.Rest.Timeout(r.bulkTransfer))
+	group.Post("/fee-quote", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.feeQuote))
 	group.Get("/", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.list))
 	group.Get("/:id/transactions", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.listTransactions))
+	group.Get("/:id/limits", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.limits))
+	group.Get("/:id/statement", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.statement))
 }
 
 func (r *AccountRoutes) create(c *fiber.Ctx) error {
@@ -180,6 +197,80 @@ func (r *AccountRoutes) debit(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+func (r *AccountRoutes) authorize(c *fiber.Ctx) error {
+	var req AccountAuthorizeReq
+	if err := c.ParamsParser(&req); err != nil {
+		return err
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	res, err := r.AccountUseCase.Authorize(c.UserContext(), r.Tracer, usecase.AccountAuthorizeOpts{
+		UserId:      claim.User.ID,
+		AccountId:   uuid.MustParse(req.ID),
+		Amount:      req.Amount,
+		Description: req.Description,
+		ExpiresIn:   time.Duration(req.ExpiresInMinutes) * time.Minute,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(res)
+}
+
+func (r *AccountRoutes) capture(c *fiber.Ctx) error {
+	var req AccountCaptureReq
+	if err := c.ParamsParser(&req); err != nil {
+		return err
+	}
+	// without a body the whole held amount is captured
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return err
+		}
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	err := r.AccountUseCase.Capture(c.UserContext(), r.Tracer, usecase.AccountCaptureOpts{
+		UserId:    claim.User.ID,
+		AccountId: uuid.MustParse(req.ID),
+		HoldId:    uuid.MustParse(req.HoldId),
+		UserEmail: claim.Email,
+		UserName:  claim.Name,
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (r *AccountRoutes) void(c *fiber.Ctx) error {
+	var req AccountHoldReq
+	if err := c.ParamsParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	err := r.AccountUseCase.Void(c.UserContext(), r.Tracer, usecase.AccountVoidOpts{
+		UserId:    claim.User.ID,
+		AccountId: uuid.MustParse(req.ID),
+		HoldId:    uuid.MustParse(req.HoldId),
+	})
+	if err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 func (r *AccountRoutes) transferMoney(c *fiber.Ctx) error {
 	var req AccountTransferReq
 	if err := c.BodyParser(&req); err != nil {
@@ -189,7 +280,7 @@ func (r *AccountRoutes) transferMoney(c *fiber.Ctx) error {
 		return err
 	}
 	claim := middlewares.AccessMustParse(c)
-	err := r.AccountUseCase.TransferMoney(c.UserContext(), r.Tracer, usecase.AccountTransferMoneyOpts{
+	verifyToken, err := r.AccountUseCase.TransferMoney(c.UserContext(), r.Tracer, usecase.AccountTransferMoneyOpts{
 		UserId:    claim.User.ID,
 		AccountId: req.AccountId,
 		UserEmail: claim.Email,
@@ -202,9 +293,116 @@ func (r *AccountRoutes) transferMoney(c *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
+	if verifyToken != nil {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"verify_token": *verifyToken})
+	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+func (r *AccountRoutes) confirmTransfer(c *fiber.Ctx) error {
+	var req AccountConfirmTransferReq
+	if err := c.BodyParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	err := r.AccountUseCase.ConfirmTransfer(c.UserContext(), r.Tracer, usecase.AccountConfirmTransferOpts{
+		UserId:      claim.User.ID,
+		VerifyToken: req.VerifyToken,
+		Code:        req.Code,
+	})
+	if err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (r *AccountRoutes) reverse(c *fiber.Ctx) error {
+	var req AccountReverseReq
+	if err := c.ParamsParser(&req); err != nil {
+		return err
+	}
+	// the description is optional, so is the body
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return err
+		}
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	res, err := r.AccountUseCase.Reverse(c.UserContext(), r.Tracer, usecase.AccountReverseOpts{
+		UserId:        claim.User.ID,
+		AccountId:     uuid.MustParse(req.ID),
+		TransactionId: uuid.MustParse(req.TransactionId),
+		Desc:          req.Description,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(res)
+}
+
+// bulkTransfer executes a pain.001 or csv payment file given as the raw body and answers with a pain.002 status report.
+func (r *AccountRoutes) bulkTransfer(c *fiber.Ctx) error {
+	var req AccountBulkTransferReq
+	if err := c.QueryParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	var batch *pain.Batch
+	var err error
+	if req.Format == "csv" {
+		batch, err = pain.ParseCSV(bytes.NewReader(c.Body()), uuid.NewString())
+	} else {
+		batch, err = pain.ParsePain001(bytes.NewReader(c.Body()))
+	}
+	if err != nil {
+		return account.BulkInvalid(err)
+	}
+	claim := middlewares.AccessMustParse(c)
+	statuses, err := r.AccountUseCase.BulkTransfer(c.UserContext(), r.Tracer, usecase.AccountBulkTransferOpts{
+		UserId:    claim.User.ID,
+		UserEmail: claim.Email,
+		UserName:  claim.Name,
+		Batch:     batch,
+		Atomic:    req.Mode == "atomic",
+	})
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationXMLCharsetUTF8)
+	return pain.WritePain002(c, uuid.NewString(), batch, statuses)
+}
+
+func (r *AccountRoutes) feeQuote(c *fiber.Ctx) error {
+	var req AccountFeeQuoteReq
+	if err := c.BodyParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	res, err := r.AccountUseCase.FeeQuote(c.UserContext(), r.Tracer, usecase.AccountFeeQuoteOpts{
+		UserId:    claim.User.ID,
+		AccountId: req.AccountId,
+		Amount:    req.Amount,
+		Kind:      account.TransactionKind(req.Kind),
+		ToIban:    req.ToIban,
+		ToOwner:   req.ToOwner,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
 func (r *AccountRoutes) list(c *fiber.Ctx) error {
 	var pagi list.PagiRequest
 	if err := c.QueryParser(&pagi); err != nil {
@@ -251,3 +449,65 @@ func (r *AccountRoutes) listTransactions(c *fiber.Ctx) error {
 	}
 	return c.Status(fiber.StatusOK).JSON(res)
 }
+
+// statement streams the transactions of a date range, both ends of the range are inclusive days.
+func (r *AccountRoutes) limits(c *fiber.Ctx) error {
+	var req AccountLimitsReq
+	if err := c.ParamsParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	res, err := r.AccountUseCase.Limits(c.UserContext(), r.Tracer, usecase.AccountLimitsOpts{
+		UserId:    claim.User.ID,
+		AccountId: uuid.MustParse(req.ID),
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+func (r *AccountRoutes) statement(c *fiber.Ctx) error {
+	var req AccountStatementReq
+	if err := c.ParamsParser(&req); err != nil {
+		return err
+	}
+	if err := c.QueryParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	from, _ := time.Parse(time.DateOnly, req.StartDate)
+	to, _ := time.Parse(time.DateOnly, req.EndDate)
+	claim := middlewares.AccessMustParse(c)
+	st, err := r.AccountUseCase.Statement(c.UserContext(), r.Tracer, usecase.AccountStatementOpts{
+		UserId:    claim.User.ID,
+		AccountId: uuid.MustParse(req.ID),
+		From:      from,
+		To:        to.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		return err
+	}
+	format := statement.Format(req.Format)
+	c.Set(fiber.HeaderContentType, format.ContentType())
+	c.Attachment(fmt.Sprintf("statement-%s-%s-%s.%s", st.Account.Iban, req.StartDate, req.EndDate, format.Extension()))
+	// the body is written after the handler returns, so the stream must outlive the request timeout
+	ctx := context.WithoutCancel(c.UserContext())
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		err := r.AccountUseCase.WriteStatement(ctx, r.Tracer, usecase.AccountWriteStatementOpts{
+			Statement: st,
+			Format:    format,
+			Writer:    w,
+		})
+		if err != nil {
+			trace.SpanFromContext(ctx).RecordError(err)
+		}
+		w.Flush()
+	})
+	return nil
+}
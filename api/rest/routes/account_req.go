@@ -22,6 +22,24 @@ type AccountDebitReq struct {
 	Amount    string    `json:"amount" validate:"required,amount"`
 }
 
+type AccountAuthorizeReq struct {
+	ID               string `params:"id" validate:"required,uuid"`
+	Amount           string `json:"amount" validate:"required,amount"`
+	Description      string `json:"description" validate:"required,min=3,max=255"`
+	ExpiresInMinutes int    `json:"expires_in_minutes" validate:"omitempty,min=1,max=43200"`
+}
+
+type AccountHoldReq struct {
+	ID     string `params:"id" validate:"required,uuid"`
+	HoldId string `params:"hold_id" validate:"required,uuid"`
+}
+
+type AccountCaptureReq struct {
+	ID     string `params:"id" validate:"required,uuid"`
+	HoldId string `params:"hold_id" validate:"required,uuid"`
+	Amount string `json:"amount" validate:"omitempty,amount"`
+}
+
 type AccountTransferReq struct {
 	AccountId   uuid.UUID `json:"account_id" validate:"required,uuid"`
 	Amount      string    `json:"amount" validate:"required,amount"`
@@ -29,3 +47,38 @@ type AccountTransferReq struct {
 	ToOwner     string    `json:"to_owner" validate:"required,min=3,max=255"`
 	Description string    `json:"description" validate:"required,min=3,max=255"`
 }
+
+type AccountConfirmTransferReq struct {
+	VerifyToken string `json:"verify_token" validate:"required,uuid"`
+	Code        string `json:"code" validate:"required,numeric,len=4"`
+}
+
+type AccountFeeQuoteReq struct {
+	AccountId uuid.UUID `json:"account_id" validate:"required,uuid"`
+	Amount    string    `json:"amount" validate:"required,amount"`
+	Kind      string    `json:"kind" validate:"required,oneof=deposit withdrawal transfer"`
+	ToIban    string    `json:"to_iban" validate:"omitempty,iban"`
+	ToOwner   string    `json:"to_owner" validate:"required_with=ToIban,omitempty,min=3,max=255"`
+}
+
+type AccountReverseReq struct {
+	ID            string `params:"id" validate:"required,uuid"`
+	TransactionId string `params:"transaction_id" validate:"required,uuid"`
+	Description   string `json:"description" validate:"omitempty,min=3,max=255"`
+}
+
+type AccountBulkTransferReq struct {
+	Format string `query:"format" validate:"required,oneof=pain001 csv"`
+	Mode   string `query:"mode" validate:"required,oneof=individual atomic"`
+}
+
+type AccountLimitsReq struct {
+	ID string `params:"id" validate:"required,uuid"`
+}
+
+type AccountStatementReq struct {
+	ID        string `params:"id" validate:"required,uuid"`
+	Format    string `query:"format" validate:"required,oneof=csv ofx camt053"`
+	StartDate string `query:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate   string `query:"end_date" validate:"required,datetime=2006-01-02"`
+}
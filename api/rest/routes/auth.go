@@ -21,15 +21,33 @@ type AuthRoutes struct {
 }
 
 func (r *AuthRoutes) Register(router fiber.Router) {
+	router.Get("/.well-known/jwks.json", r.jwks)
 	group := router.Group("/auth")
 	group.Post("/login/start", r.Rest.VerifyTokenExcluded(), r.Rest.Timeout(r.loginStart))
 	group.Post("/login/verify", r.Rest.AccessInit(), r.Rest.AccessExcluded(), r.Rest.VerifyTokenRequired(), r.Rest.Timeout(r.loginVerify))
 	group.Get("/verify/check", r.Rest.AccessInit(), r.Rest.AccessExcluded(), r.Rest.Timeout(r.loginVerifyCheck))
 	group.Post("/refresh", r.Rest.RefreshInit(), r.Rest.RefreshRequired(), r.Rest.Timeout(r.refreshToken))
 	group.Post("/register", r.Rest.AccessInit(), r.Rest.AccessExcluded(), r.Rest.Turnstile(), r.Rest.Timeout(r.register))
+	group.Post("/totp", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.totpEnroll))
+	group.Post("/totp/confirm", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.totpConfirm))
+	group.Delete("/totp", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.totpDisable))
+	group.Post("/webauthn/register/start", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.webauthnRegisterStart))
+	group.Post("/webauthn/register/finish", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.webauthnRegisterFinish))
+	group.Post("/webauthn/login/start", r.Rest.AccessInit(), r.Rest.AccessExcluded(), r.Rest.Timeout(r.webauthnLoginStart))
+	group.Post("/webauthn/login/finish", r.Rest.AccessInit(), r.Rest.AccessExcluded(), r.Rest.Timeout(r.webauthnLoginFinish))
+	group.Get("/sessions", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.sessions))
+	group.Delete("/sessions", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.revokeOtherSessions))
+	group.Post("/sessions/reverify", r.Rest.Timeout(r.reverifySession))
+	group.Delete("/sessions/:device_id", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.revokeSession))
+	group.Post("/logout", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.logout))
 	group.Post("/registration/:token/verify", r.Rest.AccessInit(), r.Rest.AccessExcluded(), r.Rest.Turnstile(), r.Rest.Timeout(r.registrationVerify))
 }
 
+func (r *AuthRoutes) jwks(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(r.AuthUseCase.Jwks(c.UserContext(), r.Tracer))
+}
+
 func (r *AuthRoutes) loginVerifyCheck(c *fiber.Ctx) error {
 	err := r.AuthUseCase.LoginVerifyCheck(c.UserContext(), r.Tracer, usecase.AuthLoginVerifyCheckOpts{
 		VerifyToken: middlewares.VerifyTokenParse(c),
@@ -67,8 +85,9 @@ func (r *AuthRoutes) loginVerify(c *fiber.Ctx) error {
 	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
 		return err
 	}
-	access, refresh, err := r.AuthUseCase.LoginVerify(c.Context(), r.Tracer, usecase.AuthLoginVerifyOpts{
+	access, refresh, err := r.AuthUseCase.LoginVerify(c.UserContext(), r.Tracer, usecase.AuthLoginVerifyOpts{
 		Code:        req.Code,
+		Factor:      auth.Factor(req.Factor),
 		VerifyToken: middlewares.VerifyTokenParse(c),
 		Device:      r.Rest.MakeDevice(c),
 	})
@@ -84,8 +103,130 @@ func (r *AuthRoutes) loginVerify(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusOK)
 }
 
+func (r *AuthRoutes) totpEnroll(c *fiber.Ctx) error {
+	claim := middlewares.AccessMustParse(c)
+	res, err := r.AuthUseCase.TotpEnroll(c.UserContext(), r.Tracer, usecase.AuthTotpEnrollOpts{
+		UserId: claim.User.ID,
+		Email:  claim.Email,
+		Name:   claim.Name,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(res)
+}
+
+func (r *AuthRoutes) totpConfirm(c *fiber.Ctx) error {
+	var req AuthTotpConfirmReq
+	if err := c.BodyParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	codes, err := r.AuthUseCase.TotpConfirm(c.UserContext(), r.Tracer, usecase.AuthTotpConfirmOpts{
+		UserId:      claim.User.ID,
+		Email:       claim.Email,
+		Name:        claim.Name,
+		VerifyToken: req.VerifyToken,
+		EmailCode:   req.EmailCode,
+		Code:        req.Code,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"recovery_codes": codes})
+}
+
+func (r *AuthRoutes) totpDisable(c *fiber.Ctx) error {
+	var req AuthTotpDisableReq
+	if err := c.BodyParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	err := r.AuthUseCase.TotpDisable(c.UserContext(), r.Tracer, usecase.AuthTotpDisableOpts{
+		UserId: middlewares.AccessMustParse(c).User.ID,
+		Code:   req.Code,
+		Factor: auth.Factor(req.Factor),
+	})
+	if err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (r *AuthRoutes) webauthnRegisterStart(c *fiber.Ctx) error {
+	claim := middlewares.AccessMustParse(c)
+	token, options, err := r.AuthUseCase.WebauthnRegisterStart(c.UserContext(), r.Tracer, usecase.AuthWebauthnRegisterStartOpts{
+		UserId: claim.User.ID,
+		Email:  claim.Email,
+		Name:   claim.Name,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"token": *token, "options": options})
+}
+
+func (r *AuthRoutes) webauthnRegisterFinish(c *fiber.Ctx) error {
+	var req AuthWebauthnRegisterFinishReq
+	if err := c.BodyParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	res, err := r.AuthUseCase.WebauthnRegisterFinish(c.UserContext(), r.Tracer, usecase.AuthWebauthnRegisterFinishOpts{
+		UserId:      claim.User.ID,
+		Email:       claim.Email,
+		Name:        claim.Name,
+		Token:       req.Token,
+		Code:        req.Code,
+		Factor:      auth.Factor(req.Factor),
+		PasskeyName: req.Name,
+		Credential:  req.Credential,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(res)
+}
+
+func (r *AuthRoutes) webauthnLoginStart(c *fiber.Ctx) error {
+	token, options, err := r.AuthUseCase.WebauthnLoginStart(c.UserContext(), r.Tracer)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"token": *token, "options": options})
+}
+
+func (r *AuthRoutes) webauthnLoginFinish(c *fiber.Ctx) error {
+	var req AuthWebauthnLoginFinishReq
+	if err := c.BodyParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	access, refresh, err := r.AuthUseCase.WebauthnLoginFinish(c.UserContext(), r.Tracer, usecase.AuthWebauthnLoginFinishOpts{
+		Token:      req.Token,
+		Credential: req.Credential,
+		Device:     r.Rest.MakeDevice(c),
+	})
+	if err != nil {
+		return err
+	}
+	middlewares.AccessTokenSetCookie(c, *access, r.Domain)
+	middlewares.RefreshTokenSetCookie(c, *refresh, r.Domain)
+	return c.SendStatus(fiber.StatusOK)
+}
+
 func (r *AuthRoutes) refreshToken(c *fiber.Ctx) error {
-	access, err := r.AuthUseCase.RefreshToken(c.UserContext(), r.Tracer, usecase.AuthRefreshTokenOpts{
+	access, refresh, err := r.AuthUseCase.RefreshToken(c.UserContext(), r.Tracer, usecase.AuthRefreshTokenOpts{
 		UserId:     middlewares.RefreshMustParse(c).User.ID,
 		AccessTkn:  middlewares.AccessGetToken(c),
 		RefreshTkn: middlewares.RefreshParseToken(c),
@@ -95,9 +236,83 @@ func (r *AuthRoutes) refreshToken(c *fiber.Ctx) error {
 		return err
 	}
 	middlewares.AccessTokenSetCookie(c, *access, r.Domain)
+	middlewares.RefreshTokenSetCookie(c, *refresh, r.Domain)
 	return c.SendStatus(fiber.StatusOK)
 }
 
+func (r *AuthRoutes) sessions(c *fiber.Ctx) error {
+	claim := middlewares.AccessMustParse(c)
+	res, err := r.AuthUseCase.Sessions(c.UserContext(), r.Tracer, usecase.AuthSessionsOpts{
+		UserId: claim.User.ID,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+func (r *AuthRoutes) reverifySession(c *fiber.Ctx) error {
+	var req AuthReverifySessionReq
+	if err := c.BodyParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	err := r.AuthUseCase.ReverifySession(c.UserContext(), r.Tracer, usecase.AuthReverifySessionOpts{
+		VerifyToken: req.VerifyToken,
+		Code:        req.Code,
+		IpAddr:      middlewares.IpMustParse(c),
+	})
+	if err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (r *AuthRoutes) revokeSession(c *fiber.Ctx) error {
+	var req AuthRevokeSessionReq
+	if err := c.ParamsParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	err := r.AuthUseCase.RevokeSession(c.UserContext(), r.Tracer, usecase.AuthRevokeSessionOpts{
+		UserId:   claim.User.ID,
+		DeviceId: req.DeviceId,
+	})
+	if err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (r *AuthRoutes) revokeOtherSessions(c *fiber.Ctx) error {
+	claim := middlewares.AccessMustParse(c)
+	err := r.AuthUseCase.RevokeOtherSessions(c.UserContext(), r.Tracer, usecase.AuthRevokeOtherSessionsOpts{
+		UserId: claim.User.ID,
+	})
+	if err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (r *AuthRoutes) logout(c *fiber.Ctx) error {
+	claim := middlewares.AccessMustParse(c)
+	err := r.AuthUseCase.Logout(c.UserContext(), r.Tracer, usecase.AuthLogoutOpts{
+		UserId: claim.User.ID,
+	})
+	if err != nil {
+		return err
+	}
+	middlewares.AccessTokenRemoveCookie(c, r.Domain)
+	middlewares.RefreshTokenRemoveCookie(c, r.Domain)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 func (r *AuthRoutes) register(c *fiber.Ctx) error {
 	var req AuthRegisterReq
 	if err := c.BodyParser(&req); err != nil {
@@ -1,11 +1,27 @@
 package routes
 
+import "github.com/9ssi7/bank/pkg/webauthn"
+
 type AuthLoginStartReq struct {
 	Email string `json:"email" validate:"required,email"`
 }
 
+// Code is the emailed 4 digit code, or for the totp and recovery factors a code of the app or a recovery code.
 type AuthLoginReq struct {
-	Code string `json:"code" validate:"required,numeric,len=4"`
+	Code   string `json:"code" validate:"required,min=4,max=11"`
+	Factor string `json:"factor" validate:"omitempty,oneof=email totp recovery"`
+}
+
+// EmailCode is the code TotpEnroll emailed, Code a code of the app.
+type AuthTotpConfirmReq struct {
+	VerifyToken string `json:"verify_token" validate:"required,uuid"`
+	EmailCode   string `json:"email_code" validate:"required,numeric,len=4"`
+	Code        string `json:"code" validate:"required,numeric,len=6"`
+}
+
+type AuthTotpDisableReq struct {
+	Code   string `json:"code" validate:"required,min=6,max=11"`
+	Factor string `json:"factor" validate:"required,oneof=totp recovery"`
 }
 
 type AuthRegisterReq struct {
@@ -16,3 +32,26 @@ type AuthRegisterReq struct {
 type AuthRegistrationVerifyReq struct {
 	Token string `params:"token" validate:"required,uuid"`
 }
+
+type AuthReverifySessionReq struct {
+	VerifyToken string `json:"verify_token" validate:"required,uuid"`
+	Code        string `json:"code" validate:"required,numeric,len=4"`
+}
+
+type AuthRevokeSessionReq struct {
+	DeviceId string `params:"device_id" validate:"required,uuid"`
+}
+
+// Code is the code emailed for the registration, or for the totp and recovery factors a code of the app or a recovery code.
+type AuthWebauthnRegisterFinishReq struct {
+	Token      string                         `json:"token" validate:"required,uuid"`
+	Code       string                         `json:"code" validate:"required,min=4,max=11"`
+	Factor     string                         `json:"factor" validate:"omitempty,oneof=email totp recovery"`
+	Name       string                         `json:"name" validate:"required,min=1,max=255"`
+	Credential *webauthn.RegistrationResponse `json:"credential" validate:"required"`
+}
+
+type AuthWebauthnLoginFinishReq struct {
+	Token      string                      `json:"token" validate:"required,uuid"`
+	Credential *webauthn.AssertionResponse `json:"credential" validate:"required"`
+}
@@ -0,0 +1,158 @@
+package routes
+
+import (
+	"github.com/9ssi7/bank/api/rest/middlewares"
+	"github.com/9ssi7/bank/api/rest/restsrv"
+	"github.com/9ssi7/bank/internal/usecase"
+	"github.com/9ssi7/bank/pkg/list"
+	"github.com/9ssi7/bank/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ScheduledTransferRoutes struct {
+	Tracer                   trace.Tracer
+	ValidationSrv            *validation.Srv
+	ScheduledTransferUseCase *usecase.ScheduledTransferUseCase
+	Rest                     *restsrv.Srv
+}
+
+func (r *ScheduledTransferRoutes) Register(router fiber.Router) {
+	group := router.Group("/accounts/:id/scheduled-transfers")
+	group.Post("/", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.create))
+	group.Get("/", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.list))
+	group.Get("/:transfer_id", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.detail))
+	group.Put("/:transfer_id", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.update))
+	group.Delete("/:transfer_id", r.Rest.AccessInit(), r.Rest.AccessRequired(), r.Rest.Timeout(r.cancel))
+}
+
+func (r *ScheduledTransferRoutes) create(c *fiber.Ctx) error {
+	var params ScheduledTransferAccountReq
+	if err := c.ParamsParser(&params); err != nil {
+		return err
+	}
+	var req ScheduledTransferReq
+	if err := c.BodyParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &params); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	res, err := r.ScheduledTransferUseCase.Create(c.UserContext(), r.Tracer, usecase.ScheduledTransferCreateOpts{
+		UserId:    claim.User.ID,
+		AccountId: uuid.MustParse(params.AccountId),
+		Amount:    req.Amount,
+		ToIban:    req.ToIban,
+		ToOwner:   req.ToOwner,
+		Desc:      req.Description,
+		Cron:      req.Cron,
+		RunAt:     req.RunAt,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(res)
+}
+
+func (r *ScheduledTransferRoutes) list(c *fiber.Ctx) error {
+	var pagi list.PagiRequest
+	if err := c.QueryParser(&pagi); err != nil {
+		return err
+	}
+	pagi.Default()
+	var params ScheduledTransferAccountReq
+	if err := c.ParamsParser(&params); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &params); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	res, err := r.ScheduledTransferUseCase.List(c.UserContext(), r.Tracer, usecase.ScheduledTransferListOpts{
+		UserId:    claim.User.ID,
+		AccountId: uuid.MustParse(params.AccountId),
+		Pagi:      pagi,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+func (r *ScheduledTransferRoutes) detail(c *fiber.Ctx) error {
+	var params ScheduledTransferDetailReq
+	if err := c.ParamsParser(&params); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &params); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	res, err := r.ScheduledTransferUseCase.Get(c.UserContext(), r.Tracer, usecase.ScheduledTransferGetOpts{
+		UserId:    claim.User.ID,
+		AccountId: uuid.MustParse(params.AccountId),
+		ID:        uuid.MustParse(params.ID),
+	})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+func (r *ScheduledTransferRoutes) update(c *fiber.Ctx) error {
+	var params ScheduledTransferDetailReq
+	if err := c.ParamsParser(&params); err != nil {
+		return err
+	}
+	var req ScheduledTransferReq
+	if err := c.BodyParser(&req); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &params); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &req); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	err := r.ScheduledTransferUseCase.Update(c.UserContext(), r.Tracer, usecase.ScheduledTransferUpdateOpts{
+		UserId:    claim.User.ID,
+		AccountId: uuid.MustParse(params.AccountId),
+		ID:        uuid.MustParse(params.ID),
+		Amount:    req.Amount,
+		ToIban:    req.ToIban,
+		ToOwner:   req.ToOwner,
+		Desc:      req.Description,
+		Cron:      req.Cron,
+		RunAt:     req.RunAt,
+	})
+	if err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (r *ScheduledTransferRoutes) cancel(c *fiber.Ctx) error {
+	var params ScheduledTransferDetailReq
+	if err := c.ParamsParser(&params); err != nil {
+		return err
+	}
+	if err := r.ValidationSrv.ValidateStruct(c.UserContext(), &params); err != nil {
+		return err
+	}
+	claim := middlewares.AccessMustParse(c)
+	err := r.ScheduledTransferUseCase.Cancel(c.UserContext(), r.Tracer, usecase.ScheduledTransferCancelOpts{
+		UserId:    claim.User.ID,
+		AccountId: uuid.MustParse(params.AccountId),
+		ID:        uuid.MustParse(params.ID),
+	})
+	if err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import "time"
+
+type ScheduledTransferAccountReq struct {
+	AccountId string `params:"id" validate:"required,uuid"`
+}
+
+type ScheduledTransferDetailReq struct {
+	AccountId string `params:"id" validate:"required,uuid"`
+	ID        string `params:"transfer_id" validate:"required,uuid"`
+}
+
+// ScheduledTransferReq needs a cron expression for standing orders, a run_at for one off transfers, or both
+// to start a standing order at a later date.
+type ScheduledTransferReq struct {
+	Amount      string     `json:"amount" validate:"required,amount"`
+	ToIban      string     `json:"to_iban" validate:"required,iban"`
+	ToOwner     string     `json:"to_owner" validate:"required,min=3,max=255"`
+	Description string     `json:"description" validate:"required,min=3,max=255"`
+	Cron        string     `json:"cron" validate:"required_without=RunAt,omitempty,max=255"`
+	RunAt       *time.Time `json:"run_at" validate:"required_without=Cron"`
+}
@@ -2,21 +2,24 @@ package middlewares
 
 import (
 	"context"
-	"errors"
-	"strings"
 
+	"github.com/9ssi7/bank/api/rpc/rpcres"
 	"github.com/9ssi7/bank/internal/usecase"
+	"github.com/9ssi7/bank/pkg/state"
+	"github.com/9ssi7/bank/pkg/token"
 	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors"
 	"go.opentelemetry.io/otel/trace"
-	"google.golang.org/grpc/metadata"
 )
 
+const userKey state.ContextKey = "user"
+
 type MatcherFunc func(ctx context.Context, callMeta interceptors.CallMeta) bool
 
+// NewAccessMatcher matches the calls of the protected routes, given as the full methods of the actions.
 func NewAccessMatcher(protectedRoutes []string) MatcherFunc {
 	return func(ctx context.Context, callMeta interceptors.CallMeta) bool {
-		fm := strings.ToLower(callMeta.FullMethod())
+		fm := callMeta.FullMethod()
 		for _, pr := range protectedRoutes {
 			if fm == pr {
 				return true
@@ -26,17 +29,19 @@ func NewAccessMatcher(protectedRoutes []string) MatcherFunc {
 	}
 }
 
+// NewAccessGuard verifies the bearer token of the call and puts the claim of the caller into the context,
+// the handlers of the protected routes take it with AccessMustParse.
 func NewAccessGuard(authUseCase *usecase.AuthUseCase, trc trace.Tracer) grpc_auth.AuthFunc {
 	return func(ctx context.Context) (context.Context, error) {
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, errors.New("metadata not found")
-		}
-		token, err := grpc_auth.AuthFromMD(ctx, "bearer")
+		claim, err := VerifyAccess(ctx, authUseCase, trc)
 		if err != nil {
-			return nil, err
+			return nil, rpcres.Error(err)
 		}
-		md.Set("token", token)
-		return metadata.NewIncomingContext(ctx, md), nil
+		return context.WithValue(ctx, userKey, claim), nil
 	}
 }
+
+// AccessMustParse returns the claim the access guard put into the context, it panics on unprotected routes.
+func AccessMustParse(ctx context.Context) *token.UserClaim {
+	return ctx.Value(userKey).(*token.UserClaim)
+}
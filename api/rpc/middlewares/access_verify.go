@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"context"
+	"net"
+
+	"github.com/9ssi7/bank/internal/domain/auth"
+	"github.com/9ssi7/bank/internal/usecase"
+	"github.com/9ssi7/bank/pkg/token"
+	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/peer"
+)
+
+// VerifyAccess checks the bearer token of the call against the session of the device
+// given by the device_id metadata and returns the claim of the caller.
+func VerifyAccess(ctx context.Context, authUseCase *usecase.AuthUseCase, trc trace.Tracer) (*token.UserClaim, error) {
+	t, err := grpc_auth.AuthFromMD(ctx, "bearer")
+	if err != nil {
+		return nil, auth.Unauthorized(err)
+	}
+	return authUseCase.VerifyAccess(ctx, trc, usecase.AuthVerifyAccessOpts{
+		AccessTkn: t,
+		IpAddr:    PeerIp(ctx),
+	})
+}
+
+// PeerIp returns the ip address of the caller, without the port.
+func PeerIp(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -9,21 +9,27 @@ import (
 	"google.golang.org/grpc/metadata"
 )
 
+// NewDeviceId puts the device_id metadata of the call into the context, where the use cases look for it.
+// A call without a valid one gets a new id, sent back in the device_id header for the client to keep.
 func NewDeviceId() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		md, ok := metadata.FromIncomingContext(ctx)
 		if !ok {
 			md = metadata.New(nil)
 		}
-		already := md.Get("device_id")
-		if already == nil {
-			id := uuid.New().String()
-			newMd := metadata.Pairs("device_id", id)
-			ctx = metadata.NewIncomingContext(ctx, newMd)
-			state.SetDeviceId(ctx, id)
-		} else {
-			state.SetDeviceId(ctx, already[0])
+		var deviceId string
+		if ids := md.Get("device_id"); len(ids) > 0 {
+			deviceId = ids[0]
 		}
-		return handler(ctx, req)
+		if _, err := uuid.Parse(deviceId); err != nil {
+			deviceId = uuid.New().String()
+			md = md.Copy()
+			md.Set("device_id", deviceId)
+			ctx = metadata.NewIncomingContext(ctx, md)
+			if err := grpc.SetHeader(ctx, metadata.Pairs("device_id", deviceId)); err != nil {
+				return nil, err
+			}
+		}
+		return handler(state.SetDeviceId(ctx, deviceId), req)
 	}
 }
@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"context"
+
+	accountpb "github.com/9ssi7/bank/api/rpc/generated/account/v1"
+	"github.com/9ssi7/bank/api/rpc/middlewares"
+	"github.com/9ssi7/bank/api/rpc/rpcres"
+	"github.com/9ssi7/bank/internal/usecase"
+	"github.com/9ssi7/bank/pkg/validation"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+type AccountRoutes struct {
+	accountpb.UnimplementedAccountServer
+	Tracer         trace.Tracer
+	ValidationSrv  *validation.Srv
+	AuthUseCase    *usecase.AuthUseCase
+	AccountUseCase *usecase.AccountUseCase
+}
+
+type accountReverseReq struct {
+	AccountId     string `validate:"required,uuid"`
+	TransactionId string `validate:"required,uuid"`
+	Description   string `validate:"omitempty,min=3,max=255"`
+}
+
+func (r *AccountRoutes) ProtectedRoutes() []string {
+	return protectedActions(accountpb.Account_ServiceDesc.ServiceName, "Reverse")
+}
+
+func (r *AccountRoutes) RegisterRouter(s *grpc.Server) {
+	accountpb.RegisterAccountServer(s, r)
+}
+
+func (r *AccountRoutes) Reverse(ctx context.Context, req *accountpb.ReverseRequest) (*accountpb.ReverseResponse, error) {
+	claim := middlewares.AccessMustParse(ctx)
+	v := accountReverseReq{AccountId: req.AccountId, TransactionId: req.TransactionId, Description: req.Description}
+	if err := r.ValidationSrv.ValidateStruct(ctx, &v); err != nil {
+		return nil, rpcres.Error(err)
+	}
+	res, err := r.AccountUseCase.Reverse(ctx, r.Tracer, usecase.AccountReverseOpts{
+		UserId:        claim.User.ID,
+		AccountId:     uuid.MustParse(req.AccountId),
+		TransactionId: uuid.MustParse(req.TransactionId),
+		Desc:          req.Description,
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	return &accountpb.ReverseResponse{
+		ReversalId: res.String(),
+	}, nil
+}
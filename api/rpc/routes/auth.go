@@ -2,12 +2,16 @@ package routes
 
 import (
 	"context"
+	"time"
 
 	authpb "github.com/9ssi7/bank/api/rpc/generated/auth/v1"
+	"github.com/9ssi7/bank/api/rpc/middlewares"
 	"github.com/9ssi7/bank/api/rpc/rpcres"
+	"github.com/9ssi7/bank/internal/domain/auth"
 	"github.com/9ssi7/bank/internal/usecase"
 	"github.com/9ssi7/bank/pkg/agent"
 	"github.com/9ssi7/bank/pkg/validation"
+	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 )
@@ -21,21 +25,27 @@ type AuthRoutes struct {
 }
 
 func (r *AuthRoutes) ProtectedRoutes() []string {
-	return protectedActions(authpb.Auth_ServiceDesc.ServiceName, "RefreshToken")
+	return protectedActions(authpb.Auth_ServiceDesc.ServiceName, "TotpEnroll", "TotpConfirm", "TotpDisable", "ListSessions", "RevokeSession", "RevokeOtherSessions", "Logout")
 }
 
 func (r *AuthRoutes) RegisterRouter(s *grpc.Server) {
 	authpb.RegisterAuthServer(s, r)
 }
 
+// LoginStart opens the login of the device given by the device_id metadata, the session is bound
+// to the address the call comes from rather than the one the device reports.
 func (r *AuthRoutes) LoginStart(ctx context.Context, req *authpb.LoginStartRequest) (*authpb.LoginStartResponse, error) {
+	v := authLoginStartReq{Email: req.Email}
+	if err := r.ValidationSrv.ValidateStruct(ctx, &v); err != nil {
+		return nil, rpcres.Error(err)
+	}
 	res, err := r.AuthUseCase.LoginStart(ctx, r.Tracer, usecase.AuthLoginStartOpts{
 		Email: req.Email,
 		Device: agent.Device{
-			Name: req.Device.Name,
-			Type: req.Device.Type,
-			OS:   req.Device.Os,
-			IP:   req.Device.Ip,
+			Name: req.GetDevice().GetName(),
+			Type: req.GetDevice().GetType(),
+			OS:   req.GetDevice().GetOs(),
+			IP:   middlewares.PeerIp(ctx),
 		},
 	})
 	if err != nil {
@@ -46,12 +56,271 @@ func (r *AuthRoutes) LoginStart(ctx context.Context, req *authpb.LoginStartReque
 	}, nil
 }
 
+type authLoginStartReq struct {
+	Email string `validate:"required,email"`
+}
+
+type authLoginVerifyReq struct {
+	Token  string `validate:"required,uuid"`
+	Code   string `validate:"required,min=4,max=11"`
+	Factor string `validate:"omitempty,oneof=email totp recovery"`
+}
+
+type authRefreshTokenReq struct {
+	RefreshToken string `validate:"required"`
+}
+
+type authRegisterReq struct {
+	Name  string `validate:"required"`
+	Email string `validate:"required,email"`
+}
+
+type authRegistrationVerifyReq struct {
+	Token string `validate:"required,uuid"`
+}
+
+type authTotpConfirmReq struct {
+	VerifyToken string `validate:"required,uuid"`
+	EmailCode   string `validate:"required,numeric,len=4"`
+	Code        string `validate:"required,numeric,len=6"`
+}
+
+type authTotpDisableReq struct {
+	Code   string `validate:"required,min=6,max=11"`
+	Factor string `validate:"required,oneof=totp recovery"`
+}
+
+type authReverifySessionReq struct {
+	VerifyToken string `validate:"required,uuid"`
+	Code        string `validate:"required,numeric,len=4"`
+}
+
+type authRevokeSessionReq struct {
+	DeviceId string `validate:"required,uuid"`
+}
+
+// LoginVerify opens the session of the device the way LoginStart does, bound to the address of the call.
 func (r *AuthRoutes) LoginVerify(ctx context.Context, req *authpb.LoginVerifyRequest) (*authpb.LoginVerifyResponse, error) {
-	err := r.AuthUseCase.LoginVerifyCheck(ctx, r.Tracer, usecase.AuthLoginVerifyCheckOpts{
+	v := authLoginVerifyReq{Token: req.Token, Code: req.Code, Factor: req.Factor}
+	if err := r.ValidationSrv.ValidateStruct(ctx, &v); err != nil {
+		return nil, rpcres.Error(err)
+	}
+	access, refresh, err := r.AuthUseCase.LoginVerify(ctx, r.Tracer, usecase.AuthLoginVerifyOpts{
+		Code:        req.Code,
+		Factor:      auth.Factor(req.Factor),
 		VerifyToken: req.Token,
+		Device: agent.Device{
+			Name: req.GetDevice().GetName(),
+			Type: req.GetDevice().GetType(),
+			OS:   req.GetDevice().GetOs(),
+			IP:   middlewares.PeerIp(ctx),
+		},
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	return &authpb.LoginVerifyResponse{
+		AccessToken:  *access,
+		RefreshToken: *refresh,
+	}, nil
+}
+
+// RefreshToken rotates the tokens of the session, the access token is read from the bearer
+// of the call and may have expired already.
+func (r *AuthRoutes) RefreshToken(ctx context.Context, req *authpb.RefreshTokenRequest) (*authpb.RefreshTokenResponse, error) {
+	v := authRefreshTokenReq{RefreshToken: req.RefreshToken}
+	if err := r.ValidationSrv.ValidateStruct(ctx, &v); err != nil {
+		return nil, rpcres.Error(err)
+	}
+	accessTkn, err := grpc_auth.AuthFromMD(ctx, "bearer")
+	if err != nil {
+		return nil, rpcres.Error(auth.Unauthorized(err))
+	}
+	ip := middlewares.PeerIp(ctx)
+	claim, err := r.AuthUseCase.VerifyRefresh(ctx, r.Tracer, &usecase.AuthVerifyRefreshOpts{
+		AccessTkn:  accessTkn,
+		RefreshTkn: req.RefreshToken,
+		IpAddr:     ip,
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	access, refresh, err := r.AuthUseCase.RefreshToken(ctx, r.Tracer, usecase.AuthRefreshTokenOpts{
+		UserId:     claim.User.ID,
+		AccessTkn:  accessTkn,
+		RefreshTkn: req.RefreshToken,
+		IpAddr:     ip,
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	return &authpb.RefreshTokenResponse{
+		AccessToken:  *access,
+		RefreshToken: *refresh,
+	}, nil
+}
+
+func (r *AuthRoutes) Register(ctx context.Context, req *authpb.RegisterRequest) (*authpb.RegisterResponse, error) {
+	v := authRegisterReq{Name: req.Name, Email: req.Email}
+	if err := r.ValidationSrv.ValidateStruct(ctx, &v); err != nil {
+		return nil, rpcres.Error(err)
+	}
+	err := r.AuthUseCase.Register(ctx, r.Tracer, usecase.AuthRegisterOpts{
+		Name:  req.Name,
+		Email: req.Email,
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	return &authpb.RegisterResponse{}, nil
+}
+
+func (r *AuthRoutes) RegistrationVerify(ctx context.Context, req *authpb.RegistrationVerifyRequest) (*authpb.RegistrationVerifyResponse, error) {
+	v := authRegistrationVerifyReq{Token: req.Token}
+	if err := r.ValidationSrv.ValidateStruct(ctx, &v); err != nil {
+		return nil, rpcres.Error(err)
+	}
+	err := r.AuthUseCase.RegistrationVerify(ctx, r.Tracer, usecase.AuthRegistrationVerifyOpts{
+		Token: req.Token,
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	return &authpb.RegistrationVerifyResponse{}, nil
+}
+
+func (r *AuthRoutes) TotpEnroll(ctx context.Context, req *authpb.TotpEnrollRequest) (*authpb.TotpEnrollResponse, error) {
+	claim := middlewares.AccessMustParse(ctx)
+	res, err := r.AuthUseCase.TotpEnroll(ctx, r.Tracer, usecase.AuthTotpEnrollOpts{
+		UserId: claim.User.ID,
+		Email:  claim.Email,
+		Name:   claim.Name,
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	return &authpb.TotpEnrollResponse{
+		Secret:      res.Secret,
+		Uri:         res.Uri,
+		VerifyToken: res.VerifyToken,
+	}, nil
+}
+
+func (r *AuthRoutes) TotpConfirm(ctx context.Context, req *authpb.TotpConfirmRequest) (*authpb.TotpConfirmResponse, error) {
+	claim := middlewares.AccessMustParse(ctx)
+	v := authTotpConfirmReq{VerifyToken: req.VerifyToken, EmailCode: req.EmailCode, Code: req.Code}
+	if err := r.ValidationSrv.ValidateStruct(ctx, &v); err != nil {
+		return nil, rpcres.Error(err)
+	}
+	codes, err := r.AuthUseCase.TotpConfirm(ctx, r.Tracer, usecase.AuthTotpConfirmOpts{
+		UserId:      claim.User.ID,
+		Email:       claim.Email,
+		Name:        claim.Name,
+		VerifyToken: req.VerifyToken,
+		EmailCode:   req.EmailCode,
+		Code:        req.Code,
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	return &authpb.TotpConfirmResponse{
+		RecoveryCodes: codes,
+	}, nil
+}
+
+func (r *AuthRoutes) TotpDisable(ctx context.Context, req *authpb.TotpDisableRequest) (*authpb.TotpDisableResponse, error) {
+	claim := middlewares.AccessMustParse(ctx)
+	v := authTotpDisableReq{Code: req.Code, Factor: req.Factor}
+	if err := r.ValidationSrv.ValidateStruct(ctx, &v); err != nil {
+		return nil, rpcres.Error(err)
+	}
+	err := r.AuthUseCase.TotpDisable(ctx, r.Tracer, usecase.AuthTotpDisableOpts{
+		UserId: claim.User.ID,
+		Code:   req.Code,
+		Factor: auth.Factor(req.Factor),
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	return &authpb.TotpDisableResponse{}, nil
+}
+
+func (r *AuthRoutes) ListSessions(ctx context.Context, req *authpb.ListSessionsRequest) (*authpb.ListSessionsResponse, error) {
+	claim := middlewares.AccessMustParse(ctx)
+	res, err := r.AuthUseCase.Sessions(ctx, r.Tracer, usecase.AuthSessionsOpts{
+		UserId: claim.User.ID,
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	sessions := make([]*authpb.Session, 0, len(res))
+	for _, s := range res {
+		sessions = append(sessions, &authpb.Session{
+			DeviceId:   s.DeviceId,
+			DeviceName: s.DeviceName,
+			DeviceType: s.DeviceType,
+			DeviceOs:   s.DeviceOS,
+			IpAddress:  s.IpAddress,
+			LastLogin:  s.LastLogin.Format(time.RFC3339),
+			CreatedAt:  s.CreatedAt.Format(time.RFC3339),
+			Current:    s.Current,
+		})
+	}
+	return &authpb.ListSessionsResponse{
+		Sessions: sessions,
+	}, nil
+}
+
+func (r *AuthRoutes) RevokeSession(ctx context.Context, req *authpb.RevokeSessionRequest) (*authpb.RevokeSessionResponse, error) {
+	claim := middlewares.AccessMustParse(ctx)
+	v := authRevokeSessionReq{DeviceId: req.DeviceId}
+	if err := r.ValidationSrv.ValidateStruct(ctx, &v); err != nil {
+		return nil, rpcres.Error(err)
+	}
+	err := r.AuthUseCase.RevokeSession(ctx, r.Tracer, usecase.AuthRevokeSessionOpts{
+		UserId:   claim.User.ID,
+		DeviceId: req.DeviceId,
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	return &authpb.RevokeSessionResponse{}, nil
+}
+
+func (r *AuthRoutes) RevokeOtherSessions(ctx context.Context, req *authpb.RevokeOtherSessionsRequest) (*authpb.RevokeOtherSessionsResponse, error) {
+	claim := middlewares.AccessMustParse(ctx)
+	err := r.AuthUseCase.RevokeOtherSessions(ctx, r.Tracer, usecase.AuthRevokeOtherSessionsOpts{
+		UserId: claim.User.ID,
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	return &authpb.RevokeOtherSessionsResponse{}, nil
+}
+
+func (r *AuthRoutes) ReverifySession(ctx context.Context, req *authpb.ReverifySessionRequest) (*authpb.ReverifySessionResponse, error) {
+	v := authReverifySessionReq{VerifyToken: req.VerifyToken, Code: req.Code}
+	if err := r.ValidationSrv.ValidateStruct(ctx, &v); err != nil {
+		return nil, rpcres.Error(err)
+	}
+	err := r.AuthUseCase.ReverifySession(ctx, r.Tracer, usecase.AuthReverifySessionOpts{
+		VerifyToken: req.VerifyToken,
+		Code:        req.Code,
+		IpAddr:      middlewares.PeerIp(ctx),
+	})
+	if err != nil {
+		return nil, rpcres.Error(err)
+	}
+	return &authpb.ReverifySessionResponse{}, nil
+}
+
+func (r *AuthRoutes) Logout(ctx context.Context, req *authpb.LogoutRequest) (*authpb.LogoutResponse, error) {
+	claim := middlewares.AccessMustParse(ctx)
+	err := r.AuthUseCase.Logout(ctx, r.Tracer, usecase.AuthLogoutOpts{
+		UserId: claim.User.ID,
 	})
 	if err != nil {
 		return nil, rpcres.Error(err)
 	}
-	return &authpb.LoginVerifyResponse{}, nil
+	return &authpb.LogoutResponse{}, nil
 }
@@ -2,10 +2,11 @@ package routes
 
 import "fmt"
 
+// protectedActions gives the full methods of the actions of the service, as the access matcher sees them.
 func protectedActions(srv string, a ...string) []string {
 	var actions []string
 	for _, method := range a {
-		actions = append(actions, fmt.Sprintf("%s/%s", srv, method))
+		actions = append(actions, fmt.Sprintf("/%s/%s", srv, method))
 	}
 	return actions
 }
@@ -10,9 +10,11 @@ import (
 	"github.com/9ssi7/bank/internal/usecase"
 	"github.com/9ssi7/bank/pkg/validation"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
 	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/selector"
 
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/otel/metric"
@@ -65,6 +67,19 @@ func (s *Server) Listen() error {
 		return err
 	}
 	fmt.Printf("rpc server listening on port %s\n", s.port)
+	auth := routes.AuthRoutes{
+		Tracer:        s.t,
+		ValidationSrv: s.validationSrv,
+		AuthUseCase:   s.authUseCase,
+		Domain:        s.domain,
+	}
+	account := routes.AccountRoutes{
+		Tracer:         s.t,
+		ValidationSrv:  s.validationSrv,
+		AuthUseCase:    s.authUseCase,
+		AccountUseCase: s.accountUseCase,
+	}
+	protected := append(auth.ProtectedRoutes(), account.ProtectedRoutes()...)
 	s.srv = grpc.NewServer(
 		grpc.StatsHandler(otelgrpc.NewServerHandler()),
 		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
@@ -72,6 +87,11 @@ func (s *Server) Listen() error {
 			grpc_ctxtags.UnaryServerInterceptor(),
 			grpc_opentracing.UnaryServerInterceptor(),
 			middlewares.UnaryServerMetric(durationM, reqM, s.t),
+			middlewares.NewDeviceId(),
+			selector.UnaryServerInterceptor(
+				grpc_auth.UnaryServerInterceptor(middlewares.NewAccessGuard(s.authUseCase, s.t)),
+				selector.MatchFunc(middlewares.NewAccessMatcher(protected)),
+			),
 		)),
 		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
 			grpc_recovery.StreamServerInterceptor(),
@@ -82,14 +102,8 @@ func (s *Server) Listen() error {
 		grpc.MaxRecvMsgSize(1024*1024*1024),
 		grpc.MaxSendMsgSize(1024*1024*1024),
 	)
-
-	auth := routes.AuthRoutes{
-		Tracer:        s.t,
-		ValidationSrv: s.validationSrv,
-		AuthUseCase:   s.authUseCase,
-		Domain:        s.domain,
-	}
 	auth.RegisterRouter(s.srv)
+	account.RegisterRouter(s.srv)
 	if err := s.srv.Serve(lis); err != nil {
 		return err
 	}
@@ -10,17 +10,29 @@ func EmbedMailTemplate() embed.FS {
 }
 
 type templates struct {
-	AuthRegistered string
-	AuthVerify     string
+	AuthRegistered      string
+	AuthSecurityChanged string
+	AuthSecurityCode    string
+	AuthSessionReverify string
+	AuthSessionRevoked  string
+	AuthVerify          string
 
-	TransferIncoming string
-	TransferOutgoing string
+	TransferConfirm         string
+	TransferIncoming        string
+	TransferOutgoing        string
+	TransferScheduledFailed string
 }
 
 var Templates = templates{
-	AuthRegistered: "auth/registered",
-	AuthVerify:     "auth/verify",
+	AuthRegistered:      "auth/registered",
+	AuthSecurityChanged: "auth/security_changed",
+	AuthSecurityCode:    "auth/security_code",
+	AuthSessionReverify: "auth/session_reverify",
+	AuthSessionRevoked:  "auth/session_revoked",
+	AuthVerify:          "auth/verify",
 
-	TransferIncoming: "transfer/incoming",
-	TransferOutgoing: "transfer/outgoing",
+	TransferConfirm:         "transfer/confirm",
+	TransferIncoming:        "transfer/incoming",
+	TransferOutgoing:        "transfer/outgoing",
+	TransferScheduledFailed: "transfer/scheduled_failed",
 }
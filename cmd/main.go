@@ -8,23 +8,41 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/9ssi7/bank/api/eventstream"
+	"github.com/9ssi7/bank/api/health"
+	"github.com/9ssi7/bank/api/jobs"
 	"github.com/9ssi7/bank/api/rest"
 	"github.com/9ssi7/bank/api/rpc"
 	"github.com/9ssi7/bank/config"
+	"github.com/9ssi7/bank/internal/domain/auth"
+	"github.com/9ssi7/bank/internal/domain/fee"
+	"github.com/9ssi7/bank/internal/domain/fx"
+	"github.com/9ssi7/bank/internal/domain/limit"
+	"github.com/9ssi7/bank/internal/eventhandler"
 	"github.com/9ssi7/bank/internal/infra/db"
 	"github.com/9ssi7/bank/internal/infra/db/migration"
 	"github.com/9ssi7/bank/internal/infra/eventer"
+	"github.com/9ssi7/bank/internal/infra/fxrate"
 	"github.com/9ssi7/bank/internal/infra/keyval"
+	"github.com/9ssi7/bank/internal/infra/mail"
 	"github.com/9ssi7/bank/internal/infra/observer"
 	"github.com/9ssi7/bank/internal/repository"
 	"github.com/9ssi7/bank/internal/usecase"
 	"github.com/9ssi7/bank/pkg/cancel"
+	"github.com/9ssi7/bank/pkg/iban"
 	"github.com/9ssi7/bank/pkg/retry"
+	"github.com/9ssi7/bank/pkg/seal"
+	"github.com/9ssi7/bank/pkg/server"
 	"github.com/9ssi7/bank/pkg/token"
 	"github.com/9ssi7/bank/pkg/validation"
+	"github.com/9ssi7/bank/pkg/webauthn"
 	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var once sync.Once
@@ -39,8 +57,12 @@ type app struct {
 	tokenSrv *token.Service
 	cnf      *config.App
 
-	authUseCase    *usecase.AuthUseCase
-	accountUseCase *usecase.AccountUseCase
+	authUseCase        *usecase.AuthUseCase
+	accountUseCase     *usecase.AccountUseCase
+	idempotencyUseCase *usecase.IdempotencyUseCase
+	outboxUseCase      *usecase.OutboxUseCase
+
+	scheduledTransferUseCase *usecase.ScheduledTransferUseCase
 }
 
 func init() {
@@ -50,48 +72,152 @@ func init() {
 			log.Fatalf("failed to initialize app: %v", err)
 		}
 		a.valSrv = validation.New()
+		sealer, err := a.sealer()
+		if err != nil {
+			log.Fatalf("failed to load key encryption key: %v", err)
+		}
 		userRepo := repository.NewUserSqlRepo(a.db)
 		accountRepo := repository.NewAccountSqlRepo(a.db)
 		transactionRepo := repository.NewTransactionSqlRepo(a.db)
+		ledgerRepo := repository.NewLedgerSqlRepo(a.db)
+		holdRepo := repository.NewHoldSqlRepo(a.db)
 		verifyRepo := repository.NewVerifyRedisRepo(a.rdb)
 		sessionRepo := repository.NewSessionRedisRepo(a.rdb)
+		refreshFamilyRepo := repository.NewRefreshFamilyRedisRepo(a.rdb)
+		totpRepo := repository.NewTotpSqlRepo(a.db, sealer)
+		passkeyRepo := repository.NewPasskeySqlRepo(a.db)
+		webauthnRepo := repository.NewWebauthnRedisRepo(a.rdb)
+		idempotencyRepo := repository.NewIdempotencyRedisRepo(a.rdb)
+		outboxRepo := repository.NewOutboxSqlRepo(a.db)
+		scheduledTransferRepo := repository.NewScheduledTransferSqlRepo(a.db)
+		fxRepo := repository.NewFxSqlRepo(a.db)
+		fxProvider, err := a.fxProvider()
+		if err != nil {
+			log.Fatalf("failed to load fx rates: %v", err)
+		}
+		feePolicy, err := a.feePolicy()
+		if err != nil {
+			log.Fatalf("failed to load fee rules: %v", err)
+		}
+		limitPolicy, err := a.limitPolicy()
+		if err != nil {
+			log.Fatalf("failed to load limit rules: %v", err)
+		}
+		stepUpThresholds, err := a.stepUpThresholds()
+		if err != nil {
+			log.Fatalf("failed to load step-up thresholds: %v", err)
+		}
+		devicePolicy, err := a.devicePolicy()
+		if err != nil {
+			log.Fatalf("failed to load session policy: %v", err)
+		}
+		ibans, err := iban.NewGenerator(a.cnf.Iban.Country, a.cnf.Iban.BankCode)
+		if err != nil {
+			log.Fatalf("failed to configure ibans: %v", err)
+		}
 		a.authUseCase = &usecase.AuthUseCase{
 			TokenSrv:    a.tokenSrv,
-			EventSrv:    a.eventSrv,
+			OutboxRepo:  outboxRepo,
 			VerifyRepo:  verifyRepo,
 			UserRepo:    userRepo,
 			SessionRepo: sessionRepo,
+			TotpRepo:    totpRepo,
+			TotpIssuer:  a.cnf.Totp.Issuer,
+
+			RefreshFamilyRepo: refreshFamilyRepo,
+			DevicePolicy:      devicePolicy,
+
+			PasskeyRepo:  passkeyRepo,
+			WebauthnRepo: webauthnRepo,
+			Webauthn: &webauthn.Config{
+				RPID:    a.cnf.Webauthn.RPID,
+				RPName:  a.cnf.Webauthn.RPName,
+				Origins: a.cnf.Webauthn.Origins,
+			},
 		}
 		a.accountUseCase = &usecase.AccountUseCase{
-			EventSrv:        a.eventSrv,
+			OutboxRepo:      outboxRepo,
 			AccountRepo:     accountRepo,
 			TransactionRepo: transactionRepo,
+			LedgerRepo:      ledgerRepo,
+			HoldRepo:        holdRepo,
 			UserRepo:        userRepo,
+			FxRepo:          fxRepo,
+			FxProvider:      fxProvider,
+			FeePolicy:       feePolicy,
+			LimitPolicy:     limitPolicy,
+			Ibans:           ibans,
+
+			VerifyRepo:       verifyRepo,
+			StepUpThresholds: stepUpThresholds,
+		}
+		a.idempotencyUseCase = &usecase.IdempotencyUseCase{
+			Repo: idempotencyRepo,
+		}
+		a.outboxUseCase = &usecase.OutboxUseCase{
+			EventSrv: a.eventSrv,
+			Repo:     outboxRepo,
+		}
+		a.scheduledTransferUseCase = &usecase.ScheduledTransferUseCase{
+			Repo:           scheduledTransferRepo,
+			OutboxRepo:     outboxRepo,
+			AccountRepo:    accountRepo,
+			UserRepo:       userRepo,
+			AccountUseCase: a.accountUseCase,
 		}
 	})
 }
 
+// main runs the api and the worker in one process by default.
+// The "api" and "worker" commands run them as separate processes.
 func main() {
 	tracer := a.obsrvr.GetTracer()
 	meter := a.obsrvr.GetMeter()
 
+	command := "all"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+	var listeners []listener
+	switch command {
+	case "all":
+		listeners = append(a.apiListeners(tracer, meter), a.workerListeners(tracer)...)
+	case "api":
+		listeners = a.apiListeners(tracer, meter)
+	case "worker":
+		listeners = a.workerListeners(tracer)
+	default:
+		log.Fatalf("unknown command %q, expected one of all, api, worker", command)
+	}
+	a.run(listeners...)
+	fmt.Println("All servers are stopped.")
+}
+
+type listener struct {
+	name string
+	srv  server.Listener
+}
+
+func (a *app) apiListeners(tracer trace.Tracer, meter metric.Meter) []listener {
 	restSrv := rest.New(rest.Config{
-		Tracer:           tracer,
-		Meter:            meter,
-		ValidationSrv:    a.valSrv,
-		AuthUseCase:      a.authUseCase,
-		AccountUseCase:   a.accountUseCase,
-		Host:             a.cnf.Rest.Host,
-		Port:             a.cnf.Rest.Port,
-		Domain:           a.cnf.Rest.Domain,
-		AllowedMethods:   a.cnf.Rest.AllowMethods,
-		AllowedHeaders:   a.cnf.Rest.AllowHeaders,
-		AllowedOrigins:   a.cnf.Rest.AllowOrigins,
-		ExposeHeaders:    a.cnf.Rest.ExposeHeader,
-		AllowCredentials: a.cnf.Rest.AllowCred,
-		Locales:          a.cnf.I18n.Locales,
-		TurnstileSecret:  a.cnf.Turnstile.Secret,
-		TurnstileSkip:    a.cnf.Turnstile.Skip,
+		Tracer:                   tracer,
+		Meter:                    meter,
+		ValidationSrv:            a.valSrv,
+		AuthUseCase:              a.authUseCase,
+		AccountUseCase:           a.accountUseCase,
+		IdempotencyUseCase:       a.idempotencyUseCase,
+		ScheduledTransferUseCase: a.scheduledTransferUseCase,
+		Host:                     a.cnf.Rest.Host,
+		Port:                     a.cnf.Rest.Port,
+		Domain:                   a.cnf.Rest.Domain,
+		AllowedMethods:           a.cnf.Rest.AllowMethods,
+		AllowedHeaders:           a.cnf.Rest.AllowHeaders,
+		AllowedOrigins:           a.cnf.Rest.AllowOrigins,
+		ExposeHeaders:            a.cnf.Rest.ExposeHeader,
+		AllowCredentials:         a.cnf.Rest.AllowCred,
+		Locales:                  a.cnf.I18n.Locales,
+		TurnstileSecret:          a.cnf.Turnstile.Secret,
+		TurnstileSkip:            a.cnf.Turnstile.Skip,
 	})
 
 	rpcSrv := rpc.New(rpc.Config{
@@ -103,34 +229,212 @@ func main() {
 		Domain:          a.cnf.Rpc.Domain,
 		Port:            a.cnf.Rpc.Port,
 	})
+	return []listener{{"rest server", restSrv}, {"rpc server", rpcSrv}}
+}
+
+func (a *app) workerListeners(tracer trace.Tracer) []listener {
+	mailSrv := mail.New(mail.Config{
+		Host:     a.cnf.Mail.Host,
+		Port:     a.cnf.Mail.Port,
+		Sender:   a.cnf.Mail.Sender,
+		Password: a.cnf.Mail.Password,
+		From:     a.cnf.Mail.From,
+		Reply:    a.cnf.Mail.Reply,
+	})
+
+	streamSrv := eventstream.New(eventstream.Config{
+		Eventer:        a.eventSrv,
+		Tracer:         tracer,
+		Stream:         a.cnf.Event.Stream,
+		Durable:        a.cnf.Event.Durable,
+		MaxDeliver:     a.cnf.Event.MaxDeliver,
+		Backoff:        a.cnf.Event.Backoff,
+		AuthHandler:    eventhandler.NewAuthHandler(mailSrv, a.cnf.Rest.PublicHost),
+		AccountHandler: eventhandler.NewAccountHandler(mailSrv),
+	})
+
+	jobsSrv := jobs.New(jobs.Config{
+		Tracer:           tracer,
+		OutboxUseCase:    a.outboxUseCase,
+		RelayInterval:    a.cnf.Outbox.RelayInterval,
+		RelayBatchSize:   a.cnf.Outbox.BatchSize,
+		RelayMaxAttempts: a.cnf.Outbox.MaxAttempts,
+
+		ScheduledTransferUseCase: a.scheduledTransferUseCase,
+		ScheduleInterval:         a.cnf.Schedule.Interval,
+		ScheduleBatchSize:        a.cnf.Schedule.BatchSize,
+
+		AccountUseCase: a.accountUseCase,
+		HoldInterval:   a.cnf.Hold.ReleaseInterval,
+		HoldBatchSize:  a.cnf.Hold.BatchSize,
+
+		AuthUseCase: a.authUseCase,
+		KeyInterval: a.cnf.Token.SyncInterval,
+	})
 
+	healthSrv := health.New(health.Config{
+		Host: a.cnf.Worker.HealthHost,
+		Port: a.cnf.Worker.HealthPort,
+		Checks: map[string]health.Check{
+			"database": a.db.PingContext,
+			"keyval":   a.pingKeyval,
+			"event":    a.eventSrv.Health,
+		},
+	})
+	return []listener{{"event stream", streamSrv}, {"jobs", jobsSrv}, {"health server", healthSrv}}
+}
+
+// run starts the listeners and blocks until all of them are shut down by a signal.
+func (a *app) run(listeners ...listener) {
 	var wg sync.WaitGroup
-	wg.Add(3)
-	go func() {
-		defer wg.Done()
-		if err := restSrv.Listen(); err != nil {
-			log.Fatalf("failed to start rest server: %v", err)
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		if err := rpcSrv.Listen(); err != nil {
-			log.Fatalf("failed to start rpc server: %v", err)
-		}
-	}()
+	wg.Add(len(listeners) + 1)
+	shutdowns := make([]disconFunc, 0, len(listeners))
+	for _, l := range listeners {
+		shutdowns = append(shutdowns, l.srv.Shutdown)
+		go func(l listener) {
+			defer wg.Done()
+			if err := l.srv.Listen(); err != nil {
+				log.Fatalf("failed to start %s: %v", l.name, err)
+			}
+		}(l)
+	}
 	shutdownCh := make(chan os.Signal, 1)
-	signal.Notify(shutdownCh, os.Interrupt)
+	signal.Notify(shutdownCh, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		defer wg.Done()
 		<-shutdownCh
 		log.Println("application is shutting down...")
-		if err := a.disconnect(context.Background(), restSrv.Shutdown, rpcSrv.Shutdown); err != nil {
+		if err := a.disconnect(context.Background(), shutdowns...); err != nil {
 			log.Fatalf("failed to disconnect: %v", err)
 		}
 	}()
-
 	wg.Wait()
-	fmt.Println("All servers are stopped.")
+}
+
+func (a *app) fxProvider() (fx.Provider, error) {
+	if a.cnf.Fx.File != "" {
+		return fxrate.NewFile(a.cnf.Fx.File)
+	}
+	return fxrate.NewStatic(a.cnf.Fx.Rates)
+}
+
+// feePolicy builds the fee rules of the config, without rules every transaction is free.
+func (a *app) feePolicy() (*fee.Policy, error) {
+	rules := make([]fee.Rule, 0, len(a.cnf.Fee.Rules))
+	for _, r := range a.cnf.Fee.Rules {
+		rule := fee.Rule{Currency: r.Currency, Kind: r.Kind}
+		var err error
+		if rule.Flat, err = parseDecimal(r.Flat); err != nil {
+			return nil, err
+		}
+		if rule.Percentage, err = parseDecimal(r.Percentage); err != nil {
+			return nil, err
+		}
+		if rule.Min, err = parseNullDecimal(r.Min); err != nil {
+			return nil, err
+		}
+		if rule.Max, err = parseNullDecimal(r.Max); err != nil {
+			return nil, err
+		}
+		for _, t := range r.Tiers {
+			var tier fee.Tier
+			if tier.UpTo, err = parseNullDecimal(t.UpTo); err != nil {
+				return nil, err
+			}
+			if tier.Flat, err = parseDecimal(t.Flat); err != nil {
+				return nil, err
+			}
+			if tier.Percentage, err = parseDecimal(t.Percentage); err != nil {
+				return nil, err
+			}
+			rule.Tiers = append(rule.Tiers, tier)
+		}
+		rules = append(rules, rule)
+	}
+	return fee.NewPolicy(rules...), nil
+}
+
+// limitPolicy builds the limit rules of the config, without rules nothing is limited.
+func (a *app) limitPolicy() (*limit.Policy, error) {
+	rules := make([]limit.Rule, 0, len(a.cnf.Limit.Rules))
+	for _, r := range a.cnf.Limit.Rules {
+		scope := limit.Scope(r.Scope)
+		if scope != limit.ScopeAccount && scope != limit.ScopeUser {
+			return nil, fmt.Errorf("unknown limit scope %q", r.Scope)
+		}
+		rule := limit.Rule{Scope: scope, Currency: r.Currency, DailyCount: r.DailyCount, MonthlyCount: r.MonthlyCount}
+		var err error
+		if rule.PerTransaction, err = parseNullDecimal(r.PerTransaction); err != nil {
+			return nil, err
+		}
+		if rule.DailyAmount, err = parseNullDecimal(r.DailyAmount); err != nil {
+			return nil, err
+		}
+		if rule.MonthlyAmount, err = parseNullDecimal(r.MonthlyAmount); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return limit.NewPolicy(rules...), nil
+}
+
+// devicePolicy reads the policy the sessions moving to another address are held against, strict by default.
+func (a *app) devicePolicy() (auth.DevicePolicy, error) {
+	mode := auth.IpMode(a.cnf.Session.IpPolicy)
+	switch mode {
+	case "":
+		mode = auth.IpModeStrict
+	case auth.IpModeStrict, auth.IpModePrefix, auth.IpModeDevice, auth.IpModeRisk:
+	default:
+		return auth.DevicePolicy{}, fmt.Errorf("unknown session ip policy %q", a.cnf.Session.IpPolicy)
+	}
+	return auth.DevicePolicy{
+		Mode:          mode,
+		PrefixV4:      a.cnf.Session.PrefixV4,
+		PrefixV6:      a.cnf.Session.PrefixV6,
+		RiskThreshold: a.cnf.Session.RiskThreshold,
+		Reverify:      a.cnf.Session.Reverify,
+	}, nil
+}
+
+// sealer seals the secrets kept at rest, the totp secrets, with the key encryption key of the tokens.
+func (a *app) sealer() (*seal.Sealer, error) {
+	kek, err := seal.ReadKeyFile(a.cnf.Token.KeyEncryptionKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return seal.New(kek)
+}
+
+// stepUpThresholds parses the thresholds of the config, a transfer above one waits for a mailed code.
+func (a *app) stepUpThresholds() (map[string]decimal.Decimal, error) {
+	thresholds := make(map[string]decimal.Decimal, len(a.cnf.StepUp.Thresholds))
+	for currency, s := range a.cnf.StepUp.Thresholds {
+		d, err := decimal.NewFromString(s)
+		if err != nil {
+			return nil, err
+		}
+		thresholds[currency] = d
+	}
+	return thresholds, nil
+}
+
+func parseDecimal(s string) (decimal.Decimal, error) {
+	if s == "" {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromString(s)
+}
+
+func parseNullDecimal(s string) (decimal.NullDecimal, error) {
+	if s == "" {
+		return decimal.NullDecimal{}, nil
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.NullDecimal{}, err
+	}
+	return decimal.NewNullDecimal(d), nil
 }
 
 func (a *app) loadConfig() error {
@@ -185,18 +489,33 @@ func (a *app) initialize(ctx context.Context) error {
 				return err
 			}
 			tknSrv, err := token.New(token.Config{
-				PublicKeyFile:  a.cnf.Token.PublicKeyFile,
-				PrivateKeyFile: a.cnf.Token.PrivateKeyFile,
-				Project:        a.cnf.Token.Project,
-				SignMethod:     a.cnf.Token.SignMethod,
+				PublicKeyFile:        a.cnf.Token.PublicKeyFile,
+				PrivateKeyFile:       a.cnf.Token.PrivateKeyFile,
+				Project:              a.cnf.Token.Project,
+				SignMethod:           a.cnf.Token.SignMethod,
+				RotateEvery:          a.cnf.Token.RotateEvery,
+				Store:                repository.NewTokenKeyRedisRepo(rdb),
+				KeyEncryptionKeyFile: a.cnf.Token.KeyEncryptionKeyFile,
+				Logger:               func(l string) { log.Println(l) },
+				Denylist:             repository.NewTokenDenylistRedisRepo(rdb),
 			})
 			if err != nil {
 				return err
 			}
-			a.eventSrv = eventer.New(a.cnf.Event.StreamUrl)
+			a.eventSrv = eventer.New(eventer.Config{
+				StreamUrl:         a.cnf.Event.StreamUrl,
+				Stream:            a.cnf.Event.Stream,
+				Subjects:          a.cnf.Event.Subjects,
+				MaxAge:            a.cnf.Event.MaxAge,
+				DeadLetterStream:  a.cnf.Event.DeadLetterStream,
+				DeadLetterSubject: a.cnf.Event.DeadLetterSubject,
+			})
 			if err := a.eventSrv.Connect(ctx); err != nil {
 				return err
 			}
+			if err := a.eventSrv.Provision(ctx); err != nil {
+				return err
+			}
 			a.tokenSrv = tknSrv
 			a.rdb = rdb
 			a.db = db
@@ -231,3 +550,7 @@ func (a *app) closeDB(ctx context.Context) error {
 func (a *app) closeKeyval(ctx context.Context) error {
 	return a.rdb.Close()
 }
+
+func (a *app) pingKeyval(ctx context.Context) error {
+	return a.rdb.Ping(ctx).Err()
+}
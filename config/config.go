@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -34,15 +35,135 @@ type Observer struct {
 	UseSSL   bool   `yaml:"use_ssl"`
 }
 
+type Totp struct {
+	Issuer string `yaml:"issuer"`
+}
+
+// Origins are the exact origins, scheme and port included, the ceremonies may come from.
+type Webauthn struct {
+	RPID    string   `yaml:"rp_id"`
+	RPName  string   `yaml:"rp_name"`
+	Origins []string `yaml:"origins"`
+}
+
+// RotateEvery is the age the signing key is replaced at, zero keeps signing with the key of the files.
+// SyncInterval is how often an instance looks for keys rotated by the others and rotates when due.
 type Token struct {
-	PublicKeyFile  string `yaml:"public_key_file"`
-	PrivateKeyFile string `yaml:"private_key_file"`
-	Project        string `yaml:"project"`
-	SignMethod     string `yaml:"sign_method"`
+	PublicKeyFile  string        `yaml:"public_key_file"`
+	PrivateKeyFile string        `yaml:"private_key_file"`
+	Project        string        `yaml:"project"`
+	SignMethod     string        `yaml:"sign_method"`
+	RotateEvery    time.Duration `yaml:"rotate_every"`
+	SyncInterval   time.Duration `yaml:"sync_interval"`
+
+	// KeyEncryptionKeyFile holds the base64 of the 32 byte key the rotated keys are sealed with in the keyval store,
+	// the totp secrets are sealed with it in the database too.
+	KeyEncryptionKeyFile string `yaml:"key_encryption_key_file"`
+}
+
+// Session configures the device policy of the sessions. IpPolicy is strict, prefix, device or risk,
+// Reverify emails a code for the moves the policy does not take instead of logging the session out.
+type Session struct {
+	IpPolicy      string `yaml:"ip_policy"`
+	PrefixV4      int    `yaml:"prefix_v4"`
+	PrefixV6      int    `yaml:"prefix_v6"`
+	RiskThreshold int    `yaml:"risk_threshold"`
+	Reverify      bool   `yaml:"reverify"`
 }
 
 type EventStream struct {
-	StreamUrl string `yaml:"stream_url"`
+	StreamUrl         string          `yaml:"stream_url"`
+	Stream            string          `yaml:"stream"`
+	Subjects          []string        `yaml:"subjects"`
+	MaxAge            time.Duration   `yaml:"max_age"`
+	Durable           string          `yaml:"durable"`
+	MaxDeliver        int             `yaml:"max_deliver"`
+	Backoff           []time.Duration `yaml:"backoff"`
+	DeadLetterStream  string          `yaml:"dead_letter_stream"`
+	DeadLetterSubject string          `yaml:"dead_letter_subject"`
+}
+
+type Outbox struct {
+	RelayInterval time.Duration `yaml:"relay_interval"`
+	BatchSize     int           `yaml:"batch_size"`
+	MaxAttempts   int           `yaml:"max_attempts"`
+}
+
+type Schedule struct {
+	Interval  time.Duration `yaml:"interval"`
+	BatchSize int           `yaml:"batch_size"`
+}
+
+// Iban configures the ibans of new accounts, the bank code has to fit the format of the country.
+type Iban struct {
+	Country  string `yaml:"country"`
+	BankCode string `yaml:"bank_code"`
+}
+
+// Hold configures the job releasing expired holds.
+type Hold struct {
+	ReleaseInterval time.Duration `yaml:"release_interval"`
+	BatchSize       int           `yaml:"batch_size"`
+}
+
+type Fx struct {
+	File  string            `yaml:"file"`
+	Rates map[string]string `yaml:"rates"`
+}
+
+// Amounts of fee rules are decimal strings, percentages are given in percent, e.g. "0.5" for 0.5%.
+type FeeTier struct {
+	UpTo       string `yaml:"up_to"`
+	Flat       string `yaml:"flat"`
+	Percentage string `yaml:"percentage"`
+}
+
+type FeeRule struct {
+	Currency   string    `yaml:"currency"`
+	Kind       string    `yaml:"kind"`
+	Flat       string    `yaml:"flat"`
+	Percentage string    `yaml:"percentage"`
+	Tiers      []FeeTier `yaml:"tiers"`
+	Min        string    `yaml:"min"`
+	Max        string    `yaml:"max"`
+}
+
+type Fee struct {
+	Rules []FeeRule `yaml:"rules"`
+}
+
+// Amounts of limit rules are decimal strings, unset amounts and zero counts are not limited.
+type LimitRule struct {
+	Scope          string `yaml:"scope"`
+	Currency       string `yaml:"currency"`
+	PerTransaction string `yaml:"per_transaction"`
+	DailyAmount    string `yaml:"daily_amount"`
+	DailyCount     int    `yaml:"daily_count"`
+	MonthlyAmount  string `yaml:"monthly_amount"`
+	MonthlyCount   int    `yaml:"monthly_count"`
+}
+
+type Limit struct {
+	Rules []LimitRule `yaml:"rules"`
+}
+
+// Thresholds map a currency to the amount a transfer may reach without step-up verification.
+type StepUp struct {
+	Thresholds map[string]string `yaml:"thresholds"`
+}
+
+type Mail struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Sender   string `yaml:"sender"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	Reply    string `yaml:"reply"`
+}
+
+type Worker struct {
+	HealthHost string `yaml:"health_host"`
+	HealthPort string `yaml:"health_port"`
 }
 
 type Turnstile struct {
@@ -59,6 +180,7 @@ type Rest struct {
 	AllowOrigins string `yaml:"allowed_origins"`
 	ExposeHeader string `yaml:"expose_headers"`
 	AllowCred    bool   `yaml:"allow_credentials"`
+	PublicHost   string `yaml:"public_host"`
 }
 
 type Rpc struct {
@@ -78,7 +200,20 @@ type App struct {
 	Keyval    Keyval      `yaml:"keyval"`
 	Observer  Observer    `yaml:"observer"`
 	Token     Token       `yaml:"token"`
+	Session   Session     `yaml:"session"`
+	Totp      Totp        `yaml:"totp"`
+	Webauthn  Webauthn    `yaml:"webauthn"`
 	Event     EventStream `yaml:"event"`
+	Outbox    Outbox      `yaml:"outbox"`
+	Schedule  Schedule    `yaml:"schedule"`
+	Hold      Hold        `yaml:"hold"`
+	Mail      Mail        `yaml:"mail"`
+	Fx        Fx          `yaml:"fx"`
+	Fee       Fee         `yaml:"fee"`
+	Limit     Limit       `yaml:"limit"`
+	StepUp    StepUp      `yaml:"step_up"`
+	Iban      Iban        `yaml:"iban"`
+	Worker    Worker      `yaml:"worker"`
 	Rest      Rest        `yaml:"rest"`
 	Rpc       Rpc         `yaml:"rpc"`
 	Turnstile Turnstile   `yaml:"turnstile"`
@@ -3,7 +3,6 @@ package account
 import (
 	"time"
 
-	"github.com/9ssi7/bank/pkg/iban"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
@@ -29,6 +28,8 @@ type AccountListItem struct {
 	Currency string    `json:"currency"`
 	Balance  string    `json:"balance"`
 	Status   string    `json:"status"`
+
+	AvailableBalance string `json:"available_balance"`
 }
 
 type Account struct {
@@ -39,20 +40,24 @@ type Account struct {
 	Iban   string    `json:"iban"`
 
 	// ISO 4217 currency code
-	Currency  string          `json:"currency" example:"EUR"`
-	Status    Status          `json:"status"`
-	Balance   decimal.Decimal `json:"balance"`
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
-	DeletedAt time.Time       `json:"deleted_at"`
-}
+	Currency string          `json:"currency" example:"EUR"`
+	Status   Status          `json:"status"`
+	Balance  decimal.Decimal `json:"balance"`
+	Version  int64           `json:"version"`
 
-func (a *Account) Credit(amount decimal.Decimal) {
-	a.Balance = a.Balance.Add(amount)
+	// AvailableBalance is the balance less what active holds reserve, it is what can be spent.
+	AvailableBalance decimal.Decimal `json:"available_balance"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	DeletedAt time.Time `json:"deleted_at"`
 }
 
-func (a *Account) Debit(amount decimal.Decimal) {
-	a.Balance = a.Balance.Sub(amount)
+// Reconcile replaces the cached balances with the one derived from the ledger postings
+// and the amount reserved by holds.
+func (a *Account) Reconcile(balance decimal.Decimal, reserved decimal.Decimal) {
+	a.Balance = balance
+	a.AvailableBalance = balance.Sub(reserved)
 }
 
 func (a *Account) Lock() {
@@ -76,7 +81,7 @@ func (a *Account) IsAvailable() bool {
 }
 
 func (a *Account) CanCredit(amount decimal.Decimal) bool {
-	return a.IsAvailable() && amount.GreaterThan(decimal.Zero) && a.Balance.GreaterThanOrEqual(amount)
+	return a.IsAvailable() && amount.GreaterThan(decimal.Zero) && a.AvailableBalance.GreaterThanOrEqual(amount)
 }
 
 type Config struct {
@@ -84,6 +89,7 @@ type Config struct {
 	Name     string    `example:"My Account"`
 	Owner    string    `example:"John Doe"`
 	Currency string    `example:"EUR"` // ISO 4217 currency code
+	Iban     string    `example:"TR330006100519786457841326"`
 }
 
 func New(cnf Config) *Account {
@@ -91,9 +97,11 @@ func New(cnf Config) *Account {
 		UserId:   cnf.UserId,
 		Name:     cnf.Name,
 		Owner:    cnf.Owner,
-		Iban:     iban.New(),
+		Iban:     cnf.Iban,
 		Currency: cnf.Currency,
 		Balance:  decimal.Zero,
 		Status:   StatusActive,
+
+		AvailableBalance: decimal.Zero,
 	}
 }
@@ -3,6 +3,7 @@ package account
 const (
 	SubjectTransferIncoming = "Account.TransferIncoming"
 	SubjectTransferOutgoing = "Account.TransferOutgoing"
+	SubjectTransferPending  = "Account.TransferPending"
 )
 
 type EventTranfserIncoming struct {
@@ -22,3 +23,14 @@ type EventTranfserOutgoing struct {
 	Account     string `json:"account"`
 	Description string `json:"description"`
 }
+
+// EventTransferPending carries the code confirming a transfer held back for step-up verification.
+type EventTransferPending struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Code     string `json:"code"`
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+	ToIban   string `json:"to_iban"`
+	ToOwner  string `json:"to_owner"`
+}
@@ -0,0 +1,92 @@
+package account
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type HoldStatus string
+
+func (s HoldStatus) String() string {
+	return string(s)
+}
+
+const (
+	HoldStatusActive   HoldStatus = "active"
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusVoided   HoldStatus = "voided"
+	HoldStatusExpired  HoldStatus = "expired"
+)
+
+// DefaultHoldExpiry is how long a hold reserves money when the authorisation does not say otherwise.
+const DefaultHoldExpiry = 7 * 24 * time.Hour
+
+// Hold reserves an amount of an account for a later capture, an active hold lowers the available
+// balance but not the balance. Fee is the fee reserved along with the amount, a capture never
+// charges more than it.
+type Hold struct {
+	ID            uuid.UUID       `json:"id"`
+	AccountId     uuid.UUID       `json:"account_id"`
+	Amount        decimal.Decimal `json:"amount"`
+	Fee           decimal.Decimal `json:"fee"`
+	Currency      string          `json:"currency"`
+	Description   string          `json:"description"`
+	Status        HoldStatus      `json:"status"`
+	ExpiresAt     time.Time       `json:"expires_at"`
+	Captured      decimal.Decimal `json:"captured"`
+	TransactionId *uuid.UUID      `json:"transaction_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// Reserved is what the hold takes away from the available balance while it is active.
+func (h *Hold) Reserved() decimal.Decimal {
+	return h.Amount.Add(h.Fee)
+}
+
+func (h *Hold) IsActive() bool {
+	return h.Status == HoldStatusActive
+}
+
+func (h *Hold) IsExpired(now time.Time) bool {
+	return !now.Before(h.ExpiresAt)
+}
+
+// Capture settles the hold with the transaction booking the captured amount, the rest is released.
+func (h *Hold) Capture(amount decimal.Decimal, transactionId uuid.UUID) {
+	h.Status = HoldStatusCaptured
+	h.Captured = amount
+	h.TransactionId = &transactionId
+}
+
+func (h *Hold) Void() {
+	h.Status = HoldStatusVoided
+}
+
+func (h *Hold) Expire() {
+	h.Status = HoldStatusExpired
+}
+
+type HoldConfig struct {
+	AccountId   uuid.UUID       `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Amount      decimal.Decimal `example:"100.00"`
+	Fee         decimal.Decimal `example:"1.00"`
+	Currency    string          `example:"EUR"`
+	Description string          `example:"Hotel reservation"`
+	ExpiresAt   time.Time       `example:"2024-01-08T00:00:00Z"`
+}
+
+func NewHold(cnf HoldConfig) *Hold {
+	return &Hold{
+		AccountId:   cnf.AccountId,
+		Amount:      cnf.Amount,
+		Fee:         cnf.Fee,
+		Currency:    cnf.Currency,
+		Description: cnf.Description,
+		ExpiresAt:   cnf.ExpiresAt,
+		Status:      HoldStatusActive,
+		Captured:    decimal.Zero,
+	}
+}
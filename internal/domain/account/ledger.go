@@ -0,0 +1,105 @@
+package account
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type PostingDirection string
+
+func (pd PostingDirection) String() string {
+	return string(pd)
+}
+
+const (
+	PostingDirectionDebit  PostingDirection = "debit"
+	PostingDirectionCredit PostingDirection = "credit"
+)
+
+// System ledger accounts are the counterparts of money entering or leaving the bank.
+// They only live in the ledger, there is no row for them in the accounts table.
+var (
+	LedgerCashAccountId = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	LedgerFeeAccountId  = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	LedgerFxAccountId   = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+)
+
+type Posting struct {
+	ID        uuid.UUID        `json:"id"`
+	EntryId   uuid.UUID        `json:"entry_id"`
+	AccountId uuid.UUID        `json:"account_id"`
+	Direction PostingDirection `json:"direction"`
+	Amount    decimal.Decimal  `json:"amount"`
+	Currency  string           `json:"currency"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// Signed returns the effect of the posting on a customer account balance.
+// Customer accounts are liabilities of the bank, so credits increase them.
+func (p *Posting) Signed() decimal.Decimal {
+	if p.Direction == PostingDirectionDebit {
+		return p.Amount.Neg()
+	}
+	return p.Amount
+}
+
+type JournalEntry struct {
+	ID            uuid.UUID  `json:"id"`
+	TransactionId uuid.UUID  `json:"transaction_id"`
+	Description   string     `json:"description"`
+	Postings      []*Posting `json:"postings"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (e *JournalEntry) Debit(accountId uuid.UUID, amount decimal.Decimal, currency string) {
+	e.add(accountId, PostingDirectionDebit, amount, currency)
+}
+
+func (e *JournalEntry) Credit(accountId uuid.UUID, amount decimal.Decimal, currency string) {
+	e.add(accountId, PostingDirectionCredit, amount, currency)
+}
+
+func (e *JournalEntry) add(accountId uuid.UUID, direction PostingDirection, amount decimal.Decimal, currency string) {
+	e.Postings = append(e.Postings, &Posting{
+		AccountId: accountId,
+		Direction: direction,
+		Amount:    amount,
+		Currency:  currency,
+	})
+}
+
+// IsBalanced reports whether debits equal credits for every currency of the entry.
+// Entries without postings or with non positive amounts are never balanced.
+func (e *JournalEntry) IsBalanced() bool {
+	if len(e.Postings) < 2 {
+		return false
+	}
+	sums := make(map[string]decimal.Decimal)
+	for _, p := range e.Postings {
+		if !p.Amount.GreaterThan(decimal.Zero) {
+			return false
+		}
+		sums[p.Currency] = sums[p.Currency].Add(p.Signed())
+	}
+	for _, sum := range sums {
+		if !sum.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+type JournalEntryConfig struct {
+	TransactionId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Description   string    `example:"Transfer"`
+}
+
+func NewJournalEntry(cnf JournalEntryConfig) *JournalEntry {
+	return &JournalEntry{
+		TransactionId: cnf.TransactionId,
+		Description:   cnf.Description,
+		Postings:      make([]*Posting, 0, 2),
+	}
+}
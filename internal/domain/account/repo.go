@@ -2,10 +2,13 @@ package account
 
 import (
 	"context"
+	"time"
 
+	"github.com/9ssi7/bank/internal/domain/limit"
 	"github.com/9ssi7/bank/pkg/list"
 	"github.com/9ssi7/bank/pkg/txadapter"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -15,13 +18,44 @@ type Repo interface {
 	ListByUserId(ctx context.Context, t trace.Tracer, opts ListByUserIdOpts) (*list.PagiResponse[*Account], error)
 	FindByIbanAndOwner(ctx context.Context, t trace.Tracer, opts FindByIbanAndOwnerOpts) (*Account, error)
 	FindByUserIdAndId(ctx context.Context, t trace.Tracer, opts FindByUserIdAndIdOpts) (*Account, error)
+	FindByUserIdAndIban(ctx context.Context, t trace.Tracer, opts FindByUserIdAndIbanOpts) (*Account, error)
 	FindById(ctx context.Context, t trace.Tracer, opts FindByIdOpts) (*Account, error)
+
+	// FindByIdsForUpdate locks the accounts in id order until the current transaction ends.
+	FindByIdsForUpdate(ctx context.Context, t trace.Tracer, opts FindByIdsForUpdateOpts) ([]*Account, error)
 }
 
 type TransactionRepo interface {
 	txadapter.Repo
 	Save(ctx context.Context, t trace.Tracer, opts TransactionSaveOpts) error
 	Filter(ctx context.Context, t trace.Tracer, opts TransactionFilterOpts) (*list.PagiResponse[*Transaction], error)
+	FindById(ctx context.Context, t trace.Tracer, opts TransactionFindByIdOpts) (*Transaction, error)
+	FindByReferenceId(ctx context.Context, t trace.Tracer, opts TransactionFindByReferenceIdOpts) (*Transaction, error)
+	ListByParentId(ctx context.Context, t trace.Tracer, opts TransactionListByParentIdOpts) ([]*Transaction, error)
+
+	// Usage sums the transfers and withdrawals leaving an account, or all accounts of a user,
+	// since the start of the day and of the month. Fees and reversals are not counted.
+	Usage(ctx context.Context, t trace.Tracer, opts TransactionUsageOpts) (*limit.Usage, error)
+
+	// Stream calls fn for every transaction of the account booked in the range, oldest first,
+	// without loading the range into memory. An error returned by fn stops the stream.
+	Stream(ctx context.Context, t trace.Tracer, opts TransactionStreamOpts, fn func(*Transaction) error) error
+}
+
+type LedgerRepo interface {
+	txadapter.Repo
+	Save(ctx context.Context, t trace.Tracer, opts LedgerSaveOpts) error
+	Balance(ctx context.Context, t trace.Tracer, opts LedgerBalanceOpts) (decimal.Decimal, error)
+}
+
+type HoldRepo interface {
+	txadapter.Repo
+	Save(ctx context.Context, t trace.Tracer, opts HoldSaveOpts) error
+	FindByAccountIdAndId(ctx context.Context, t trace.Tracer, opts HoldFindByAccountIdAndIdOpts) (*Hold, error)
+
+	// Reserved sums what the active holds of the account reserve, expired but unreleased ones included.
+	Reserved(ctx context.Context, t trace.Tracer, opts HoldReservedOpts) (decimal.Decimal, error)
+	ListExpired(ctx context.Context, t trace.Tracer, opts HoldListExpiredOpts) ([]*Hold, error)
 }
 
 type SaveOpts struct {
@@ -43,10 +77,19 @@ type FindByUserIdAndIdOpts struct {
 	ID     uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
 }
 
+type FindByUserIdAndIbanOpts struct {
+	UserId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Iban   string    `example:"TR0000000000000000000000"`
+}
+
 type FindByIdOpts struct {
 	ID uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
 }
 
+type FindByIdsForUpdateOpts struct {
+	IDs []uuid.UUID `example:"[\"550e8400-e29b-41d4-a716-446655440000\"]"`
+}
+
 type TransactionSaveOpts struct {
 	Transaction *Transaction `example:"{}"`
 }
@@ -56,3 +99,60 @@ type TransactionFilterOpts struct {
 	Pagi      *list.PagiRequest
 	Filters   *TransactionFilters
 }
+
+type TransactionFindByIdOpts struct {
+	ID uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+type TransactionFindByReferenceIdOpts struct {
+	ReferenceId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+type TransactionListByParentIdOpts struct {
+	ParentId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+// TransactionUsageOpts takes either an account or a user, the usage of a user leaves out
+// transfers between its own accounts. Active holds count as withdrawals.
+type TransactionUsageOpts struct {
+	AccountId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	UserId    uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Currency  string    `example:"EUR"`
+	Day       time.Time `example:"2024-01-15T00:00:00Z"`
+	Month     time.Time `example:"2024-01-01T00:00:00Z"`
+}
+
+type LedgerSaveOpts struct {
+	Entry *JournalEntry `example:"{}"`
+}
+
+type LedgerBalanceOpts struct {
+	AccountId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+
+	// Before limits the balance to postings made before it, the zero time takes every posting.
+	Before time.Time `example:"2024-01-01T00:00:00Z"`
+}
+
+type TransactionStreamOpts struct {
+	AccountId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	From      time.Time `example:"2024-01-01T00:00:00Z"`
+	To        time.Time `example:"2024-02-01T00:00:00Z"`
+}
+
+type HoldSaveOpts struct {
+	Hold *Hold `example:"{}"`
+}
+
+type HoldFindByAccountIdAndIdOpts struct {
+	AccountId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	ID        uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+type HoldReservedOpts struct {
+	AccountId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+type HoldListExpiredOpts struct {
+	Now   time.Time `example:"2024-01-01T09:00:00Z"`
+	Limit int       `example:"50"`
+}
@@ -1,6 +1,7 @@
 package account
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/9ssi7/bank/pkg/rescode"
@@ -26,4 +27,55 @@ var (
 	CurrencyMismatch = rescode.New(4005, http.StatusForbidden, codes.Unavailable, "currency_mismatch", rescode.R{
 		"isCurrencyMismatch": true,
 	})
+	LedgerUnbalanced = rescode.New(4006, http.StatusInternalServerError, codes.Internal, "ledger_unbalanced", rescode.R{
+		"isLedgerUnbalanced": true,
+	})
+	VersionConflict = rescode.New(4007, http.StatusConflict, codes.Aborted, "version_conflict", rescode.R{
+		"isVersionConflict": true,
+	})
+	AmountInvalid = rescode.New(4008, http.StatusUnprocessableEntity, codes.InvalidArgument, "amount_invalid", rescode.R{
+		"isAmountInvalid": true,
+	})
+	StatementRangeInvalid = rescode.New(4009, http.StatusUnprocessableEntity, codes.InvalidArgument, "statement_range_invalid", rescode.R{
+		"isStatementRangeInvalid": true,
+	})
+	IbanInvalid = rescode.New(4010, http.StatusUnprocessableEntity, codes.InvalidArgument, "iban_invalid", rescode.R{
+		"isIbanInvalid": true,
+	})
+	BulkInvalid = rescode.New(4011, http.StatusUnprocessableEntity, codes.InvalidArgument, "bulk_invalid", rescode.R{
+		"isBulkInvalid": true,
+	})
+	TransactionNotFound = rescode.New(4012, http.StatusNotFound, codes.NotFound, "transaction_not_found", rescode.R{
+		"isTransactionNotFound": true,
+	})
+	TransactionNotReversible = rescode.New(4013, http.StatusForbidden, codes.FailedPrecondition, "transaction_not_reversible", rescode.R{
+		"isTransactionNotReversible": true,
+	})
+	TransactionAlreadyReversed = rescode.New(4014, http.StatusConflict, codes.AlreadyExists, "transaction_already_reversed", rescode.R{
+		"isTransactionAlreadyReversed": true,
+	})
+	HoldNotFound = rescode.New(4015, http.StatusNotFound, codes.NotFound, "hold_not_found", rescode.R{
+		"isHoldNotFound": true,
+	})
+	HoldNotActive = rescode.New(4016, http.StatusConflict, codes.FailedPrecondition, "hold_not_active", rescode.R{
+		"isHoldNotActive": true,
+	})
+	HoldAmountExceeded = rescode.New(4017, http.StatusUnprocessableEntity, codes.InvalidArgument, "hold_amount_exceeded", rescode.R{
+		"isHoldAmountExceeded": true,
+	})
+	LimitExceeded = rescode.New(4018, http.StatusForbidden, codes.ResourceExhausted, "limit_exceeded", rescode.R{
+		"isLimitExceeded": true,
+	})
+	StepUpRequired = rescode.New(4019, http.StatusForbidden, codes.PermissionDenied, "step_up_required", rescode.R{
+		"isStepUpRequired": true,
+	})
 )
+
+// ErrIbanTaken is returned when an account is saved with an iban another account already has.
+var ErrIbanTaken = errors.New("iban already in use")
+
+// ErrAlreadyReversed is returned when a second reversal of the same transaction is saved.
+var ErrAlreadyReversed = errors.New("transaction already reversed")
+
+// ErrVersionConflict is wrapped by VersionConflict when an account row was changed by someone else.
+var ErrVersionConflict = errors.New("account changed since it was read")
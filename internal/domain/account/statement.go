@@ -0,0 +1,20 @@
+package account
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Statement covers the transactions of an account booked from From (inclusive) to To (exclusive).
+// The balances come from the ledger, so they hold even when the range starts mid history.
+type Statement struct {
+	ID        uuid.UUID       `json:"id"`
+	Account   *Account        `json:"account"`
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Opening   decimal.Decimal `json:"opening"`
+	Closing   decimal.Decimal `json:"closing"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -23,6 +23,7 @@ const (
 	TransactionKindDeposit    TransactionKind = "deposit"
 	TransactionKindTransfer   TransactionKind = "transfer"
 	TransactionKindFee        TransactionKind = "fee"
+	TransactionKindReversal   TransactionKind = "reversal"
 )
 
 const (
@@ -36,6 +37,7 @@ type TransactionListItem struct {
 	AccountId   *uuid.UUID `json:"account_id,omitempty"`
 	AccountName *string    `json:"account_name,omitempty"`
 	Amount      string     `json:"amount"`
+	Currency    string     `json:"currency"`
 	Description string     `json:"description"`
 	Kind        string     `json:"kind"`
 	Direction   string     `json:"direction"`
@@ -47,14 +49,75 @@ type Transaction struct {
 	SenderId    uuid.UUID       `json:"sender_id"`
 	ReceiverId  uuid.UUID       `json:"receiver_id"`
 	Amount      decimal.Decimal `json:"amount"`
+	Currency    string          `json:"currency"`
 	Description string          `json:"description"`
 	Kind        TransactionKind `json:"kind"`
 
+	// The receiver side differs from the sender side only for cross currency transfers,
+	// FxRate and FxRateId point at the rate snapshot used for the conversion.
+	ReceiverAmount   decimal.Decimal     `json:"receiver_amount"`
+	ReceiverCurrency string              `json:"receiver_currency"`
+	FxRate           decimal.NullDecimal `json:"fx_rate"`
+	FxRateId         *uuid.UUID          `json:"fx_rate_id"`
+
+	// ParentId links a fee to the transaction it was charged for.
+	ParentId *uuid.UUID `json:"parent_id"`
+
+	// ReferenceId links a reversal to the transaction it compensates, a transaction has at most one reversal.
+	ReferenceId *uuid.UUID `json:"reference_id"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	DeletedAt time.Time `json:"deleted_at"`
 }
 
+// Exchange records that the receiver got the amount in another currency at the given rate.
+func (t *Transaction) Exchange(rateId uuid.UUID, rate decimal.Decimal, amount decimal.Decimal, currency string) {
+	t.FxRateId = &rateId
+	t.FxRate = decimal.NewNullDecimal(rate)
+	t.ReceiverAmount = amount
+	t.ReceiverCurrency = currency
+}
+
+// Reverse returns the compensating transaction, it sends the money back the way it came.
+// The reversal of a fee refunds it to the account it was charged from.
+func (t *Transaction) Reverse(description string) *Transaction {
+	return &Transaction{
+		SenderId:         t.ReceiverId,
+		ReceiverId:       t.SenderId,
+		Amount:           t.ReceiverAmount,
+		Currency:         t.ReceiverCurrency,
+		Description:      description,
+		Kind:             TransactionKindReversal,
+		ReceiverAmount:   t.Amount,
+		ReceiverCurrency: t.Currency,
+		FxRate:           t.FxRate,
+		FxRateId:         t.FxRateId,
+		ReferenceId:      &t.ID,
+	}
+}
+
+// IsReversible reports whether the transaction can be reversed, only transfers between two accounts can.
+// Their fees are reversed along with them.
+func (t *Transaction) IsReversible() bool {
+	return t.Kind == TransactionKindTransfer && !t.IsItself()
+}
+
+// SignedFor returns the effect of the transaction on the given account, in the currency of that account.
+// Deposits and refunded fees credit an account on its own, withdrawals and fees debit it.
+func (t *Transaction) SignedFor(accountId uuid.UUID) decimal.Decimal {
+	if t.IsItself() {
+		if t.Kind == TransactionKindDeposit || t.Kind == TransactionKindReversal {
+			return t.Amount
+		}
+		return t.Amount.Neg()
+	}
+	if t.IsUserSender(accountId) {
+		return t.Amount.Neg()
+	}
+	return t.ReceiverAmount
+}
+
 func (t *Transaction) IsItself() bool {
 	return t.SenderId == t.ReceiverId
 }
@@ -71,16 +134,22 @@ type TransactionConfig struct {
 	SenderId    uuid.UUID       `example:"00000000-0000-0000-0000-000000000000"`
 	ReceiverId  uuid.UUID       `example:"00000000-0000-0000-0000-000000000000"`
 	Amount      decimal.Decimal `example:"100.00"`
+	Currency    string          `example:"EUR"` // ISO 4217 currency code
 	Description string          `example:"Transfer"`
 	Kind        TransactionKind `example:"withdrawal"`
+	ParentId    *uuid.UUID      `example:"00000000-0000-0000-0000-000000000000"`
 }
 
 func NewTransaction(cnf TransactionConfig) *Transaction {
 	return &Transaction{
-		SenderId:    cnf.SenderId,
-		ReceiverId:  cnf.ReceiverId,
-		Amount:      cnf.Amount,
-		Description: cnf.Description,
-		Kind:        cnf.Kind,
+		SenderId:         cnf.SenderId,
+		ReceiverId:       cnf.ReceiverId,
+		Amount:           cnf.Amount,
+		Currency:         cnf.Currency,
+		Description:      cnf.Description,
+		Kind:             cnf.Kind,
+		ParentId:         cnf.ParentId,
+		ReceiverAmount:   cnf.Amount,
+		ReceiverCurrency: cnf.Currency,
 	}
 }
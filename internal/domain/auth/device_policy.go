@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"net"
+	"time"
+)
+
+// IpMode is how much of the ip address a session was opened from the later calls have to keep.
+type IpMode string
+
+const (
+	// IpModeStrict takes the very same address only.
+	IpModeStrict IpMode = "strict"
+
+	// IpModePrefix takes any address in the same network, a /24 of ipv4 or a /48 of ipv6 by default.
+	IpModePrefix IpMode = "prefix"
+
+	// IpModeDevice trusts the device id and takes any address.
+	IpModeDevice IpMode = "device"
+
+	// IpModeRisk scores the move, see DevicePolicy.Risk, and takes it below the threshold.
+	IpModeRisk IpMode = "risk"
+)
+
+// DeviceDecision is what becomes of a call that comes from another address than the session has.
+type DeviceDecision int
+
+const (
+	DeviceAllow DeviceDecision = iota
+	DeviceReverify
+	DeviceReject
+)
+
+const (
+	defaultPrefixV4      = 24
+	defaultPrefixV6      = 48
+	defaultRiskThreshold = 50
+)
+
+// DevicePolicy decides on the calls of a session coming from an address it was not opened from.
+// The zero policy is strict and rejects them, Reverify asks the user for an emailed code instead.
+type DevicePolicy struct {
+	Mode          IpMode
+	PrefixV4      int
+	PrefixV6      int
+	RiskThreshold int
+	Reverify      bool
+}
+
+func (p DevicePolicy) Evaluate(s *Session, ip string, now time.Time) DeviceDecision {
+	if s.IpAddress == ip || p.trusts(s, ip, now) {
+		return DeviceAllow
+	}
+	if p.Reverify {
+		return DeviceReverify
+	}
+	return DeviceReject
+}
+
+func (p DevicePolicy) trusts(s *Session, ip string, now time.Time) bool {
+	switch p.Mode {
+	case IpModeDevice:
+		return true
+	case IpModePrefix:
+		return p.samePrefix(s.IpAddress, ip)
+	case IpModeRisk:
+		return p.Risk(s, ip, now) < p.riskThreshold()
+	default:
+		return false
+	}
+}
+
+// Risk scores a move of the session to the address, a move within the network scores low,
+// one to another network or address family higher, and every move of the last hour adds to it.
+func (p DevicePolicy) Risk(s *Session, ip string, now time.Time) int {
+	from, to := net.ParseIP(s.IpAddress), net.ParseIP(ip)
+	if from == nil || to == nil {
+		return 100
+	}
+	score := 40
+	switch {
+	case p.samePrefix(s.IpAddress, ip):
+		score = 10
+	case (from.To4() == nil) != (to.To4() == nil):
+		// Dual stack devices move between the families of the same network all the time.
+		score = 30
+	}
+	for _, c := range s.IpChanges {
+		if now.Sub(c.At) < time.Hour {
+			score += 15
+		}
+	}
+	return score
+}
+
+func (p DevicePolicy) samePrefix(a, b string) bool {
+	x, y := net.ParseIP(a), net.ParseIP(b)
+	if x == nil || y == nil {
+		return false
+	}
+	if x4, y4 := x.To4(), y.To4(); x4 != nil || y4 != nil {
+		if x4 == nil || y4 == nil {
+			return false
+		}
+		mask := net.CIDRMask(p.prefixV4(), 32)
+		return x4.Mask(mask).Equal(y4.Mask(mask))
+	}
+	mask := net.CIDRMask(p.prefixV6(), 128)
+	return x.Mask(mask).Equal(y.Mask(mask))
+}
+
+func (p DevicePolicy) prefixV4() int {
+	if p.PrefixV4 <= 0 || p.PrefixV4 > 32 {
+		return defaultPrefixV4
+	}
+	return p.PrefixV4
+}
+
+func (p DevicePolicy) prefixV6() int {
+	if p.PrefixV6 <= 0 || p.PrefixV6 > 128 {
+		return defaultPrefixV6
+	}
+	return p.PrefixV6
+}
+
+func (p DevicePolicy) riskThreshold() int {
+	if p.RiskThreshold <= 0 {
+		return defaultRiskThreshold
+	}
+	return p.RiskThreshold
+}
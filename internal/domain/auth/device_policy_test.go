@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDevicePolicy_Evaluate(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	moved := []IpChange{{From: "10.0.0.1", To: "10.0.0.1", At: now.Add(-30 * time.Minute)}}
+	tests := []struct {
+		name    string
+		policy  DevicePolicy
+		changes []IpChange
+		ip      string
+		want    DeviceDecision
+	}{
+		{"same address", DevicePolicy{}, nil, "10.0.0.1", DeviceAllow},
+		{"zero policy rejects", DevicePolicy{}, nil, "10.0.0.2", DeviceReject},
+		{"unknown mode rejects", DevicePolicy{Mode: "other"}, nil, "10.0.0.2", DeviceReject},
+		{"strict reverifies", DevicePolicy{Mode: IpModeStrict, Reverify: true}, nil, "10.0.0.2", DeviceReverify},
+		{"device takes any address", DevicePolicy{Mode: IpModeDevice}, nil, "192.168.1.1", DeviceAllow},
+		{"prefix takes the network", DevicePolicy{Mode: IpModePrefix}, nil, "10.0.0.200", DeviceAllow},
+		{"prefix rejects another network", DevicePolicy{Mode: IpModePrefix}, nil, "10.0.1.1", DeviceReject},
+		{"prefix reverifies another network", DevicePolicy{Mode: IpModePrefix, Reverify: true}, nil, "10.0.1.1", DeviceReverify},
+		{"risk takes another network", DevicePolicy{Mode: IpModeRisk}, nil, "192.168.1.1", DeviceAllow},
+		{"risk rejects a second move in the hour", DevicePolicy{Mode: IpModeRisk}, moved, "192.168.1.1", DeviceReject},
+		{"risk takes it above a higher threshold", DevicePolicy{Mode: IpModeRisk, RiskThreshold: 60}, moved, "192.168.1.1", DeviceAllow},
+		{"risk reverifies an invalid address", DevicePolicy{Mode: IpModeRisk, Reverify: true}, nil, "invalid", DeviceReverify},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Session{IpAddress: "10.0.0.1", IpChanges: tt.changes}
+			if got := tt.policy.Evaluate(s, tt.ip, now); got != tt.want {
+				t.Errorf("Evaluate(%s) = %d, want %d", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDevicePolicy_SamePrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DevicePolicy
+		a, b   string
+		want   bool
+	}{
+		{"ipv4 same /24", DevicePolicy{}, "10.0.0.1", "10.0.0.200", true},
+		{"ipv4 other /24", DevicePolicy{}, "10.0.0.1", "10.0.1.1", false},
+		{"ipv4 /16", DevicePolicy{PrefixV4: 16}, "10.0.0.1", "10.0.200.1", true},
+		{"ipv4 /32", DevicePolicy{PrefixV4: 32}, "10.0.0.1", "10.0.0.2", false},
+		{"ipv4 prefix above 32 falls back", DevicePolicy{PrefixV4: 33}, "10.0.0.1", "10.0.0.200", true},
+		{"ipv4 negative prefix falls back", DevicePolicy{PrefixV4: -8}, "10.0.0.1", "10.0.1.1", false},
+		{"ipv4 mapped ipv6", DevicePolicy{}, "::ffff:10.0.0.1", "10.0.0.2", true},
+		{"ipv6 same /48", DevicePolicy{}, "2001:db8:1::1", "2001:db8:1:ffff::1", true},
+		{"ipv6 other /48", DevicePolicy{}, "2001:db8:1::1", "2001:db8:2::1", false},
+		{"ipv6 /64", DevicePolicy{PrefixV6: 64}, "2001:db8:1::1", "2001:db8:1:ffff::1", false},
+		{"ipv6 prefix above 128 falls back", DevicePolicy{PrefixV6: 129}, "2001:db8:1::1", "2001:db8:1:ffff::1", true},
+		{"ipv6 prefix does not apply to ipv4", DevicePolicy{PrefixV6: 8}, "10.0.0.1", "10.0.1.1", false},
+		{"ipv4 against ipv6", DevicePolicy{}, "10.0.0.1", "2001:db8:1::1", false},
+		{"invalid address", DevicePolicy{}, "10.0.0.1", "invalid", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.samePrefix(tt.a, tt.b); got != tt.want {
+				t.Errorf("samePrefix(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDevicePolicy_Risk(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	recent := IpChange{From: "10.0.0.9", To: "10.0.0.1", At: now.Add(-59 * time.Minute)}
+	old := IpChange{From: "10.0.0.9", To: "10.0.0.1", At: now.Add(-time.Hour)}
+	tests := []struct {
+		name    string
+		changes []IpChange
+		ip      string
+		want    int
+	}{
+		{"same network", nil, "10.0.0.2", 10},
+		{"other network", nil, "192.168.1.1", 40},
+		{"other family", nil, "2001:db8:1::1", 30},
+		{"invalid address", nil, "invalid", 100},
+		{"move in the last hour", []IpChange{recent}, "192.168.1.1", 55},
+		{"every move in the last hour", []IpChange{recent, recent, recent}, "10.0.0.2", 55},
+		{"move an hour ago", []IpChange{old}, "192.168.1.1", 40},
+		{"only the recent moves", []IpChange{old, recent}, "192.168.1.1", 55},
+	}
+
+	p := DevicePolicy{Mode: IpModeRisk}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Session{IpAddress: "10.0.0.1", IpChanges: tt.changes}
+			if got := p.Risk(s, tt.ip, now); got != tt.want {
+				t.Errorf("Risk(%s) = %d, want %d", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
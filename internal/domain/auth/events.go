@@ -3,7 +3,13 @@ package auth
 import "github.com/9ssi7/bank/pkg/agent"
 
 const (
-	SubjectLoginStarted = "Auth.LoginStart"
+	SubjectLoginStarted       = "Auth.LoginStart"
+	SubjectRefreshTokenReused = "Auth.RefreshTokenReused"
+	SubjectSessionReverify    = "Auth.SessionReverify"
+	SubjectTotpEnrollStarted  = "Auth.TotpEnrollStarted"
+	SubjectTotpEnrolled       = "Auth.TotpEnrolled"
+	SubjectPasskeyStarted     = "Auth.PasskeyStarted"
+	SubjectPasskeyAdded       = "Auth.PasskeyAdded"
 )
 
 type EventLoginStarted struct {
@@ -11,3 +17,50 @@ type EventLoginStarted struct {
 	Code   string       `json:"code"`
 	Device agent.Device `json:"device"`
 }
+
+// EventRefreshTokenReused tells the user a refresh token was presented again after it was rotated out,
+// the session it belonged to is revoked by then.
+type EventRefreshTokenReused struct {
+	Email      string `json:"email"`
+	Name       string `json:"name"`
+	DeviceName string `json:"device_name"`
+	DeviceOS   string `json:"device_os"`
+	IpAddress  string `json:"ip_address"`
+}
+
+// EventSessionReverify carries the code a session moves to another address with, Device is the session
+// along with the address it moves to.
+type EventSessionReverify struct {
+	Email  string       `json:"email"`
+	Code   string       `json:"code"`
+	Device agent.Device `json:"device"`
+}
+
+// EventTotpEnrollStarted carries the code an authenticator app is enrolled with, so only the owner of the
+// mailbox can add one.
+type EventTotpEnrollStarted struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Code  string `json:"code"`
+}
+
+// EventTotpEnrolled tells the user an authenticator app was added as a second factor.
+type EventTotpEnrolled struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// EventPasskeyStarted carries the code a passkey is registered with, so only the owner of the mailbox
+// or of the authenticator app can add one.
+type EventPasskeyStarted struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Code  string `json:"code"`
+}
+
+// EventPasskeyAdded tells the user a passkey was registered, it logs in without any other code.
+type EventPasskeyAdded struct {
+	Email       string `json:"email"`
+	Name        string `json:"name"`
+	PasskeyName string `json:"passkey_name"`
+}
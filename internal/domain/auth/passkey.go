@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Passkey is a webauthn credential a user logs in with instead of an emailed code,
+// PublicKey is cose encoded and SignCount is the last signature counter the authenticator reported.
+type Passkey struct {
+	ID           uuid.UUID  `json:"id"`
+	UserId       uuid.UUID  `json:"user_id"`
+	Name         string     `json:"name"`
+	CredentialId []byte     `json:"-"`
+	PublicKey    []byte     `json:"-"`
+	SignCount    uint32     `json:"-"`
+	Aaguid       []byte     `json:"-"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Use records a login with the passkey along with the counter the authenticator signed it with.
+func (p *Passkey) Use(signCount uint32, now time.Time) {
+	p.SignCount = signCount
+	p.LastUsedAt = &now
+}
+
+type PasskeyConfig struct {
+	UserId       uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name         string    `example:"MacBook"`
+	CredentialId []byte
+	PublicKey    []byte
+	SignCount    uint32
+	Aaguid       []byte
+}
+
+func NewPasskey(cnf PasskeyConfig) *Passkey {
+	return &Passkey{
+		UserId:       cnf.UserId,
+		Name:         cnf.Name,
+		CredentialId: cnf.CredentialId,
+		PublicKey:    cnf.PublicKey,
+		SignCount:    cnf.SignCount,
+		Aaguid:       cnf.Aaguid,
+		CreatedAt:    time.Now(),
+	}
+}
+
+type WebauthnCeremony string
+
+const (
+	WebauthnCeremonyRegister WebauthnCeremony = "register"
+	WebauthnCeremonyLogin    WebauthnCeremony = "login"
+)
+
+// WebauthnChallenge is a ceremony in flight, a registration belongs to the user who started it.
+type WebauthnChallenge struct {
+	Ceremony  WebauthnCeremony `json:"ceremony"`
+	Challenge []byte           `json:"challenge"`
+	UserId    uuid.UUID        `json:"user_id"`
+	DeviceId  string           `json:"device_id"`
+}
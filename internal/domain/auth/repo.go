@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 
+	"github.com/9ssi7/bank/pkg/txadapter"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -21,6 +22,34 @@ type SessionRepo interface {
 	Destroy(ctx context.Context, t trace.Tracer, opts SessionDestroyOpts) error
 }
 
+// RefreshFamilyRepo remembers the refresh tokens a family already rotated out, so a replayed one can be told apart.
+type RefreshFamilyRepo interface {
+	MarkUsed(ctx context.Context, t trace.Tracer, opts RefreshFamilyMarkUsedOpts) (bool, error)
+	IsUsed(ctx context.Context, t trace.Tracer, opts RefreshFamilyIsUsedOpts) (bool, error)
+}
+
+type TotpRepo interface {
+	txadapter.Repo
+	Save(ctx context.Context, t trace.Tracer, opts TotpSaveOpts) error
+	FindByUserId(ctx context.Context, t trace.Tracer, opts TotpFindByUserIdOpts) (*Totp, error)
+	Delete(ctx context.Context, t trace.Tracer, opts TotpDeleteOpts) error
+	SaveRecoveryCodes(ctx context.Context, t trace.Tracer, opts TotpSaveRecoveryCodesOpts) error
+	UseRecoveryCode(ctx context.Context, t trace.Tracer, opts TotpUseRecoveryCodeOpts) (bool, error)
+}
+
+type PasskeyRepo interface {
+	txadapter.Repo
+	Save(ctx context.Context, t trace.Tracer, opts PasskeySaveOpts) error
+	FindByCredentialId(ctx context.Context, t trace.Tracer, opts PasskeyFindByCredentialIdOpts) (*Passkey, error)
+	ListByUserId(ctx context.Context, t trace.Tracer, opts PasskeyListByUserIdOpts) ([]*Passkey, error)
+}
+
+// WebauthnRepo keeps the challenges of the ceremonies in flight, Take hands a challenge out once.
+type WebauthnRepo interface {
+	Save(ctx context.Context, t trace.Tracer, opts WebauthnSaveOpts) error
+	Take(ctx context.Context, t trace.Tracer, opts WebauthnTakeOpts) (*WebauthnChallenge, error)
+}
+
 type VerifySaveOpts struct {
 	Token  string  `example:"token"`
 	Verify *Verify `example:"{}"`
@@ -59,3 +88,58 @@ type SessionDestroyOpts struct {
 type FindAllByUserOpts struct {
 	UserId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
 }
+
+type RefreshFamilyMarkUsedOpts struct {
+	Family string `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Token  string `example:"token"`
+}
+
+type RefreshFamilyIsUsedOpts struct {
+	Family string `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Token  string `example:"token"`
+}
+
+type TotpSaveOpts struct {
+	Totp *Totp `example:"{}"`
+}
+
+type TotpFindByUserIdOpts struct {
+	UserId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+type TotpDeleteOpts struct {
+	UserId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+// Hashes replace the recovery codes the user had before.
+type TotpSaveRecoveryCodesOpts struct {
+	UserId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Hashes []string  `example:"[]"`
+}
+
+type TotpUseRecoveryCodeOpts struct {
+	UserId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Hash   string    `example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+type PasskeySaveOpts struct {
+	Passkey *Passkey `example:"{}"`
+}
+
+type PasskeyFindByCredentialIdOpts struct {
+	CredentialId []byte `example:"Y3JlZGVudGlhbA"`
+}
+
+type PasskeyListByUserIdOpts struct {
+	UserId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+type WebauthnSaveOpts struct {
+	Token     string             `example:"token"`
+	Challenge *WebauthnChallenge `example:"{}"`
+}
+
+type WebauthnTakeOpts struct {
+	Token    string `example:"token"`
+	DeviceId string `example:"device_id"`
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/9ssi7/bank/pkg/rescode"
@@ -32,4 +33,28 @@ var (
 	Unauthorized = rescode.New(3007, http.StatusForbidden, codes.Unauthenticated, "unauthorized", rescode.R{
 		"isUnauthorized": true,
 	})
+	TotpAlreadyEnrolled = rescode.New(3008, http.StatusConflict, codes.AlreadyExists, "totp_already_enrolled", rescode.R{
+		"isAlreadyEnrolled": true,
+	})
+	TotpNotEnrolled = rescode.New(3009, http.StatusForbidden, codes.FailedPrecondition, "totp_not_enrolled", rescode.R{
+		"isNotEnrolled": true,
+	})
+	WebauthnInvalid = rescode.New(3010, http.StatusForbidden, codes.Unauthenticated, "webauthn_invalid", rescode.R{
+		"isInvalid": true,
+	})
+	PasskeyAlreadyRegistered = rescode.New(3011, http.StatusConflict, codes.AlreadyExists, "passkey_already_registered", rescode.R{
+		"isAlreadyRegistered": true,
+	})
+	SessionNotFound = rescode.New(3012, http.StatusNotFound, codes.NotFound, "session_not_found", rescode.R{
+		"isNotFound": true,
+	})
+	RefreshTokenReused = rescode.New(3013, http.StatusForbidden, codes.Unauthenticated, "refresh_token_reused", rescode.R{
+		"isReused": true,
+	})
+	ReverifyRequired = rescode.New(3014, http.StatusForbidden, codes.Unauthenticated, "session_reverify_required", rescode.R{
+		"isReverifyRequired": true,
+	})
 )
+
+// ErrPasskeyTaken is returned when a passkey is saved with a credential id another passkey already has.
+var ErrPasskeyTaken = errors.New("passkey credential already registered")
@@ -6,6 +6,19 @@ import (
 	"github.com/9ssi7/bank/pkg/agent"
 )
 
+type SessionListItem struct {
+	DeviceId   string    `json:"device_id"`
+	DeviceName string    `json:"device_name"`
+	DeviceType string    `json:"device_type"`
+	DeviceOS   string    `json:"device_os"`
+	IpAddress  string    `json:"ip_address"`
+	LastLogin  time.Time `json:"last_login"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Current marks the session of the device the list is asked from.
+	Current bool `json:"current"`
+}
+
 type Session struct {
 	DeviceId     string    `json:"device_id"`
 	DeviceName   string    `json:"device_name"`
@@ -15,9 +28,31 @@ type Session struct {
 	FcmToken     string    `json:"fcm_token"`
 	RefreshToken string    `json:"refresh_token"`
 	AccessToken  string    `json:"access_token"`
+	Family       string    `json:"family"` // shared by every refresh token rotated out of the login of the session
 	LastLogin    time.Time `json:"last_login"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	// IpChanges are the last moves of the session the device policy took, the newest last.
+	IpChanges []IpChange `json:"ip_changes,omitempty"`
+
+	// PendingIp is the address the session waits an emailed code for before it moves there.
+	PendingIp          string `json:"pending_ip,omitempty"`
+	PendingVerifyToken string `json:"pending_verify_token,omitempty"`
+}
+
+type IpChange struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// MaxIpChanges is how many moves a session remembers.
+const MaxIpChanges = 10
+
+// SessionReverifyPayload is what a code sent for a session move is bound to.
+type SessionReverifyPayload struct {
+	IpAddress string `json:"ip_address"`
 }
 
 func (s *Session) SetFromDevice(d *agent.Device) {
@@ -27,12 +62,33 @@ func (s *Session) SetFromDevice(d *agent.Device) {
 	s.IpAddress = d.IP
 }
 
-func (s *Session) IsRefreshValid(accessToken, refreshToken string, ipAddress string) bool {
-	return s.RefreshToken == refreshToken && s.AccessToken == accessToken && s.IpAddress == ipAddress
+// IsRefreshValid checks the tokens only, the address is up to the DevicePolicy.
+func (s *Session) IsRefreshValid(accessToken, refreshToken string) bool {
+	return s.RefreshToken == refreshToken && s.AccessToken == accessToken
 }
 
-func (s *Session) IsAccessValid(accessToken, ipAddress string) bool {
-	return s.AccessToken == accessToken && s.IpAddress == ipAddress
+// IsAccessValid checks the token only, the address is up to the DevicePolicy.
+func (s *Session) IsAccessValid(accessToken string) bool {
+	return s.AccessToken == accessToken
+}
+
+// MoveTo records the move of the session to the address and drops a pending one.
+func (s *Session) MoveTo(ip string) {
+	s.IpChanges = append(s.IpChanges, IpChange{From: s.IpAddress, To: ip, At: time.Now()})
+	if len(s.IpChanges) > MaxIpChanges {
+		s.IpChanges = s.IpChanges[len(s.IpChanges)-MaxIpChanges:]
+	}
+	s.IpAddress = ip
+	s.PendingIp = ""
+	s.PendingVerifyToken = ""
+	s.UpdatedAt = time.Now()
+}
+
+// AwaitReverify keeps the session where it is until the code behind the token is confirmed from the address.
+func (s *Session) AwaitReverify(ip string, verifyToken string) {
+	s.PendingIp = ip
+	s.PendingVerifyToken = verifyToken
+	s.UpdatedAt = time.Now()
 }
 
 func (s *Session) Refresh(token string) {
@@ -41,6 +97,12 @@ func (s *Session) Refresh(token string) {
 	s.UpdatedAt = time.Now()
 }
 
+// Rotate swaps both tokens of the session, the refresh token it had becomes a used one of the family.
+func (s *Session) Rotate(accessToken, refreshToken string) {
+	s.Refresh(accessToken)
+	s.RefreshToken = refreshToken
+}
+
 func (s *Session) VerifyToken(token string) bool {
 	return s.AccessToken == token
 }
@@ -58,6 +120,7 @@ type SessionConfig struct {
 	DeviceId     string `example:"550e8400-e29b-41d4-a716-446655440000"`
 	AccessToken  string
 	RefreshToken string
+	Family       string
 }
 
 func NewSession(cnf SessionConfig) *Session {
@@ -73,5 +136,6 @@ func NewSession(cnf SessionConfig) *Session {
 		UpdatedAt:    t,
 		RefreshToken: cnf.RefreshToken,
 		AccessToken:  cnf.AccessToken,
+		Family:       cnf.Family,
 	}
 }
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/9ssi7/bank/pkg/totp"
+	"github.com/google/uuid"
+)
+
+// RecoveryCodeCount is how many recovery codes a user gets when the authenticator app is confirmed.
+const RecoveryCodeCount = 10
+
+const (
+	// TotpMaxFailures is how many codes in a row may be rejected before the app is locked.
+	TotpMaxFailures = 5
+
+	// TotpLockout is how long a locked app takes no codes, recovery codes included.
+	TotpLockout = 15 * time.Minute
+)
+
+// Totp is the authenticator app of a user, it is a second factor only once a code of it was confirmed.
+// LastStep is the time step of the last accepted code, so no code is accepted twice.
+// Failures counts the codes rejected in a row, see Fail.
+type Totp struct {
+	UserId      uuid.UUID  `json:"user_id"`
+	Secret      string     `json:"-"`
+	LastStep    int64      `json:"-"`
+	Failures    int        `json:"-"`
+	LockedUntil *time.Time `json:"-"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TotpEnrollment is what an authenticator app needs to enrol, Uri is usually shown as a qr code.
+// VerifyToken is the token of the code emailed to confirm the enrolment with.
+type TotpEnrollment struct {
+	Secret      string `json:"secret"`
+	Uri         string `json:"uri"`
+	VerifyToken string `json:"verify_token"`
+}
+
+func (t *Totp) IsConfirmed() bool {
+	return t.ConfirmedAt != nil
+}
+
+// Check reports whether the code is a fresh code of the app, an accepted code uses up its time step.
+func (t *Totp) Check(code string, now time.Time) bool {
+	step, ok := totp.Match(t.Secret, code, now, t.LastStep)
+	if ok {
+		t.LastStep = step
+		t.UpdatedAt = now
+	}
+	return ok
+}
+
+// IsLocked reports whether the app takes no codes for now, too many were rejected in a row.
+func (t *Totp) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
+
+// Fail counts a rejected code, the app is locked for TotpLockout once TotpMaxFailures were rejected in a row.
+func (t *Totp) Fail(now time.Time) {
+	t.Failures++
+	if t.Failures >= TotpMaxFailures {
+		until := now.Add(TotpLockout)
+		t.LockedUntil = &until
+		t.Failures = 0
+	}
+	t.UpdatedAt = now
+}
+
+// Pass starts the count of rejected codes over after one was accepted.
+func (t *Totp) Pass(now time.Time) {
+	t.Failures = 0
+	t.LockedUntil = nil
+	t.UpdatedAt = now
+}
+
+func (t *Totp) Confirm(now time.Time) {
+	t.ConfirmedAt = &now
+	t.UpdatedAt = now
+}
+
+func NewTotp(userId uuid.UUID, secret string) *Totp {
+	now := time.Now()
+	return &Totp{
+		UserId:    userId,
+		Secret:    secret,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// NewRecoveryCodes returns fresh single use codes, only their hashes are kept.
+func NewRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		h := hex.EncodeToString(b)
+		codes[i] = h[:5] + "-" + h[5:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the hash a recovery code is kept as, case and surrounding spaces do not matter.
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
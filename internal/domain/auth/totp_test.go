@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTotp_Fail(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	tp := NewTotp(uuid.New(), "")
+	for i := 1; i < TotpMaxFailures; i++ {
+		tp.Fail(now)
+		if tp.IsLocked(now) {
+			t.Fatalf("locked after %d rejected codes, want %d", i, TotpMaxFailures)
+		}
+	}
+	tp.Fail(now)
+	if !tp.IsLocked(now) {
+		t.Fatalf("not locked after %d rejected codes", TotpMaxFailures)
+	}
+	if !tp.IsLocked(now.Add(TotpLockout - time.Second)) {
+		t.Errorf("lock ended before TotpLockout")
+	}
+	if tp.IsLocked(now.Add(TotpLockout)) {
+		t.Errorf("still locked after TotpLockout")
+	}
+	if tp.Failures != 0 {
+		t.Errorf("Failures = %d after the lock, want 0", tp.Failures)
+	}
+}
+
+func TestTotp_Pass(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	tp := NewTotp(uuid.New(), "")
+	for i := 1; i < TotpMaxFailures; i++ {
+		tp.Fail(now)
+	}
+	tp.Pass(now)
+	tp.Fail(now)
+	if tp.IsLocked(now) || tp.Failures != 1 {
+		t.Errorf("accepted code did not start the count over, Failures = %d", tp.Failures)
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -9,6 +10,25 @@ import (
 	"github.com/google/uuid"
 )
 
+type VerifyPurpose string
+
+const (
+	VerifyPurposeLogin    VerifyPurpose = "login"
+	VerifyPurposeTransfer VerifyPurpose = "transfer"
+	VerifyPurposeSession  VerifyPurpose = "session"
+	VerifyPurposeTotp     VerifyPurpose = "totp"
+	VerifyPurposePasskey  VerifyPurpose = "passkey"
+)
+
+// Factor is what a login is verified with, the emailed code unless the user says otherwise.
+type Factor string
+
+const (
+	FactorEmail    Factor = "email"
+	FactorTotp     Factor = "totp"
+	FactorRecovery Factor = "recovery"
+)
+
 type Verify struct {
 	DeviceId  string    `json:"device_id"`
 	Locale    string    `json:"locale"`
@@ -16,6 +36,19 @@ type Verify struct {
 	Code      string    `json:"code"`
 	TryCount  int       `json:"try_count"`
 	ExpiresAt int64     `json:"expires_at"`
+
+	// Purpose keeps a code from confirming anything but what it was sent for,
+	// Payload carries what the code confirms, e.g. the transfer waiting for it.
+	Purpose VerifyPurpose   `json:"purpose,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// IsFor reports whether the code was sent for the purpose, codes without one were sent for a login.
+func (v *Verify) IsFor(purpose VerifyPurpose) bool {
+	if v.Purpose == "" {
+		return purpose == VerifyPurposeLogin
+	}
+	return v.Purpose == purpose
 }
 
 func (v *Verify) IsExpired() bool {
@@ -34,6 +67,8 @@ type VerifyConfig struct {
 	UserId   uuid.UUID
 	DeviceId string
 	Locale   string
+	Purpose  VerifyPurpose
+	Payload  json.RawMessage
 }
 
 func NewVerify(cnf VerifyConfig) *Verify {
@@ -41,6 +76,8 @@ func NewVerify(cnf VerifyConfig) *Verify {
 		UserId:    cnf.UserId,
 		DeviceId:  cnf.DeviceId,
 		Locale:    cnf.Locale,
+		Purpose:   cnf.Purpose,
+		Payload:   cnf.Payload,
 		Code:      fmt.Sprintf("%04d", rand.Intn(9999)),
 		TryCount:  0,
 		ExpiresAt: time.Now().Add(5 * time.Minute).Unix(),
@@ -0,0 +1,95 @@
+package fee
+
+import (
+	"github.com/9ssi7/bank/pkg/currency"
+	"github.com/shopspring/decimal"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// Tier prices the amounts up to UpTo, a tier without UpTo covers everything above the previous ones.
+type Tier struct {
+	UpTo       decimal.NullDecimal `example:"1000.00"`
+	Flat       decimal.Decimal     `example:"1.00"`
+	Percentage decimal.Decimal     `example:"0.5"`
+}
+
+// Rule prices the transactions of a currency and kind, empty Currency or Kind match any.
+// The fee is the flat part plus the percentage of the amount, plus the tier the amount falls into,
+// kept between Min and Max when they are set.
+type Rule struct {
+	Currency   string              `example:"EUR"`
+	Kind       string              `example:"transfer"`
+	Flat       decimal.Decimal     `example:"1.00"`
+	Percentage decimal.Decimal     `example:"0.1"`
+	Tiers      []Tier              `example:"[]"`
+	Min        decimal.NullDecimal `example:"0.50"`
+	Max        decimal.NullDecimal `example:"25.00"`
+}
+
+func (r *Rule) Matches(currency string, kind string) bool {
+	return (r.Currency == "" || r.Currency == currency) && (r.Kind == "" || r.Kind == kind)
+}
+
+func (r *Rule) specificity() int {
+	s := 0
+	if r.Currency != "" {
+		s += 2
+	}
+	if r.Kind != "" {
+		s++
+	}
+	return s
+}
+
+func (r *Rule) Compute(amount decimal.Decimal) decimal.Decimal {
+	fee := r.Flat.Add(amount.Mul(r.Percentage).Div(hundred))
+	for _, t := range r.Tiers {
+		if !t.UpTo.Valid || amount.LessThanOrEqual(t.UpTo.Decimal) {
+			fee = fee.Add(t.Flat).Add(amount.Mul(t.Percentage).Div(hundred))
+			break
+		}
+	}
+	if r.Min.Valid && fee.LessThan(r.Min.Decimal) {
+		fee = r.Min.Decimal
+	}
+	if r.Max.Valid && fee.GreaterThan(r.Max.Decimal) {
+		fee = r.Max.Decimal
+	}
+	return fee
+}
+
+// Policy picks the most specific rule matching a transaction, a currency match wins over a kind match
+// and earlier rules win ties. Transactions no rule matches are free.
+type Policy struct {
+	Rules []Rule
+}
+
+func NewPolicy(rules ...Rule) *Policy {
+	return &Policy{Rules: rules}
+}
+
+// Compute returns the fee of moving the amount, rounded to the minor unit of the currency.
+func (p *Policy) Compute(amount decimal.Decimal, currencyCode string, kind string) decimal.Decimal {
+	if p == nil {
+		return decimal.Zero
+	}
+	var match *Rule
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Matches(currencyCode, kind) && (match == nil || r.specificity() > match.specificity()) {
+			match = r
+		}
+	}
+	if match == nil {
+		return decimal.Zero
+	}
+	return currency.Round(match.Compute(amount), currencyCode)
+}
+
+// Quote is what a transaction costs before it is executed.
+type Quote struct {
+	Amount   string `json:"amount"`
+	Fee      string `json:"fee"`
+	Currency string `json:"currency"`
+}
@@ -0,0 +1,115 @@
+package fee
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func nd(s string) decimal.NullDecimal {
+	return decimal.NewNullDecimal(d(s))
+}
+
+// examplePolicy is the fee policy of deployments/config.example.yaml.
+func examplePolicy() *Policy {
+	return NewPolicy(
+		Rule{Kind: "transfer", Flat: d("1")},
+		Rule{Currency: "EUR", Kind: "transfer", Percentage: d("0.1"), Min: nd("0.50"), Max: nd("25.00")},
+		Rule{Currency: "TRY", Kind: "withdrawal", Tiers: []Tier{
+			{UpTo: nd("1000"), Flat: d("2")},
+			{Percentage: d("0.2")},
+		}},
+	)
+}
+
+func TestPolicy_Compute(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		kind     string
+		want     string
+	}{
+		{"EUR transfer below min", "100", "EUR", "transfer", "0.50"},
+		{"EUR transfer percentage", "1000", "EUR", "transfer", "1.00"},
+		{"EUR transfer rounded", "1234.56", "EUR", "transfer", "1.23"},
+		{"EUR transfer above max", "100000", "EUR", "transfer", "25.00"},
+		{"USD transfer flat", "500", "USD", "transfer", "1"},
+		{"TRY transfer flat", "100", "TRY", "transfer", "1"},
+		{"TRY withdrawal first tier", "500", "TRY", "withdrawal", "2"},
+		{"TRY withdrawal tier bound", "1000", "TRY", "withdrawal", "2"},
+		{"TRY withdrawal open tier", "5000", "TRY", "withdrawal", "10"},
+		{"EUR withdrawal free", "500", "EUR", "withdrawal", "0"},
+		{"EUR deposit free", "500", "EUR", "deposit", "0"},
+	}
+
+	p := examplePolicy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.Compute(d(tt.amount), tt.currency, tt.kind)
+			if !got.Equal(d(tt.want)) {
+				t.Errorf("Compute(%s, %s, %s) = %s, want %s", tt.amount, tt.currency, tt.kind, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Specificity(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+		want  string
+	}{
+		{"currency beats kind", []Rule{{Kind: "transfer", Flat: d("1")}, {Currency: "EUR", Flat: d("2")}}, "2"},
+		{"currency and kind beat currency", []Rule{{Currency: "EUR", Kind: "transfer", Flat: d("3")}, {Currency: "EUR", Flat: d("2")}}, "3"},
+		{"kind beats catch all", []Rule{{Flat: d("5")}, {Kind: "transfer", Flat: d("1")}}, "1"},
+		{"earlier rule wins ties", []Rule{{Currency: "EUR", Flat: d("2")}, {Currency: "EUR", Flat: d("4")}}, "2"},
+		{"other currency does not match", []Rule{{Currency: "USD", Flat: d("2")}}, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewPolicy(tt.rules...).Compute(d("100"), "EUR", "transfer")
+			if !got.Equal(d(tt.want)) {
+				t.Errorf("Compute() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Nil(t *testing.T) {
+	var p *Policy
+	if got := p.Compute(d("100"), "EUR", "transfer"); !got.IsZero() {
+		t.Errorf("nil policy Compute() = %s, want 0", got)
+	}
+}
+
+func TestRule_Compute(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   Rule
+		amount string
+		want   string
+	}{
+		{"flat and percentage", Rule{Flat: d("1"), Percentage: d("1")}, "200", "3"},
+		{"tier adds to the rule", Rule{Flat: d("1"), Tiers: []Tier{{UpTo: nd("100"), Flat: d("2")}}}, "50", "3"},
+		{"above every bounded tier", Rule{Tiers: []Tier{{UpTo: nd("100"), Flat: d("2")}}}, "150", "0"},
+		{"first matching tier only", Rule{Tiers: []Tier{{UpTo: nd("100"), Flat: d("2")}, {UpTo: nd("1000"), Flat: d("7")}}}, "100", "2"},
+		{"min clamps", Rule{Percentage: d("1"), Min: nd("5")}, "100", "5"},
+		{"max clamps", Rule{Percentage: d("1"), Max: nd("5")}, "1000", "5"},
+		{"zero amount", Rule{Flat: d("1"), Percentage: d("1")}, "0", "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rule.Compute(d(tt.amount))
+			if !got.Equal(d(tt.want)) {
+				t.Errorf("Compute(%s) = %s, want %s", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package fx
+
+import (
+	"context"
+	"time"
+
+	"github.com/9ssi7/bank/pkg/currency"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Rate is a quote for exchanging one unit of Base into Quote.
+// Every rate used for a transfer is kept as a snapshot, so the transfer can be explained later.
+type Rate struct {
+	ID       uuid.UUID       `json:"id"`
+	Base     string          `json:"base"`
+	Quote    string          `json:"quote"`
+	Rate     decimal.Decimal `json:"rate"`
+	Source   string          `json:"source"`
+	QuotedAt time.Time       `json:"quoted_at"`
+}
+
+// Convert exchanges an amount of the base currency, rounded to the minor unit of the quote currency.
+func (r *Rate) Convert(amount decimal.Decimal) decimal.Decimal {
+	return currency.Round(amount.Mul(r.Rate), r.Quote)
+}
+
+// Provider quotes the current rate between two currencies.
+type Provider interface {
+	Rate(ctx context.Context, base string, quote string) (*Rate, error)
+}
+
+type Config struct {
+	Base   string          `example:"EUR"`
+	Quote  string          `example:"USD"`
+	Rate   decimal.Decimal `example:"1.0842"`
+	Source string          `example:"static"`
+}
+
+func New(cnf Config) *Rate {
+	return &Rate{
+		Base:     cnf.Base,
+		Quote:    cnf.Quote,
+		Rate:     cnf.Rate,
+		Source:   cnf.Source,
+		QuotedAt: time.Now(),
+	}
+}
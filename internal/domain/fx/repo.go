@@ -0,0 +1,17 @@
+package fx
+
+import (
+	"context"
+
+	"github.com/9ssi7/bank/pkg/txadapter"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Repo interface {
+	txadapter.Repo
+	Save(ctx context.Context, t trace.Tracer, opts SaveOpts) error
+}
+
+type SaveOpts struct {
+	Rate *Rate `example:"{}"`
+}
@@ -0,0 +1,17 @@
+package fx
+
+import (
+	"net/http"
+
+	"github.com/9ssi7/bank/pkg/rescode"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	RateNotFound = rescode.New(6000, http.StatusUnprocessableEntity, codes.FailedPrecondition, "fx_rate_not_found", rescode.R{
+		"isRateNotFound": true,
+	})
+	AmountTooSmall = rescode.New(6001, http.StatusUnprocessableEntity, codes.InvalidArgument, "fx_amount_too_small", rescode.R{
+		"isAmountTooSmall": true,
+	})
+)
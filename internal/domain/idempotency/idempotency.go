@@ -0,0 +1,36 @@
+package idempotency
+
+import "time"
+
+// Record is the first outcome of a request made with an idempotency key.
+// While the request is still running the record is kept without a response.
+type Record struct {
+	BodyHash    string    `json:"body_hash"`
+	Completed   bool      `json:"completed"`
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (r *Record) Matches(bodyHash string) bool {
+	return r.BodyHash == bodyHash
+}
+
+func (r *Record) Complete(status int, contentType string, body []byte) {
+	r.Completed = true
+	r.Status = status
+	r.ContentType = contentType
+	r.Body = body
+}
+
+type Config struct {
+	BodyHash string `example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+func New(cnf Config) *Record {
+	return &Record{
+		BodyHash:  cnf.BodyHash,
+		CreatedAt: time.Now(),
+	}
+}
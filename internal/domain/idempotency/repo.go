@@ -0,0 +1,46 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Repo interface {
+	Start(ctx context.Context, t trace.Tracer, opts StartOpts) (bool, error)
+	Find(ctx context.Context, t trace.Tracer, opts FindOpts) (*Record, bool, error)
+	Save(ctx context.Context, t trace.Tracer, opts SaveOpts) error
+	Delete(ctx context.Context, t trace.Tracer, opts DeleteOpts) error
+	Expire(ctx context.Context, t trace.Tracer, opts ExpireOpts) error
+}
+
+type StartOpts struct {
+	UserId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Key    string    `example:"8e03978e-40d5-43e8-bc93-6894a57f9324"`
+	Record *Record   `example:"{}"`
+}
+
+type FindOpts struct {
+	UserId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Key    string    `example:"8e03978e-40d5-43e8-bc93-6894a57f9324"`
+}
+
+type SaveOpts struct {
+	UserId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Key    string    `example:"8e03978e-40d5-43e8-bc93-6894a57f9324"`
+	Record *Record   `example:"{}"`
+}
+
+type DeleteOpts struct {
+	UserId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Key    string    `example:"8e03978e-40d5-43e8-bc93-6894a57f9324"`
+}
+
+// ExpireOpts lets the key go after In instead of the full retention.
+type ExpireOpts struct {
+	UserId uuid.UUID     `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Key    string        `example:"8e03978e-40d5-43e8-bc93-6894a57f9324"`
+	In     time.Duration `example:"1m"`
+}
@@ -0,0 +1,20 @@
+package idempotency
+
+import (
+	"net/http"
+
+	"github.com/9ssi7/bank/pkg/rescode"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	KeyInvalid = rescode.New(5000, http.StatusBadRequest, codes.InvalidArgument, "idempotency_key_invalid", rescode.R{
+		"isKeyInvalid": true,
+	})
+	KeyReused = rescode.New(5001, http.StatusUnprocessableEntity, codes.FailedPrecondition, "idempotency_key_reused", rescode.R{
+		"isKeyReused": true,
+	})
+	InProgress = rescode.New(5002, http.StatusConflict, codes.Aborted, "idempotency_request_in_progress", rescode.R{
+		"isInProgress": true,
+	})
+)
@@ -0,0 +1,137 @@
+package limit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type Scope string
+
+func (s Scope) String() string {
+	return string(s)
+}
+
+// An account limit counts the money leaving one account, a user limit the money leaving all accounts
+// of a user, moves between the accounts of the user excluded.
+const (
+	ScopeAccount Scope = "account"
+	ScopeUser    Scope = "user"
+)
+
+// Rule caps the outgoing transfers and withdrawals of a currency within a scope, empty Currency
+// matches any. Unset amounts and zero counts are not limited.
+type Rule struct {
+	Scope          Scope               `example:"account"`
+	Currency       string              `example:"EUR"`
+	PerTransaction decimal.NullDecimal `example:"5000.00"`
+	DailyAmount    decimal.NullDecimal `example:"10000.00"`
+	DailyCount     int                 `example:"20"`
+	MonthlyAmount  decimal.NullDecimal `example:"50000.00"`
+	MonthlyCount   int                 `example:"200"`
+}
+
+func (r *Rule) Matches(scope Scope, currency string) bool {
+	return r.Scope == scope && (r.Currency == "" || r.Currency == currency)
+}
+
+// Usage is what a scope already spent in the current day and month.
+type Usage struct {
+	DailyAmount   decimal.Decimal `json:"daily_amount"`
+	DailyCount    int             `json:"daily_count"`
+	MonthlyAmount decimal.Decimal `json:"monthly_amount"`
+	MonthlyCount  int             `json:"monthly_count"`
+}
+
+// Check returns an error naming the first limit the amount would break on top of the usage.
+func (r *Rule) Check(amount decimal.Decimal, u Usage) error {
+	if r.PerTransaction.Valid && amount.GreaterThan(r.PerTransaction.Decimal) {
+		return r.exceeded("per_transaction")
+	}
+	if r.DailyAmount.Valid && u.DailyAmount.Add(amount).GreaterThan(r.DailyAmount.Decimal) {
+		return r.exceeded("daily_amount")
+	}
+	if r.DailyCount > 0 && u.DailyCount+1 > r.DailyCount {
+		return r.exceeded("daily_count")
+	}
+	if r.MonthlyAmount.Valid && u.MonthlyAmount.Add(amount).GreaterThan(r.MonthlyAmount.Decimal) {
+		return r.exceeded("monthly_amount")
+	}
+	if r.MonthlyCount > 0 && u.MonthlyCount+1 > r.MonthlyCount {
+		return r.exceeded("monthly_count")
+	}
+	return nil
+}
+
+func (r *Rule) exceeded(name string) error {
+	return fmt.Errorf("%s %s limit exceeded", r.Scope, name)
+}
+
+// Policy picks the rule of a scope and currency, a rule of the currency wins over one matching any.
+// Without a matching rule nothing is limited.
+type Policy struct {
+	Rules []Rule
+}
+
+func NewPolicy(rules ...Rule) *Policy {
+	return &Policy{Rules: rules}
+}
+
+func (p *Policy) For(scope Scope, currency string) *Rule {
+	if p == nil {
+		return nil
+	}
+	var match *Rule
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Matches(scope, currency) && (match == nil || (match.Currency == "" && r.Currency != "")) {
+			match = r
+		}
+	}
+	return match
+}
+
+// Window returns the start of the UTC day and month now falls into, usage is counted from them.
+func Window(now time.Time) (day time.Time, month time.Time) {
+	now = now.UTC()
+	day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, month
+}
+
+// Status is the usage of a scope next to the rule limiting it.
+type Status struct {
+	Scope    Scope  `json:"scope"`
+	Currency string `json:"currency"`
+	Usage    Usage  `json:"usage"`
+
+	PerTransaction *string `json:"per_transaction"`
+	DailyAmount    *string `json:"daily_amount"`
+	DailyCount     *int    `json:"daily_count"`
+	MonthlyAmount  *string `json:"monthly_amount"`
+	MonthlyCount   *int    `json:"monthly_count"`
+}
+
+// NewStatus reports the usage against the rule, limits the rule does not set are left nil.
+func NewStatus(r *Rule, currency string, u Usage) *Status {
+	s := &Status{Scope: r.Scope, Currency: currency, Usage: u}
+	s.PerTransaction = nullString(r.PerTransaction)
+	s.DailyAmount = nullString(r.DailyAmount)
+	s.MonthlyAmount = nullString(r.MonthlyAmount)
+	if r.DailyCount > 0 {
+		s.DailyCount = &r.DailyCount
+	}
+	if r.MonthlyCount > 0 {
+		s.MonthlyCount = &r.MonthlyCount
+	}
+	return s
+}
+
+func nullString(d decimal.NullDecimal) *string {
+	if !d.Valid {
+		return nil
+	}
+	s := d.Decimal.String()
+	return &s
+}
@@ -0,0 +1,132 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func nd(s string) decimal.NullDecimal {
+	return decimal.NewNullDecimal(d(s))
+}
+
+func TestRule_Check(t *testing.T) {
+	rule := Rule{
+		Scope:          ScopeAccount,
+		PerTransaction: nd("1000"),
+		DailyAmount:    nd("2000"),
+		DailyCount:     3,
+		MonthlyAmount:  nd("5000"),
+		MonthlyCount:   10,
+	}
+	tests := []struct {
+		name   string
+		amount string
+		usage  Usage
+		want   string
+	}{
+		{"within every limit", "500", Usage{DailyAmount: d("500"), DailyCount: 1, MonthlyAmount: d("1000"), MonthlyCount: 2}, ""},
+		{"per transaction bound", "1000", Usage{DailyAmount: d("0"), MonthlyAmount: d("0")}, ""},
+		{"per transaction", "1000.01", Usage{DailyAmount: d("0"), MonthlyAmount: d("0")}, "account per_transaction limit exceeded"},
+		{"daily amount bound", "500", Usage{DailyAmount: d("1500"), MonthlyAmount: d("1500")}, ""},
+		{"daily amount", "500.01", Usage{DailyAmount: d("1500"), MonthlyAmount: d("1500")}, "account daily_amount limit exceeded"},
+		{"daily count", "1", Usage{DailyAmount: d("0"), DailyCount: 3, MonthlyAmount: d("0"), MonthlyCount: 3}, "account daily_count limit exceeded"},
+		{"monthly amount", "500", Usage{DailyAmount: d("0"), MonthlyAmount: d("4600")}, "account monthly_amount limit exceeded"},
+		{"monthly count", "1", Usage{DailyAmount: d("0"), MonthlyAmount: d("0"), MonthlyCount: 10}, "account monthly_count limit exceeded"},
+		{"first broken limit named", "1500", Usage{DailyAmount: d("1900"), DailyCount: 3, MonthlyAmount: d("4900"), MonthlyCount: 10}, "account per_transaction limit exceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rule.Check(d(tt.amount), tt.usage)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("Check(%s) = %q, want %q", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRule_CheckUnset(t *testing.T) {
+	rule := Rule{Scope: ScopeUser}
+	usage := Usage{DailyAmount: d("1000000"), DailyCount: 1000, MonthlyAmount: d("1000000"), MonthlyCount: 1000}
+	if err := rule.Check(d("1000000"), usage); err != nil {
+		t.Errorf("rule without limits Check() = %v, want nil", err)
+	}
+}
+
+func TestPolicy_For(t *testing.T) {
+	anyAccount := Rule{Scope: ScopeAccount, DailyCount: 1}
+	eurAccount := Rule{Scope: ScopeAccount, Currency: "EUR", DailyCount: 2}
+	tryUser := Rule{Scope: ScopeUser, Currency: "TRY", DailyCount: 3}
+	tests := []struct {
+		name     string
+		rules    []Rule
+		scope    Scope
+		currency string
+		want     int
+	}{
+		{"rule without currency matches any", []Rule{anyAccount}, ScopeAccount, "USD", 1},
+		{"currency rule beats one without", []Rule{anyAccount, eurAccount}, ScopeAccount, "EUR", 2},
+		{"currency rule beats one without listed after it", []Rule{eurAccount, anyAccount}, ScopeAccount, "EUR", 2},
+		{"other currency falls back", []Rule{anyAccount, eurAccount}, ScopeAccount, "TRY", 1},
+		{"scope must match", []Rule{anyAccount, tryUser}, ScopeUser, "TRY", 3},
+		{"no match", []Rule{tryUser}, ScopeUser, "EUR", 0},
+		{"no rules", nil, ScopeAccount, "EUR", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewPolicy(tt.rules...).For(tt.scope, tt.currency)
+			got := 0
+			if r != nil {
+				got = r.DailyCount
+			}
+			if got != tt.want {
+				t.Errorf("For(%s, %s) picked rule %d, want %d", tt.scope, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_ForNil(t *testing.T) {
+	var p *Policy
+	if r := p.For(ScopeAccount, "EUR"); r != nil {
+		t.Errorf("nil policy For() = %v, want nil", r)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	istanbul := time.FixedZone("TRT", 3*60*60)
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantDay   time.Time
+		wantMonth time.Time
+	}{
+		{"mid month", time.Date(2024, 1, 15, 13, 30, 0, 0, time.UTC), time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"first of the month", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"other zone still the previous utc day", time.Date(2024, 2, 1, 1, 0, 0, 0, istanbul), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"leap day", time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day, month := Window(tt.now)
+			if !day.Equal(tt.wantDay) || day.Location() != time.UTC {
+				t.Errorf("Window() day = %v, want %v", day, tt.wantDay)
+			}
+			if !month.Equal(tt.wantMonth) || month.Location() != time.UTC {
+				t.Errorf("Window() month = %v, want %v", month, tt.wantMonth)
+			}
+		})
+	}
+}
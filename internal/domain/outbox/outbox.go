@@ -0,0 +1,61 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a domain event waiting to be published to the event stream.
+// It is written in the same transaction as the change that raised it.
+type Message struct {
+	ID        uuid.UUID  `json:"id"`
+	Subject   string     `json:"subject"`
+	Payload   []byte     `json:"payload"`
+	Attempts  int        `json:"attempts"`
+	LastError *string    `json:"last_error"`
+	CreatedAt time.Time  `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at"`
+	FailedAt  *time.Time `json:"failed_at"`
+
+	// ClaimId is set by the claim that handed the message to a relay,
+	// the claim can only be renewed by the relay still holding it.
+	ClaimId uuid.UUID `json:"-"`
+}
+
+func (m *Message) IsSent() bool {
+	return m.SentAt != nil
+}
+
+func (m *Message) MarkSent() {
+	t := time.Now()
+	m.SentAt = &t
+}
+
+// Fail records a failed publish, the message is given up once it failed maxAttempts times.
+func (m *Message) Fail(err error, maxAttempts int) {
+	msg := err.Error()
+	m.Attempts++
+	m.LastError = &msg
+	if maxAttempts > 0 && m.Attempts >= maxAttempts {
+		t := time.Now()
+		m.FailedAt = &t
+	}
+}
+
+func (m *Message) IsFailed() bool {
+	return m.FailedAt != nil
+}
+
+type Config struct {
+	Subject string `example:"Account.TransferIncoming"`
+	Payload []byte `example:"{}"`
+}
+
+func New(cnf Config) *Message {
+	return &Message{
+		Subject:   cnf.Subject,
+		Payload:   cnf.Payload,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,35 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/9ssi7/bank/pkg/txadapter"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Repo interface {
+	txadapter.Repo
+	Save(ctx context.Context, t trace.Tracer, opts SaveOpts) error
+	Claim(ctx context.Context, t trace.Tracer, opts ClaimOpts) ([]*Message, error)
+	Renew(ctx context.Context, t trace.Tracer, opts RenewOpts) (bool, error)
+}
+
+type SaveOpts struct {
+	Message *Message `example:"{}"`
+}
+
+// ClaimOpts picks the pending messages with fewer than MaxAttempts attempts, the fewest first,
+// and keeps the other relays off them for ClaimFor.
+type ClaimOpts struct {
+	Limit       int           `example:"100"`
+	MaxAttempts int           `example:"10"`
+	ClaimFor    time.Duration `example:"1m"`
+}
+
+// RenewOpts keeps the claim of a message for another ClaimFor, it reports false when the claim
+// lapsed and another relay claimed the message in the meantime.
+type RenewOpts struct {
+	Message  *Message      `example:"{}"`
+	ClaimFor time.Duration `example:"30s"`
+}
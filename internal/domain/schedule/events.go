@@ -0,0 +1,16 @@
+package schedule
+
+// SubjectTransferFailed lives under the account subjects, so the existing stream carries it.
+const SubjectTransferFailed = "Account.ScheduledTransferFailed"
+
+type EventTransferFailed struct {
+	Email       string `json:"email"`
+	Name        string `json:"name"`
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency"`
+	Account     string `json:"account"`
+	ToIban      string `json:"to_iban"`
+	ToOwner     string `json:"to_owner"`
+	Description string `json:"description"`
+	Reason      string `json:"reason"`
+}
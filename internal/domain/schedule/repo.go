@@ -0,0 +1,46 @@
+package schedule
+
+import (
+	"context"
+	"time"
+
+	"github.com/9ssi7/bank/pkg/list"
+	"github.com/9ssi7/bank/pkg/txadapter"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Repo interface {
+	txadapter.Repo
+	Save(ctx context.Context, t trace.Tracer, opts SaveOpts) error
+	FindByAccountIdAndId(ctx context.Context, t trace.Tracer, opts FindByAccountIdAndIdOpts) (*Transfer, error)
+
+	// FindByAccountIdAndIdForUpdate locks the transfer until the current transaction ends.
+	FindByAccountIdAndIdForUpdate(ctx context.Context, t trace.Tracer, opts FindByAccountIdAndIdOpts) (*Transfer, error)
+	ListByAccountId(ctx context.Context, t trace.Tracer, opts ListByAccountIdOpts) (*list.PagiResponse[*Transfer], error)
+
+	// ListDue locks active transfers due at the given time until the current transaction ends,
+	// skipping the ones another worker already holds.
+	ListDue(ctx context.Context, t trace.Tracer, opts ListDueOpts) ([]*Transfer, error)
+}
+
+type SaveOpts struct {
+	Transfer *Transfer `example:"{}"`
+}
+
+type FindByAccountIdAndIdOpts struct {
+	UserId    uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	AccountId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	ID        uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+type ListByAccountIdOpts struct {
+	UserId    uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	AccountId uuid.UUID `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Pagi      *list.PagiRequest
+}
+
+type ListDueOpts struct {
+	Now   time.Time `example:"2024-01-01T09:00:00Z"`
+	Limit int       `example:"50"`
+}
@@ -0,0 +1,26 @@
+package schedule
+
+import (
+	"net/http"
+
+	"github.com/9ssi7/bank/pkg/rescode"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	NotFound = rescode.New(7000, http.StatusNotFound, codes.NotFound, "scheduled_transfer_not_found", rescode.R{
+		"isNotFound": true,
+	})
+	CronInvalid = rescode.New(7001, http.StatusUnprocessableEntity, codes.InvalidArgument, "scheduled_transfer_cron_invalid", rescode.R{
+		"isCronInvalid": true,
+	})
+	RunAtInvalid = rescode.New(7002, http.StatusUnprocessableEntity, codes.InvalidArgument, "scheduled_transfer_run_at_invalid", rescode.R{
+		"isRunAtInvalid": true,
+	})
+	NotActive = rescode.New(7003, http.StatusConflict, codes.FailedPrecondition, "scheduled_transfer_not_active", rescode.R{
+		"isNotActive": true,
+	})
+	Running = rescode.New(7004, http.StatusConflict, codes.Aborted, "scheduled_transfer_running", rescode.R{
+		"isRunning": true,
+	})
+)
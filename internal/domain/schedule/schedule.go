@@ -0,0 +1,130 @@
+package schedule
+
+import (
+	"time"
+
+	"github.com/9ssi7/bank/pkg/cron"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type Status string
+
+func (s Status) String() string {
+	return string(s)
+}
+
+const (
+	StatusActive    Status = "active"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Transfer is a transfer executed later, once at NextRunAt or on every match of Cron.
+type Transfer struct {
+	ID          uuid.UUID       `json:"id"`
+	UserId      uuid.UUID       `json:"user_id"`
+	AccountId   uuid.UUID       `json:"account_id"`
+	Amount      decimal.Decimal `json:"amount"`
+	ToIban      string          `json:"to_iban"`
+	ToOwner     string          `json:"to_owner"`
+	Description string          `json:"description"`
+	Cron        string          `json:"cron"`
+	Status      Status          `json:"status"`
+	NextRunAt   time.Time       `json:"next_run_at"`
+	LastRunAt   *time.Time      `json:"last_run_at"`
+	LastError   *string         `json:"last_error"`
+	Runs        int             `json:"runs"`
+	Failures    int             `json:"failures"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+
+	// ClaimedUntil is set while a worker executes a run, the transfer can not be edited until it is done.
+	ClaimedUntil *time.Time `json:"-"`
+}
+
+func (t *Transfer) IsRecurring() bool {
+	return t.Cron != ""
+}
+
+func (t *Transfer) IsActive() bool {
+	return t.Status == StatusActive
+}
+
+// Advance moves the transfer past the run starting now, before it is executed.
+// A crash while executing then skips a run instead of paying twice.
+func (t *Transfer) Advance(now time.Time) error {
+	if !t.IsRecurring() {
+		t.Status = StatusCompleted
+		return nil
+	}
+	s, err := cron.Parse(t.Cron)
+	if err != nil {
+		return err
+	}
+	next := s.Next(now)
+	if next.IsZero() {
+		t.Status = StatusCompleted
+		return nil
+	}
+	t.NextRunAt = next
+	return nil
+}
+
+// Claim marks the transfer as being executed until the given time, when a worker that died mid run gives it up.
+func (t *Transfer) Claim(until time.Time) {
+	t.ClaimedUntil = &until
+}
+
+func (t *Transfer) IsClaimed(now time.Time) bool {
+	return t.ClaimedUntil != nil && now.Before(*t.ClaimedUntil)
+}
+
+func (t *Transfer) Succeed(now time.Time) {
+	t.ClaimedUntil = nil
+	t.LastRunAt = &now
+	t.LastError = nil
+	t.Runs++
+}
+
+// Fail records a failed run, recurring transfers keep running on their next match.
+func (t *Transfer) Fail(now time.Time, err error) {
+	msg := err.Error()
+	t.ClaimedUntil = nil
+	t.LastRunAt = &now
+	t.LastError = &msg
+	t.Failures++
+	if !t.IsRecurring() {
+		t.Status = StatusFailed
+	}
+}
+
+func (t *Transfer) Cancel() {
+	t.Status = StatusCancelled
+}
+
+type Config struct {
+	UserId      uuid.UUID       `example:"550e8400-e29b-41d4-a716-446655440000"`
+	AccountId   uuid.UUID       `example:"550e8400-e29b-41d4-a716-446655440000"`
+	Amount      decimal.Decimal `example:"100.00"`
+	ToIban      string          `example:"TR0000000000000000000000"`
+	ToOwner     string          `example:"John Doe"`
+	Description string          `example:"Rent"`
+	Cron        string          `example:"0 9 1 * *"`
+	NextRunAt   time.Time       `example:"2024-01-01T09:00:00Z"`
+}
+
+func New(cnf Config) *Transfer {
+	return &Transfer{
+		UserId:      cnf.UserId,
+		AccountId:   cnf.AccountId,
+		Amount:      cnf.Amount,
+		ToIban:      cnf.ToIban,
+		ToOwner:     cnf.ToOwner,
+		Description: cnf.Description,
+		Cron:        cnf.Cron,
+		NextRunAt:   cnf.NextRunAt,
+		Status:      StatusActive,
+	}
+}
@@ -3,11 +3,13 @@ package user
 import (
 	"context"
 
+	"github.com/9ssi7/bank/pkg/txadapter"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type Repo interface {
+	txadapter.Repo
 	FindByEmail(ctx context.Context, t trace.Tracer, opts FindByEmailOpts) (*User, error)
 	FindById(ctx context.Context, t trace.Tracer, opts FindByIdOpts) (*User, error)
 	FindByToken(ctx context.Context, t trace.Tracer, opts FindByTokenOpts) (*User, error)
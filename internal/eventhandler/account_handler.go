@@ -8,6 +8,7 @@ import (
 
 	"github.com/9ssi7/bank/assets"
 	"github.com/9ssi7/bank/internal/domain/account"
+	"github.com/9ssi7/bank/internal/domain/schedule"
 	"github.com/9ssi7/bank/internal/infra/mail"
 	"github.com/9ssi7/bank/pkg/cancel"
 	"github.com/nats-io/nats.go"
@@ -21,6 +22,30 @@ func NewAccountHandler(mailSrv *mail.Srv) *AccountHandler {
 	return &AccountHandler{mailSrv: mailSrv}
 }
 
+func (h *AccountHandler) OnTransferPending(ctx context.Context, msg *nats.Msg) error {
+	var event account.EventTransferPending
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return err
+	}
+	return cancel.NewWithTimeout(ctx, 5*time.Second, func(ctx context.Context) error {
+		return h.mailSrv.SendWithTemplate(ctx, mail.SendWithTemplateConfig{
+			SendConfig: mail.SendConfig{
+				To:      []string{event.Email},
+				Subject: "Confirm your transfer",
+				Message: event.Code,
+			},
+			Template: assets.Templates.TransferConfirm,
+			Data: map[string]interface{}{
+				"Name":   event.Name,
+				"Code":   event.Code,
+				"Amount": fmt.Sprintf("%s %s", mail.GetField(event.Amount), event.Currency),
+				"Iban":   mail.GetField(event.ToIban),
+				"Owner":  mail.GetField(event.ToOwner),
+			},
+		})
+	})
+}
+
 func (h *AccountHandler) OnTransferIncome(ctx context.Context, msg *nats.Msg) error {
 	var event account.EventTranfserIncoming
 	if err := json.Unmarshal(msg.Data, &event); err != nil {
@@ -64,3 +89,27 @@ func (h *AccountHandler) OnTransferOutcome(ctx context.Context, msg *nats.Msg) e
 		})
 	})
 }
+
+func (h *AccountHandler) OnScheduledTransferFailed(ctx context.Context, msg *nats.Msg) error {
+	var event schedule.EventTransferFailed
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return err
+	}
+	return cancel.NewWithTimeout(ctx, 5*time.Second, func(ctx context.Context) error {
+		return h.mailSrv.SendWithTemplate(ctx, mail.SendWithTemplateConfig{
+			SendConfig: mail.SendConfig{
+				To:      []string{event.Email},
+				Subject: "Scheduled transfer failed",
+			},
+			Template: assets.Templates.TransferScheduledFailed,
+			Data: map[string]interface{}{
+				"Name":        event.Name,
+				"Amount":      fmt.Sprintf("%s %s", mail.GetField(event.Amount), event.Currency),
+				"Account":     mail.GetField(event.Account),
+				"To":          fmt.Sprintf("%s (%s)", mail.GetField(event.ToOwner), event.ToIban),
+				"Description": mail.GetField(event.Description),
+				"Reason":      mail.GetField(event.Reason),
+			},
+		})
+	})
+}
@@ -65,3 +65,132 @@ func (h *AuthHandler) OnUserCreated(ctx context.Context, msg *nats.Msg) error {
 		})
 	})
 }
+
+func (h *AuthHandler) OnRefreshTokenReused(ctx context.Context, msg *nats.Msg) error {
+	var event auth.EventRefreshTokenReused
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return err
+	}
+	return cancel.NewWithTimeout(ctx, 5*time.Second, func(ctx context.Context) error {
+		return h.mailSrv.SendWithTemplate(ctx, mail.SendWithTemplateConfig{
+			SendConfig: mail.SendConfig{
+				To:      []string{event.Email},
+				Subject: "A session of yours was signed out",
+			},
+			Template: assets.Templates.AuthSessionRevoked,
+			Data: map[string]interface{}{
+				"Name":    event.Name,
+				"IP":      mail.GetField(event.IpAddress),
+				"Browser": mail.GetField(event.DeviceName),
+				"OS":      mail.GetField(event.DeviceOS),
+			},
+		})
+	})
+}
+
+func (h *AuthHandler) OnSessionReverify(ctx context.Context, msg *nats.Msg) error {
+	var event auth.EventSessionReverify
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return err
+	}
+	return cancel.NewWithTimeout(ctx, 5*time.Second, func(ctx context.Context) error {
+		return h.mailSrv.SendWithTemplate(ctx, mail.SendWithTemplateConfig{
+			SendConfig: mail.SendConfig{
+				To:      []string{event.Email},
+				Subject: "Confirm the new address of your session",
+				Message: event.Code,
+			},
+			Template: assets.Templates.AuthSessionReverify,
+			Data: map[string]interface{}{
+				"Code":    event.Code,
+				"IP":      mail.GetField(event.Device.IP),
+				"Browser": mail.GetField(event.Device.Name),
+				"OS":      mail.GetField(event.Device.OS),
+			},
+		})
+	})
+}
+
+func (h *AuthHandler) OnTotpEnrollStarted(ctx context.Context, msg *nats.Msg) error {
+	var event auth.EventTotpEnrollStarted
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return err
+	}
+	return cancel.NewWithTimeout(ctx, 5*time.Second, func(ctx context.Context) error {
+		return h.mailSrv.SendWithTemplate(ctx, mail.SendWithTemplateConfig{
+			SendConfig: mail.SendConfig{
+				To:      []string{event.Email},
+				Subject: "Confirm your authenticator app",
+				Message: event.Code,
+			},
+			Template: assets.Templates.AuthSecurityCode,
+			Data: map[string]interface{}{
+				"Name":   event.Name,
+				"Action": "add an authenticator app to your account",
+				"Code":   event.Code,
+			},
+		})
+	})
+}
+
+func (h *AuthHandler) OnTotpEnrolled(ctx context.Context, msg *nats.Msg) error {
+	var event auth.EventTotpEnrolled
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return err
+	}
+	return cancel.NewWithTimeout(ctx, 5*time.Second, func(ctx context.Context) error {
+		return h.mailSrv.SendWithTemplate(ctx, mail.SendWithTemplateConfig{
+			SendConfig: mail.SendConfig{
+				To:      []string{event.Email},
+				Subject: "An authenticator app was added",
+			},
+			Template: assets.Templates.AuthSecurityChanged,
+			Data: map[string]interface{}{
+				"Name":   event.Name,
+				"Change": "An authenticator app was added to your account, its codes now sign you in.",
+			},
+		})
+	})
+}
+
+func (h *AuthHandler) OnPasskeyStarted(ctx context.Context, msg *nats.Msg) error {
+	var event auth.EventPasskeyStarted
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return err
+	}
+	return cancel.NewWithTimeout(ctx, 5*time.Second, func(ctx context.Context) error {
+		return h.mailSrv.SendWithTemplate(ctx, mail.SendWithTemplateConfig{
+			SendConfig: mail.SendConfig{
+				To:      []string{event.Email},
+				Subject: "Confirm your passkey",
+				Message: event.Code,
+			},
+			Template: assets.Templates.AuthSecurityCode,
+			Data: map[string]interface{}{
+				"Name":   event.Name,
+				"Action": "add a passkey to your account",
+				"Code":   event.Code,
+			},
+		})
+	})
+}
+
+func (h *AuthHandler) OnPasskeyAdded(ctx context.Context, msg *nats.Msg) error {
+	var event auth.EventPasskeyAdded
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return err
+	}
+	return cancel.NewWithTimeout(ctx, 5*time.Second, func(ctx context.Context) error {
+		return h.mailSrv.SendWithTemplate(ctx, mail.SendWithTemplateConfig{
+			SendConfig: mail.SendConfig{
+				To:      []string{event.Email},
+				Subject: "A passkey was added",
+			},
+			Template: assets.Templates.AuthSecurityChanged,
+			Data: map[string]interface{}{
+				"Name":   event.Name,
+				"Change": fmt.Sprintf("The passkey %q was added to your account, it signs you in without any other code.", event.PasskeyName),
+			},
+		})
+	})
+}
@@ -17,7 +17,7 @@ func runner(ctx context.Context, db *sql.DB, migrations ...migration) error {
 }
 
 func Run(ctx context.Context, db *sql.DB) error {
-	return runner(ctx, db, userModelMigration, accountModelMigration, transactionModelMigration)
+	return runner(ctx, db, userModelMigration, accountModelMigration, accountVersionMigration, transactionModelMigration, ledgerModelMigration, ledgerOpeningBalanceMigration, outboxModelMigration, fxModelMigration, transactionFxMigration, amountScaleMigration, transactionParentMigration, scheduledTransferModelMigration, accountIbanUniqueMigration, transactionReferenceMigration, holdModelMigration, totpModelMigration, passkeyModelMigration, outboxClaimMigration, scheduledTransferClaimMigration, totpLockoutMigration, outboxClaimIdMigration, totpSealedSecretMigration)
 }
 
 func userModelMigration(ctx context.Context, db *sql.DB) error {
@@ -67,6 +67,12 @@ func accountModelMigration(ctx context.Context, db *sql.DB) error {
 	return err
 }
 
+func accountVersionMigration(ctx context.Context, db *sql.DB) error {
+	q := `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0`
+	_, err := db.ExecContext(ctx, q)
+	return err
+}
+
 func transactionModelMigration(ctx context.Context, db *sql.DB) error {
 	q := `CREATE TABLE IF NOT EXISTS transactions (
 		id UUID PRIMARY KEY,
@@ -90,3 +96,368 @@ func transactionModelMigration(ctx context.Context, db *sql.DB) error {
 	_, err = db.ExecContext(ctx, q)
 	return err
 }
+
+func ledgerModelMigration(ctx context.Context, db *sql.DB) error {
+	q := `CREATE TABLE IF NOT EXISTS journal_entries (
+		id UUID PRIMARY KEY,
+		transaction_id UUID NULL DEFAULT NULL,
+		description TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_journal_entries_transaction_id ON journal_entries (transaction_id)`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE TABLE IF NOT EXISTS postings (
+		id UUID PRIMARY KEY,
+		entry_id UUID NOT NULL REFERENCES journal_entries (id),
+		account_id UUID NOT NULL,
+		direction VARCHAR(6) NOT NULL CHECK (direction IN ('debit', 'credit')),
+		amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+		currency VARCHAR(3) NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_postings_account_id ON postings (account_id, created_at)`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_postings_entry_id ON postings (entry_id)`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+// ledgerOpeningBalanceMigration books the balance of accounts created before the ledger
+// existed as an opening entry against the cash account, so reconciled balances stay the same.
+func ledgerOpeningBalanceMigration(ctx context.Context, db *sql.DB) error {
+	q := `WITH opening AS (
+		SELECT gen_random_uuid() AS entry_id, a.id AS account_id, a.balance, a.currency
+		FROM accounts a
+		WHERE a.balance <> 0 AND NOT EXISTS (SELECT 1 FROM postings p WHERE p.account_id = a.id)
+	), entries AS (
+		INSERT INTO journal_entries (id, description) SELECT entry_id, 'Opening balance' FROM opening
+	)
+	INSERT INTO postings (id, entry_id, account_id, direction, amount, currency)
+	SELECT gen_random_uuid(), entry_id, account_id, CASE WHEN balance > 0 THEN 'credit' ELSE 'debit' END, ABS(balance), currency FROM opening
+	UNION ALL
+	SELECT gen_random_uuid(), entry_id, '00000000-0000-0000-0000-000000000001', CASE WHEN balance > 0 THEN 'debit' ELSE 'credit' END, ABS(balance), currency FROM opening`
+	_, err := db.ExecContext(ctx, q)
+	return err
+}
+
+func outboxModelMigration(ctx context.Context, db *sql.DB) error {
+	q := `CREATE TABLE IF NOT EXISTS outbox (
+		id UUID PRIMARY KEY,
+		subject VARCHAR(255) NOT NULL,
+		payload JSONB NOT NULL,
+		attempts INT NOT NULL DEFAULT 0,
+		last_error TEXT NULL DEFAULT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		sent_at TIMESTAMP NULL DEFAULT NULL
+	)`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (created_at) WHERE sent_at IS NULL`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+func fxModelMigration(ctx context.Context, db *sql.DB) error {
+	q := `CREATE TABLE IF NOT EXISTS fx_rates (
+		id UUID PRIMARY KEY,
+		base VARCHAR(3) NOT NULL,
+		quote VARCHAR(3) NOT NULL,
+		rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+		source VARCHAR(255) NOT NULL,
+		quoted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_fx_rates_pair ON fx_rates (base, quote, quoted_at)`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+// transactionFxMigration records both sides of a transfer, existing rows are backfilled
+// with the currency of their accounts, as only same currency transfers were possible.
+func transactionFxMigration(ctx context.Context, db *sql.DB) error {
+	q := `ALTER TABLE transactions
+		ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NULL DEFAULT NULL,
+		ADD COLUMN IF NOT EXISTS receiver_amount DECIMAL(10, 2) NULL DEFAULT NULL,
+		ADD COLUMN IF NOT EXISTS receiver_currency VARCHAR(3) NULL DEFAULT NULL,
+		ADD COLUMN IF NOT EXISTS fx_rate NUMERIC(20, 10) NULL DEFAULT NULL,
+		ADD COLUMN IF NOT EXISTS fx_rate_id UUID NULL DEFAULT NULL`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `UPDATE transactions SET receiver_amount = amount WHERE receiver_amount IS NULL`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `UPDATE transactions t SET currency = a.currency FROM accounts a WHERE t.currency IS NULL AND a.id = t.sender_id`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `UPDATE transactions t SET receiver_currency = a.currency FROM accounts a WHERE t.receiver_currency IS NULL AND a.id = t.receiver_id`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+// amountScaleMigration widens every amount column to the largest ISO 4217 minor unit,
+// so three and four decimal currencies fit and large balances do not overflow.
+func amountScaleMigration(ctx context.Context, db *sql.DB) error {
+	q := `ALTER TABLE accounts ALTER COLUMN balance TYPE NUMERIC(36, 4)`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `ALTER TABLE transactions
+		ALTER COLUMN amount TYPE NUMERIC(36, 4),
+		ALTER COLUMN receiver_amount TYPE NUMERIC(36, 4)`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `ALTER TABLE postings ALTER COLUMN amount TYPE NUMERIC(36, 4)`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+func transactionParentMigration(ctx context.Context, db *sql.DB) error {
+	q := `ALTER TABLE transactions ADD COLUMN IF NOT EXISTS parent_id UUID NULL DEFAULT NULL`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_transactions_parent_id ON transactions (parent_id)`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+func scheduledTransferModelMigration(ctx context.Context, db *sql.DB) error {
+	q := `CREATE TABLE IF NOT EXISTS scheduled_transfers (
+		id UUID PRIMARY KEY,
+		user_id UUID NOT NULL,
+		account_id UUID NOT NULL,
+		amount NUMERIC(36, 4) NOT NULL CHECK (amount > 0),
+		to_iban VARCHAR(34) NOT NULL,
+		to_owner VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL,
+		cron VARCHAR(255) NOT NULL DEFAULT '',
+		status VARCHAR(16) NOT NULL,
+		next_run_at TIMESTAMP NOT NULL,
+		last_run_at TIMESTAMP NULL DEFAULT NULL,
+		last_error TEXT NULL DEFAULT NULL,
+		runs INT NOT NULL DEFAULT 0,
+		failures INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_account_id ON scheduled_transfers (account_id, created_at)`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_due ON scheduled_transfers (next_run_at) WHERE status = 'active'`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+func accountIbanUniqueMigration(ctx context.Context, db *sql.DB) error {
+	q := `CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_iban ON accounts (iban)`
+	_, err := db.ExecContext(ctx, q)
+	return err
+}
+
+// transactionReferenceMigration links reversals to the transaction they compensate,
+// the unique index keeps a transaction from being reversed twice.
+func transactionReferenceMigration(ctx context.Context, db *sql.DB) error {
+	q := `ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reference_id UUID NULL DEFAULT NULL`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reference_id ON transactions (reference_id)`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+// holdModelMigration adds the holds and the available balance they lower,
+// existing accounts have nothing on hold so their available balance is their balance.
+func holdModelMigration(ctx context.Context, db *sql.DB) error {
+	q := `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS available_balance NUMERIC(36, 4) NULL`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `UPDATE accounts SET available_balance = balance WHERE available_balance IS NULL`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `ALTER TABLE accounts ALTER COLUMN available_balance SET NOT NULL`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE TABLE IF NOT EXISTS holds (
+		id UUID PRIMARY KEY,
+		account_id UUID NOT NULL,
+		amount NUMERIC(36, 4) NOT NULL CHECK (amount > 0),
+		fee NUMERIC(36, 4) NOT NULL DEFAULT 0,
+		currency VARCHAR(3) NOT NULL,
+		description TEXT NOT NULL,
+		status VARCHAR(16) NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		captured NUMERIC(36, 4) NOT NULL DEFAULT 0,
+		transaction_id UUID NULL DEFAULT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_holds_account_id ON holds (account_id) WHERE status = 'active'`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_holds_expires_at ON holds (expires_at) WHERE status = 'active'`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+// totpModelMigration adds the authenticator apps of the users and their recovery codes, kept hashed.
+func totpModelMigration(ctx context.Context, db *sql.DB) error {
+	q := `CREATE TABLE IF NOT EXISTS user_totps (
+		user_id UUID PRIMARY KEY,
+		secret VARCHAR(64) NOT NULL,
+		last_step BIGINT NOT NULL DEFAULT 0,
+		confirmed_at TIMESTAMP NULL DEFAULT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE TABLE IF NOT EXISTS user_recovery_codes (
+		id UUID PRIMARY KEY,
+		user_id UUID NOT NULL,
+		code_hash VARCHAR(64) NOT NULL,
+		used_at TIMESTAMP NULL DEFAULT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user_id ON user_recovery_codes (user_id, code_hash)`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+// passkeyModelMigration adds the webauthn credentials of the users, a credential id is registered once.
+func passkeyModelMigration(ctx context.Context, db *sql.DB) error {
+	q := `CREATE TABLE IF NOT EXISTS passkeys (
+		id UUID PRIMARY KEY,
+		user_id UUID NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		credential_id BYTEA NOT NULL,
+		public_key BYTEA NOT NULL,
+		sign_count BIGINT NOT NULL DEFAULT 0,
+		aaguid BYTEA NULL DEFAULT NULL,
+		last_used_at TIMESTAMP NULL DEFAULT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE UNIQUE INDEX IF NOT EXISTS idx_passkeys_credential_id ON passkeys (credential_id)`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_passkeys_user_id ON passkeys (user_id)`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+// outboxClaimMigration lets the relay claim messages instead of holding their rows locked while publishing,
+// and gives up the messages that failed too often so they stop blocking the ones behind them.
+func outboxClaimMigration(ctx context.Context, db *sql.DB) error {
+	q := `ALTER TABLE outbox ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP NULL DEFAULT NULL`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `ALTER TABLE outbox ADD COLUMN IF NOT EXISTS failed_at TIMESTAMP NULL DEFAULT NULL`
+	_, err = db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `CREATE INDEX IF NOT EXISTS idx_outbox_claimable ON outbox (attempts, created_at) WHERE sent_at IS NULL AND failed_at IS NULL`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+// scheduledTransferClaimMigration marks the transfers a worker is executing, edits wait for the run to finish.
+func scheduledTransferClaimMigration(ctx context.Context, db *sql.DB) error {
+	q := `ALTER TABLE scheduled_transfers ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP NULL DEFAULT NULL`
+	_, err := db.ExecContext(ctx, q)
+	return err
+}
+
+// totpLockoutMigration counts the codes an authenticator app rejected in a row, the app is locked after too many.
+func totpLockoutMigration(ctx context.Context, db *sql.DB) error {
+	q := `ALTER TABLE user_totps ADD COLUMN IF NOT EXISTS failures INT NOT NULL DEFAULT 0`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `ALTER TABLE user_totps ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP NULL DEFAULT NULL`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
+
+// outboxClaimIdMigration tells the claims of the relays apart, a relay renews a message only while its claim holds.
+func outboxClaimIdMigration(ctx context.Context, db *sql.DB) error {
+	q := `ALTER TABLE outbox ADD COLUMN IF NOT EXISTS claim_id UUID NULL DEFAULT NULL`
+	_, err := db.ExecContext(ctx, q)
+	return err
+}
+
+// totpSealedSecretMigration keeps the secrets of the authenticator apps sealed, the secrets stored
+// in the clear before are read as they are and sealed the next time the app is saved.
+func totpSealedSecretMigration(ctx context.Context, db *sql.DB) error {
+	q := `ALTER TABLE user_totps ADD COLUMN IF NOT EXISTS secret_sealed BYTEA NULL DEFAULT NULL`
+	_, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	q = `ALTER TABLE user_totps ALTER COLUMN secret DROP NOT NULL`
+	_, err = db.ExecContext(ctx, q)
+	return err
+}
@@ -3,6 +3,8 @@ package eventer
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"time"
 
 	"github.com/nats-io/nats.go"
 )
@@ -11,17 +13,30 @@ type Srv struct {
 	nc *nats.Conn
 	js nats.JetStreamContext
 
-	streamUrl string
+	cnf Config
 }
 
-func New(streamUrl string) *Srv {
+type Config struct {
+	StreamUrl string
+
+	// Stream is the JetStream stream that stores every domain event.
+	Stream   string
+	Subjects []string
+	MaxAge   time.Duration
+
+	// DeadLetterStream keeps the messages that could not be handled after their last delivery.
+	DeadLetterStream  string
+	DeadLetterSubject string
+}
+
+func New(cnf Config) *Srv {
 	return &Srv{
-		streamUrl: streamUrl,
+		cnf: cnf,
 	}
 }
 
 func (s *Srv) Connect(ctx context.Context) error {
-	nc, err := nats.Connect(s.streamUrl)
+	nc, err := nats.Connect(s.cnf.StreamUrl)
 	if err != nil {
 		return err
 	}
@@ -34,6 +49,45 @@ func (s *Srv) Connect(ctx context.Context) error {
 	return nil
 }
 
+// Provision creates or updates the event and dead letter streams from the config.
+func (s *Srv) Provision(ctx context.Context) error {
+	streams := []*nats.StreamConfig{
+		{
+			Name:     s.cnf.Stream,
+			Subjects: s.cnf.Subjects,
+			MaxAge:   s.cnf.MaxAge,
+			Storage:  nats.FileStorage,
+		},
+	}
+	if s.cnf.DeadLetterStream != "" {
+		streams = append(streams, &nats.StreamConfig{
+			Name:     s.cnf.DeadLetterStream,
+			Subjects: []string{s.cnf.DeadLetterSubject + ".>"},
+			Storage:  nats.FileStorage,
+		})
+	}
+	for _, cnf := range streams {
+		_, err := s.js.StreamInfo(cnf.Name, nats.Context(ctx))
+		if errors.Is(err, nats.ErrStreamNotFound) {
+			_, err = s.js.AddStream(cnf, nats.Context(ctx))
+		} else if err == nil {
+			_, err = s.js.UpdateStream(cnf, nats.Context(ctx))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Health fails while the connection to the event stream is down.
+func (s *Srv) Health(ctx context.Context) error {
+	if s.nc == nil || !s.nc.IsConnected() {
+		return errors.New("event stream is not connected")
+	}
+	return nil
+}
+
 func (s *Srv) Disconnect(ctx context.Context) error {
 	s.nc.Close()
 	return nil
@@ -44,7 +98,22 @@ func (s *Srv) Publish(ctx context.Context, sub string, data interface{}) error {
 	if err != nil {
 		return err
 	}
-	return s.nc.Publish(sub, p)
+	_, err = s.js.Publish(sub, p, nats.Context(ctx))
+	return err
+}
+
+// DeadLetter moves a message that ran out of deliveries to the dead letter subject,
+// keeping the original subject and the last error in the headers.
+func (s *Srv) DeadLetter(ctx context.Context, msg *nats.Msg, reason error) error {
+	if s.cnf.DeadLetterSubject == "" {
+		return errors.New("dead letter subject is not configured")
+	}
+	dl := nats.NewMsg(s.cnf.DeadLetterSubject + "." + msg.Subject)
+	dl.Data = msg.Data
+	dl.Header.Set("Original-Subject", msg.Subject)
+	dl.Header.Set("Error", reason.Error())
+	_, err := s.js.PublishMsg(dl, nats.Context(ctx))
+	return err
 }
 
 func (s *Srv) GetClient() nats.JetStreamContext {
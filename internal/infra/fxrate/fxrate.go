@@ -0,0 +1,77 @@
+package fxrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/9ssi7/bank/internal/domain/fx"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// inverseScale is the precision of rates derived from the opposite pair.
+const inverseScale = 10
+
+// Static quotes the rates it was given, it is meant for local setups and tests.
+// Rates are keyed by pair, e.g. "EUR/USD", the opposite pair is derived when it is missing.
+type Static struct {
+	rates  map[string]decimal.Decimal
+	source string
+}
+
+func NewStatic(rates map[string]string) (*Static, error) {
+	return newStatic(rates, "static")
+}
+
+// NewFile loads the rates of a static provider from a yaml (or json) file of pair: rate entries.
+func NewFile(path string) (*Static, error) {
+	f, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[string]string)
+	if err := yaml.Unmarshal(f, &rates); err != nil {
+		return nil, err
+	}
+	return newStatic(rates, "file")
+}
+
+func newStatic(rates map[string]string, source string) (*Static, error) {
+	s := &Static{
+		rates:  make(map[string]decimal.Decimal, len(rates)),
+		source: source,
+	}
+	for pair, rate := range rates {
+		base, quote, ok := strings.Cut(pair, "/")
+		if !ok {
+			return nil, errors.New("fx pair must look like BASE/QUOTE: " + pair)
+		}
+		r, err := decimal.NewFromString(rate)
+		if err != nil {
+			return nil, err
+		}
+		if !r.IsPositive() {
+			return nil, errors.New("fx rate must be positive: " + pair)
+		}
+		s.rates[key(base, quote)] = r
+	}
+	return s, nil
+}
+
+func (s *Static) Rate(ctx context.Context, base string, quote string) (*fx.Rate, error) {
+	if r, ok := s.rates[key(base, quote)]; ok {
+		return fx.New(fx.Config{Base: base, Quote: quote, Rate: r, Source: s.source}), nil
+	}
+	if r, ok := s.rates[key(quote, base)]; ok {
+		inverse := decimal.NewFromInt(1).DivRound(r, inverseScale)
+		return fx.New(fx.Config{Base: base, Quote: quote, Rate: inverse, Source: s.source}), nil
+	}
+	return nil, fx.RateNotFound(errors.New("no fx rate for " + key(base, quote)))
+}
+
+func key(base string, quote string) string {
+	return strings.ToUpper(base) + "/" + strings.ToUpper(quote)
+}
@@ -6,13 +6,18 @@ import (
 
 	"github.com/9ssi7/bank/internal/domain/account"
 	"github.com/9ssi7/bank/pkg/list"
+	"github.com/lib/pq"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/google/uuid"
 )
 
+const accountColumns = "id, user_id, iban, owner, balance, currency, version, available_balance"
+
+// pqUniqueViolation is the postgres error code of a unique constraint violation.
+const pqUniqueViolation = "23505"
+
 type AccountSqlRepo struct {
-	syncRepo
 	txnSqlRepo
 	db *sql.DB
 }
@@ -21,22 +26,35 @@ func NewAccountSqlRepo(db *sql.DB) *AccountSqlRepo {
 	return &AccountSqlRepo{
 		db:         db,
 		txnSqlRepo: newTxnSqlRepo(db),
-		syncRepo:   newSyncRepo(),
 	}
 }
 
 func (r *AccountSqlRepo) Save(ctx context.Context, t trace.Tracer, opts account.SaveOpts) error {
 	ctx, span := t.Start(ctx, "AccountSqlRepo.Save")
 	defer span.End()
-	r.syncRepo.Lock()
-	defer r.syncRepo.Unlock()
-	q := "INSERT INTO accounts (id, user_id, iban, owner, balance, currency) VALUES ($1, $2, $3, $4, $5, $6)"
 	if opts.Acount.ID == uuid.Nil {
 		opts.Acount.ID = uuid.New()
-		q = "UPDATE accounts SET user_id = $2, iban = $3, owner = $4, balance = $5, currency = $6 WHERE id = $1"
+		_, err := r.adapter.GetCurrent().ExecContext(ctx, "INSERT INTO accounts (id, user_id, iban, owner, balance, currency, version, available_balance) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", opts.Acount.ID, opts.Acount.UserId, opts.Acount.Iban, opts.Acount.Owner, opts.Acount.Balance, opts.Acount.Currency, opts.Acount.Version, opts.Acount.AvailableBalance)
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == pqUniqueViolation && pqErr.Constraint == "idx_accounts_iban" {
+			opts.Acount.ID = uuid.Nil
+			return account.ErrIbanTaken
+		}
+		return err
+	}
+	// compare and swap, the row is only updated if nobody changed it since it was read
+	res, err := r.adapter.GetCurrent().ExecContext(ctx, "UPDATE accounts SET user_id = $2, iban = $3, owner = $4, balance = $5, currency = $6, available_balance = $8, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND version = $7", opts.Acount.ID, opts.Acount.UserId, opts.Acount.Iban, opts.Acount.Owner, opts.Acount.Balance, opts.Acount.Currency, opts.Acount.Version, opts.Acount.AvailableBalance)
+	if err != nil {
+		return err
 	}
-	_, err := r.adapter.GetCurrent().ExecContext(ctx, q, opts.Acount.ID, opts.Acount.UserId, opts.Acount.Iban, opts.Acount.Owner, opts.Acount.Balance, opts.Acount.Currency)
-	return err
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return account.VersionConflict(account.ErrVersionConflict)
+	}
+	opts.Acount.Version++
+	return nil
 }
 
 func (r *AccountSqlRepo) ListByUserId(ctx context.Context, t trace.Tracer, opts account.ListByUserIdOpts) (*list.PagiResponse[*account.Account], error) {
@@ -52,13 +70,13 @@ func (r *AccountSqlRepo) ListByUserId(ctx context.Context, t trace.Tracer, opts
 	}
 	res.Close()
 	accounts := make([]*account.Account, 0)
-	res, err = r.adapter.GetCurrent().QueryContext(ctx, "SELECT * FROM accounts WHERE user_id = $1 LIMIT $2 OFFSET $3", opts.UserId, *opts.Pagi.Limit, opts.Pagi.Offset())
+	res, err = r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE user_id = $1 LIMIT $2 OFFSET $3", opts.UserId, *opts.Pagi.Limit, opts.Pagi.Offset())
 	if err != nil {
 		return nil, err
 	}
 	for res.Next() {
 		var a account.Account
-		res.Scan(&a.ID, &a.UserId, &a.Iban, &a.Owner, &a.Balance, &a.Currency)
+		res.Scan(&a.ID, &a.UserId, &a.Iban, &a.Owner, &a.Balance, &a.Currency, &a.Version, &a.AvailableBalance)
 		accounts = append(accounts, &a)
 	}
 	res.Close()
@@ -76,12 +94,12 @@ func (r *AccountSqlRepo) FindByIbanAndOwner(ctx context.Context, t trace.Tracer,
 	ctx, span := t.Start(ctx, "AccountSqlRepo.FindByIbanAndOwner")
 	defer span.End()
 	var a account.Account
-	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT * FROM accounts WHERE iban = $1 AND owner = $2", opts.Iban, opts.Owner)
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE iban = $1 AND owner = $2", opts.Iban, opts.Owner)
 	if err != nil {
 		return nil, err
 	}
 	if res.Next() {
-		res.Scan(&a.ID, &a.UserId, &a.Iban, &a.Owner, &a.Balance, &a.Currency)
+		res.Scan(&a.ID, &a.UserId, &a.Iban, &a.Owner, &a.Balance, &a.Currency, &a.Version, &a.AvailableBalance)
 	}
 	res.Close()
 	return &a, nil
@@ -91,28 +109,68 @@ func (r *AccountSqlRepo) FindByUserIdAndId(ctx context.Context, t trace.Tracer,
 	ctx, span := t.Start(ctx, "AccountSqlRepo.FindByUserIdAndId")
 	defer span.End()
 	var a account.Account
-	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT * FROM accounts WHERE user_id = $1 AND id = $2", opts.UserId, opts.ID)
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE user_id = $1 AND id = $2", opts.UserId, opts.ID)
+	if err != nil {
+		return nil, err
+	}
+	if res.Next() {
+		res.Scan(&a.ID, &a.UserId, &a.Iban, &a.Owner, &a.Balance, &a.Currency, &a.Version, &a.AvailableBalance)
+	}
+	res.Close()
+	return &a, nil
+}
+
+func (r *AccountSqlRepo) FindByUserIdAndIban(ctx context.Context, t trace.Tracer, opts account.FindByUserIdAndIbanOpts) (*account.Account, error) {
+	ctx, span := t.Start(ctx, "AccountSqlRepo.FindByUserIdAndIban")
+	defer span.End()
+	var a account.Account
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE user_id = $1 AND iban = $2", opts.UserId, opts.Iban)
 	if err != nil {
 		return nil, err
 	}
 	if res.Next() {
-		res.Scan(&a.ID, &a.UserId, &a.Iban, &a.Owner, &a.Balance, &a.Currency)
+		res.Scan(&a.ID, &a.UserId, &a.Iban, &a.Owner, &a.Balance, &a.Currency, &a.Version, &a.AvailableBalance)
 	}
 	res.Close()
 	return &a, nil
 }
 
 func (r *AccountSqlRepo) FindById(ctx context.Context, t trace.Tracer, opts account.FindByIdOpts) (*account.Account, error) {
-	ctx, span := t.Start(ctx, "AccountSqlRepo.FindByUserIdAndId")
+	ctx, span := t.Start(ctx, "AccountSqlRepo.FindById")
 	defer span.End()
 	var a account.Account
-	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT * FROM accounts WHERE id = $1", opts.ID)
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE id = $1", opts.ID)
 	if err != nil {
 		return nil, err
 	}
 	if res.Next() {
-		res.Scan(&a.ID, &a.UserId, &a.Iban, &a.Owner, &a.Balance, &a.Currency)
+		res.Scan(&a.ID, &a.UserId, &a.Iban, &a.Owner, &a.Balance, &a.Currency, &a.Version, &a.AvailableBalance)
 	}
 	res.Close()
 	return &a, nil
 }
+
+func (r *AccountSqlRepo) FindByIdsForUpdate(ctx context.Context, t trace.Tracer, opts account.FindByIdsForUpdateOpts) ([]*account.Account, error) {
+	ctx, span := t.Start(ctx, "AccountSqlRepo.FindByIdsForUpdate")
+	defer span.End()
+	ids := make([]string, 0, len(opts.IDs))
+	for _, id := range opts.IDs {
+		ids = append(ids, id.String())
+	}
+	// rows are locked in the order they are returned, ordering by id keeps
+	// concurrent transfers between the same accounts from deadlocking
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE id = ANY($1::uuid[]) ORDER BY id FOR UPDATE", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	accounts := make([]*account.Account, 0, len(ids))
+	for res.Next() {
+		var a account.Account
+		if err := res.Scan(&a.ID, &a.UserId, &a.Iban, &a.Owner, &a.Balance, &a.Currency, &a.Version, &a.AvailableBalance); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, &a)
+	}
+	return accounts, res.Err()
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/9ssi7/bank/internal/domain/fx"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type FxSqlRepo struct {
+	txnSqlRepo
+	db *sql.DB
+}
+
+func NewFxSqlRepo(db *sql.DB) *FxSqlRepo {
+	return &FxSqlRepo{
+		db:         db,
+		txnSqlRepo: newTxnSqlRepo(db),
+	}
+}
+
+func (r *FxSqlRepo) Save(ctx context.Context, trc trace.Tracer, opts fx.SaveOpts) error {
+	ctx, span := trc.Start(ctx, "FxSqlRepo.Save")
+	defer span.End()
+	if opts.Rate.ID == uuid.Nil {
+		opts.Rate.ID = uuid.New()
+	}
+	_, err := r.adapter.GetCurrent().ExecContext(ctx, "INSERT INTO fx_rates (id, base, quote, rate, source, quoted_at) VALUES ($1, $2, $3, $4, $5, $6)", opts.Rate.ID, opts.Rate.Base, opts.Rate.Quote, opts.Rate.Rate, opts.Rate.Source, opts.Rate.QuotedAt)
+	return err
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/account"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const holdColumns = "id, account_id, amount, fee, currency, description, status, expires_at, captured, transaction_id, created_at, updated_at"
+
+type HoldSqlRepo struct {
+	txnSqlRepo
+	db *sql.DB
+}
+
+func NewHoldSqlRepo(db *sql.DB) *HoldSqlRepo {
+	return &HoldSqlRepo{
+		db:         db,
+		txnSqlRepo: newTxnSqlRepo(db),
+	}
+}
+
+func (r *HoldSqlRepo) Save(ctx context.Context, trc trace.Tracer, opts account.HoldSaveOpts) error {
+	ctx, span := trc.Start(ctx, "HoldSqlRepo.Save")
+	defer span.End()
+	h := opts.Hold
+	h.UpdatedAt = time.Now()
+	q := "UPDATE holds SET account_id = $2, amount = $3, fee = $4, currency = $5, description = $6, status = $7, expires_at = $8, captured = $9, transaction_id = $10, created_at = $11, updated_at = $12 WHERE id = $1"
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+		h.CreatedAt = h.UpdatedAt
+		q = "INSERT INTO holds (" + holdColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)"
+	}
+	_, err := r.adapter.GetCurrent().ExecContext(ctx, q, h.ID, h.AccountId, h.Amount, h.Fee, h.Currency, h.Description, h.Status, h.ExpiresAt, h.Captured, h.TransactionId, h.CreatedAt, h.UpdatedAt)
+	return err
+}
+
+func (r *HoldSqlRepo) FindByAccountIdAndId(ctx context.Context, trc trace.Tracer, opts account.HoldFindByAccountIdAndIdOpts) (*account.Hold, error) {
+	ctx, span := trc.Start(ctx, "HoldSqlRepo.FindByAccountIdAndId")
+	defer span.End()
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+holdColumns+" FROM holds WHERE account_id = $1 AND id = $2", opts.AccountId, opts.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	if !res.Next() {
+		return nil, account.HoldNotFound(errors.New("hold not found"))
+	}
+	return scanHold(res)
+}
+
+func (r *HoldSqlRepo) Reserved(ctx context.Context, trc trace.Tracer, opts account.HoldReservedOpts) (decimal.Decimal, error) {
+	ctx, span := trc.Start(ctx, "HoldSqlRepo.Reserved")
+	defer span.End()
+	reserved := decimal.Zero
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT COALESCE(SUM(amount + fee), 0) FROM holds WHERE account_id = $1 AND status = $2", opts.AccountId, account.HoldStatusActive)
+	if err != nil {
+		return reserved, err
+	}
+	defer res.Close()
+	if res.Next() {
+		if err := res.Scan(&reserved); err != nil {
+			return reserved, err
+		}
+	}
+	return reserved, nil
+}
+
+func (r *HoldSqlRepo) ListExpired(ctx context.Context, trc trace.Tracer, opts account.HoldListExpiredOpts) ([]*account.Hold, error) {
+	ctx, span := trc.Start(ctx, "HoldSqlRepo.ListExpired")
+	defer span.End()
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+holdColumns+" FROM holds WHERE status = $1 AND expires_at <= $2 ORDER BY expires_at LIMIT $3", account.HoldStatusActive, opts.Now, opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	holds := make([]*account.Hold, 0, opts.Limit)
+	for res.Next() {
+		h, err := scanHold(res)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, h)
+	}
+	return holds, res.Err()
+}
+
+func scanHold(res *sql.Rows) (*account.Hold, error) {
+	var h account.Hold
+	if err := res.Scan(&h.ID, &h.AccountId, &h.Amount, &h.Fee, &h.Currency, &h.Description, &h.Status, &h.ExpiresAt, &h.Captured, &h.TransactionId, &h.CreatedAt, &h.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
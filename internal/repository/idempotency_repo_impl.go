@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/idempotency"
+	"github.com/9ssi7/bank/pkg/rescode"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// idempotencyTTL is how long a client may replay a request with the same key.
+const idempotencyTTL = 24 * time.Hour
+
+type IdempotencyRedisRepo struct {
+	db *redis.Client
+}
+
+func NewIdempotencyRedisRepo(db *redis.Client) *IdempotencyRedisRepo {
+	return &IdempotencyRedisRepo{
+		db: db,
+	}
+}
+
+func (r *IdempotencyRedisRepo) Start(ctx context.Context, trc trace.Tracer, opts idempotency.StartOpts) (bool, error) {
+	ctx, span := trc.Start(ctx, "IdempotencyRedisRepo.Start")
+	defer span.End()
+	b, err := json.Marshal(opts.Record)
+	if err != nil {
+		return false, rescode.Failed(err)
+	}
+	ok, err := r.db.SetNX(ctx, r.calcKey(opts.UserId, opts.Key), b, idempotencyTTL).Result()
+	if err != nil {
+		return false, rescode.Failed(err)
+	}
+	return ok, nil
+}
+
+func (r *IdempotencyRedisRepo) Find(ctx context.Context, trc trace.Tracer, opts idempotency.FindOpts) (*idempotency.Record, bool, error) {
+	ctx, span := trc.Start(ctx, "IdempotencyRedisRepo.Find")
+	defer span.End()
+	res, err := r.db.Get(ctx, r.calcKey(opts.UserId, opts.Key)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, true, nil
+		}
+		return nil, true, rescode.Failed(err)
+	}
+	var e idempotency.Record
+	if err := json.Unmarshal([]byte(res), &e); err != nil {
+		return nil, false, rescode.Failed(err)
+	}
+	return &e, false, nil
+}
+
+func (r *IdempotencyRedisRepo) Save(ctx context.Context, trc trace.Tracer, opts idempotency.SaveOpts) error {
+	ctx, span := trc.Start(ctx, "IdempotencyRedisRepo.Save")
+	defer span.End()
+	b, err := json.Marshal(opts.Record)
+	if err != nil {
+		return rescode.Failed(err)
+	}
+	if err := r.db.Set(ctx, r.calcKey(opts.UserId, opts.Key), b, idempotencyTTL).Err(); err != nil {
+		return rescode.Failed(err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRedisRepo) Delete(ctx context.Context, trc trace.Tracer, opts idempotency.DeleteOpts) error {
+	ctx, span := trc.Start(ctx, "IdempotencyRedisRepo.Delete")
+	defer span.End()
+	if err := r.db.Del(ctx, r.calcKey(opts.UserId, opts.Key)).Err(); err != nil {
+		return rescode.Failed(err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRedisRepo) Expire(ctx context.Context, trc trace.Tracer, opts idempotency.ExpireOpts) error {
+	ctx, span := trc.Start(ctx, "IdempotencyRedisRepo.Expire")
+	defer span.End()
+	if err := r.db.Expire(ctx, r.calcKey(opts.UserId, opts.Key), opts.In).Err(); err != nil {
+		return rescode.Failed(err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRedisRepo) calcKey(userId uuid.UUID, key string) string {
+	return "idempotency" + "__" + userId.String() + "__" + key
+}
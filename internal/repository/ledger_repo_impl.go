@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/account"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type LedgerSqlRepo struct {
+	txnSqlRepo
+	db *sql.DB
+}
+
+func NewLedgerSqlRepo(db *sql.DB) *LedgerSqlRepo {
+	return &LedgerSqlRepo{
+		db:         db,
+		txnSqlRepo: newTxnSqlRepo(db),
+	}
+}
+
+func (r *LedgerSqlRepo) Save(ctx context.Context, trc trace.Tracer, opts account.LedgerSaveOpts) error {
+	ctx, span := trc.Start(ctx, "LedgerSqlRepo.Save")
+	defer span.End()
+	if opts.Entry.ID == uuid.Nil {
+		opts.Entry.ID = uuid.New()
+	}
+	if opts.Entry.CreatedAt.IsZero() {
+		opts.Entry.CreatedAt = time.Now()
+	}
+	_, err := r.adapter.GetCurrent().ExecContext(ctx, "INSERT INTO journal_entries (id, transaction_id, description, created_at) VALUES ($1, $2, $3, $4)", opts.Entry.ID, opts.Entry.TransactionId, opts.Entry.Description, opts.Entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+	for _, p := range opts.Entry.Postings {
+		p.ID = uuid.New()
+		p.EntryId = opts.Entry.ID
+		p.CreatedAt = opts.Entry.CreatedAt
+		_, err := r.adapter.GetCurrent().ExecContext(ctx, "INSERT INTO postings (id, entry_id, account_id, direction, amount, currency, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", p.ID, p.EntryId, p.AccountId, p.Direction, p.Amount, p.Currency, p.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *LedgerSqlRepo) Balance(ctx context.Context, trc trace.Tracer, opts account.LedgerBalanceOpts) (decimal.Decimal, error) {
+	ctx, span := trc.Start(ctx, "LedgerSqlRepo.Balance")
+	defer span.End()
+	balance := decimal.Zero
+	q := "SELECT COALESCE(SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END), 0) FROM postings WHERE account_id = $1"
+	args := []interface{}{opts.AccountId}
+	if !opts.Before.IsZero() {
+		q += " AND created_at < $2"
+		args = append(args, opts.Before)
+	}
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, q, args...)
+	if err != nil {
+		return balance, err
+	}
+	defer res.Close()
+	if res.Next() {
+		if err := res.Scan(&balance); err != nil {
+			return balance, err
+		}
+	}
+	return balance, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/outbox"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type OutboxSqlRepo struct {
+	txnSqlRepo
+	db *sql.DB
+}
+
+func NewOutboxSqlRepo(db *sql.DB) *OutboxSqlRepo {
+	return &OutboxSqlRepo{
+		db:         db,
+		txnSqlRepo: newTxnSqlRepo(db),
+	}
+}
+
+func (r *OutboxSqlRepo) Save(ctx context.Context, trc trace.Tracer, opts outbox.SaveOpts) error {
+	ctx, span := trc.Start(ctx, "OutboxSqlRepo.Save")
+	defer span.End()
+	m := opts.Message
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+		_, err := r.adapter.GetCurrent().ExecContext(ctx, "INSERT INTO outbox (id, subject, payload, attempts, last_error, created_at, sent_at, failed_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", m.ID, m.Subject, m.Payload, m.Attempts, m.LastError, m.CreatedAt, m.SentAt, m.FailedAt)
+		return err
+	}
+	// saving the outcome of a publish also ends the claim of the relay
+	_, err := r.adapter.GetCurrent().ExecContext(ctx, "UPDATE outbox SET attempts = $2, last_error = $3, sent_at = $4, failed_at = $5, claimed_until = NULL WHERE id = $1", m.ID, m.Attempts, m.LastError, m.SentAt, m.FailedAt)
+	return err
+}
+
+func (r *OutboxSqlRepo) Claim(ctx context.Context, trc trace.Tracer, opts outbox.ClaimOpts) ([]*outbox.Message, error) {
+	ctx, span := trc.Start(ctx, "OutboxSqlRepo.Claim")
+	defer span.End()
+	// the rows are locked only for the update, the claim keeps the other relays off them while publishing
+	// and lapses on its own when the relay dies before saving the outcome
+	q := `UPDATE outbox SET claimed_until = $1, claim_id = $5 WHERE id IN (
+		SELECT id FROM outbox
+		WHERE sent_at IS NULL AND failed_at IS NULL AND attempts < $2 AND (claimed_until IS NULL OR claimed_until < $3)
+		ORDER BY attempts, created_at LIMIT $4 FOR UPDATE SKIP LOCKED
+	) RETURNING id, subject, payload, attempts, last_error, created_at, sent_at, failed_at, claim_id`
+	now := time.Now()
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, q, now.Add(opts.ClaimFor), opts.MaxAttempts, now, opts.Limit, uuid.New())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	messages := make([]*outbox.Message, 0, opts.Limit)
+	for res.Next() {
+		var m outbox.Message
+		if err := res.Scan(&m.ID, &m.Subject, &m.Payload, &m.Attempts, &m.LastError, &m.CreatedAt, &m.SentAt, &m.FailedAt, &m.ClaimId); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &m)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING does not keep the order of the subquery
+	slices.SortFunc(messages, func(a, b *outbox.Message) int {
+		if a.Attempts != b.Attempts {
+			return a.Attempts - b.Attempts
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return messages, nil
+}
+
+func (r *OutboxSqlRepo) Renew(ctx context.Context, trc trace.Tracer, opts outbox.RenewOpts) (bool, error) {
+	ctx, span := trc.Start(ctx, "OutboxSqlRepo.Renew")
+	defer span.End()
+	// a lapsed claim is still renewed as long as no other relay claimed the message since
+	q := "UPDATE outbox SET claimed_until = $3 WHERE id = $1 AND claim_id = $2 AND sent_at IS NULL AND failed_at IS NULL"
+	res, err := r.adapter.GetCurrent().ExecContext(ctx, q, opts.Message.ID, opts.Message.ClaimId, time.Now().Add(opts.ClaimFor))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/9ssi7/bank/internal/domain/auth"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const passkeyColumns = "id, user_id, name, credential_id, public_key, sign_count, aaguid, last_used_at, created_at"
+
+type PasskeySqlRepo struct {
+	txnSqlRepo
+	db *sql.DB
+}
+
+func NewPasskeySqlRepo(db *sql.DB) *PasskeySqlRepo {
+	return &PasskeySqlRepo{
+		db:         db,
+		txnSqlRepo: newTxnSqlRepo(db),
+	}
+}
+
+func (r *PasskeySqlRepo) Save(ctx context.Context, trc trace.Tracer, opts auth.PasskeySaveOpts) error {
+	ctx, span := trc.Start(ctx, "PasskeySqlRepo.Save")
+	defer span.End()
+	p := opts.Passkey
+	q := "UPDATE passkeys SET user_id = $2, name = $3, credential_id = $4, public_key = $5, sign_count = $6, aaguid = $7, last_used_at = $8, created_at = $9 WHERE id = $1"
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+		q = "INSERT INTO passkeys (" + passkeyColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+	}
+	_, err := r.adapter.GetCurrent().ExecContext(ctx, q, p.ID, p.UserId, p.Name, p.CredentialId, p.PublicKey, int64(p.SignCount), p.Aaguid, p.LastUsedAt, p.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == pqUniqueViolation && pqErr.Constraint == "idx_passkeys_credential_id" {
+		return auth.ErrPasskeyTaken
+	}
+	return err
+}
+
+func (r *PasskeySqlRepo) FindByCredentialId(ctx context.Context, trc trace.Tracer, opts auth.PasskeyFindByCredentialIdOpts) (*auth.Passkey, error) {
+	ctx, span := trc.Start(ctx, "PasskeySqlRepo.FindByCredentialId")
+	defer span.End()
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+passkeyColumns+" FROM passkeys WHERE credential_id = $1", opts.CredentialId)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	if !res.Next() {
+		return &auth.Passkey{}, res.Err()
+	}
+	return scanPasskey(res)
+}
+
+func (r *PasskeySqlRepo) ListByUserId(ctx context.Context, trc trace.Tracer, opts auth.PasskeyListByUserIdOpts) ([]*auth.Passkey, error) {
+	ctx, span := trc.Start(ctx, "PasskeySqlRepo.ListByUserId")
+	defer span.End()
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+passkeyColumns+" FROM passkeys WHERE user_id = $1 ORDER BY created_at", opts.UserId)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	passkeys := make([]*auth.Passkey, 0)
+	for res.Next() {
+		p, err := scanPasskey(res)
+		if err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, p)
+	}
+	return passkeys, res.Err()
+}
+
+func scanPasskey(res *sql.Rows) (*auth.Passkey, error) {
+	var p auth.Passkey
+	var signCount int64
+	if err := res.Scan(&p.ID, &p.UserId, &p.Name, &p.CredentialId, &p.PublicKey, &signCount, &p.Aaguid, &p.LastUsedAt, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	p.SignCount = uint32(signCount)
+	return &p, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/9ssi7/bank/internal/domain/auth"
+	"github.com/9ssi7/bank/pkg/rescode"
+	"github.com/9ssi7/bank/pkg/token"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type RefreshFamilyRedisRepo struct {
+	db *redis.Client
+}
+
+func NewRefreshFamilyRedisRepo(db *redis.Client) *RefreshFamilyRedisRepo {
+	return &RefreshFamilyRedisRepo{
+		db: db,
+	}
+}
+
+// MarkUsed adds the token to the used ones of the family and reports false when it was in there already.
+// The family outlives the last token put in it by the lifetime of a refresh token, after that no token of it can be valid anyway.
+func (r *RefreshFamilyRedisRepo) MarkUsed(ctx context.Context, trc trace.Tracer, opts auth.RefreshFamilyMarkUsedOpts) (bool, error) {
+	ctx, span := trc.Start(ctx, "RefreshFamilyRedisRepo.MarkUsed")
+	defer span.End()
+	key := r.calcKey(opts.Family)
+	var added *redis.IntCmd
+	_, err := r.db.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		added = p.SAdd(ctx, key, r.hash(opts.Token))
+		p.Expire(ctx, key, token.RefreshTokenDuration)
+		return nil
+	})
+	if err != nil {
+		return false, rescode.Failed(err)
+	}
+	return added.Val() == 1, nil
+}
+
+func (r *RefreshFamilyRedisRepo) IsUsed(ctx context.Context, trc trace.Tracer, opts auth.RefreshFamilyIsUsedOpts) (bool, error) {
+	ctx, span := trc.Start(ctx, "RefreshFamilyRedisRepo.IsUsed")
+	defer span.End()
+	used, err := r.db.SIsMember(ctx, r.calcKey(opts.Family), r.hash(opts.Token)).Result()
+	if err != nil {
+		return false, rescode.Failed(err)
+	}
+	return used, nil
+}
+
+func (r *RefreshFamilyRedisRepo) calcKey(family string) string {
+	return "refresh_family" + "__" + family
+}
+
+func (r *RefreshFamilyRedisRepo) hash(t string) string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:])
+}
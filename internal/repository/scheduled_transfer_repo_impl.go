@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/schedule"
+	"github.com/9ssi7/bank/pkg/list"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const scheduledTransferColumns = "id, user_id, account_id, amount, to_iban, to_owner, description, cron, status, next_run_at, last_run_at, last_error, runs, failures, created_at, updated_at, claimed_until"
+
+type ScheduledTransferSqlRepo struct {
+	txnSqlRepo
+	db *sql.DB
+}
+
+func NewScheduledTransferSqlRepo(db *sql.DB) *ScheduledTransferSqlRepo {
+	return &ScheduledTransferSqlRepo{
+		db:         db,
+		txnSqlRepo: newTxnSqlRepo(db),
+	}
+}
+
+func (r *ScheduledTransferSqlRepo) Save(ctx context.Context, trc trace.Tracer, opts schedule.SaveOpts) error {
+	ctx, span := trc.Start(ctx, "ScheduledTransferSqlRepo.Save")
+	defer span.End()
+	t := opts.Transfer
+	t.UpdatedAt = time.Now()
+	q := "UPDATE scheduled_transfers SET user_id = $2, account_id = $3, amount = $4, to_iban = $5, to_owner = $6, description = $7, cron = $8, status = $9, next_run_at = $10, last_run_at = $11, last_error = $12, runs = $13, failures = $14, created_at = $15, updated_at = $16, claimed_until = $17 WHERE id = $1"
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+		t.CreatedAt = t.UpdatedAt
+		q = "INSERT INTO scheduled_transfers (" + scheduledTransferColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)"
+	}
+	_, err := r.adapter.GetCurrent().ExecContext(ctx, q, t.ID, t.UserId, t.AccountId, t.Amount, t.ToIban, t.ToOwner, t.Description, t.Cron, t.Status, t.NextRunAt, t.LastRunAt, t.LastError, t.Runs, t.Failures, t.CreatedAt, t.UpdatedAt, t.ClaimedUntil)
+	return err
+}
+
+func (r *ScheduledTransferSqlRepo) FindByAccountIdAndId(ctx context.Context, trc trace.Tracer, opts schedule.FindByAccountIdAndIdOpts) (*schedule.Transfer, error) {
+	ctx, span := trc.Start(ctx, "ScheduledTransferSqlRepo.FindByAccountIdAndId")
+	defer span.End()
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+scheduledTransferColumns+" FROM scheduled_transfers WHERE id = $1 AND account_id = $2 AND user_id = $3", opts.ID, opts.AccountId, opts.UserId)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	if !res.Next() {
+		return nil, schedule.NotFound(errors.New("scheduled transfer not found"))
+	}
+	return scanScheduledTransfer(res)
+}
+
+func (r *ScheduledTransferSqlRepo) FindByAccountIdAndIdForUpdate(ctx context.Context, trc trace.Tracer, opts schedule.FindByAccountIdAndIdOpts) (*schedule.Transfer, error) {
+	ctx, span := trc.Start(ctx, "ScheduledTransferSqlRepo.FindByAccountIdAndIdForUpdate")
+	defer span.End()
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+scheduledTransferColumns+" FROM scheduled_transfers WHERE id = $1 AND account_id = $2 AND user_id = $3 FOR UPDATE", opts.ID, opts.AccountId, opts.UserId)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	if !res.Next() {
+		return nil, schedule.NotFound(errors.New("scheduled transfer not found"))
+	}
+	return scanScheduledTransfer(res)
+}
+
+func (r *ScheduledTransferSqlRepo) ListByAccountId(ctx context.Context, trc trace.Tracer, opts schedule.ListByAccountIdOpts) (*list.PagiResponse[*schedule.Transfer], error) {
+	ctx, span := trc.Start(ctx, "ScheduledTransferSqlRepo.ListByAccountId")
+	defer span.End()
+	var total int64
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT COUNT(*) FROM scheduled_transfers WHERE account_id = $1 AND user_id = $2", opts.AccountId, opts.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if res.Next() {
+		res.Scan(&total)
+	}
+	res.Close()
+	res, err = r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+scheduledTransferColumns+" FROM scheduled_transfers WHERE account_id = $1 AND user_id = $2 ORDER BY created_at DESC LIMIT $3 OFFSET $4", opts.AccountId, opts.UserId, *opts.Pagi.Limit, opts.Pagi.Offset())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	transfers := make([]*schedule.Transfer, 0)
+	for res.Next() {
+		t, err := scanScheduledTransfer(res)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	return &list.PagiResponse[*schedule.Transfer]{
+		List:          transfers,
+		Total:         total,
+		Limit:         *opts.Pagi.Limit,
+		Page:          *opts.Pagi.Page,
+		FilteredTotal: total,
+		TotalPage:     opts.Pagi.TotalPage(total),
+	}, res.Err()
+}
+
+func (r *ScheduledTransferSqlRepo) ListDue(ctx context.Context, trc trace.Tracer, opts schedule.ListDueOpts) ([]*schedule.Transfer, error) {
+	ctx, span := trc.Start(ctx, "ScheduledTransferSqlRepo.ListDue")
+	defer span.End()
+	// skip locked lets several workers claim due transfers without running one twice
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+scheduledTransferColumns+" FROM scheduled_transfers WHERE status = $1 AND next_run_at <= $2 ORDER BY next_run_at LIMIT $3 FOR UPDATE SKIP LOCKED", schedule.StatusActive, opts.Now, opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	transfers := make([]*schedule.Transfer, 0, opts.Limit)
+	for res.Next() {
+		t, err := scanScheduledTransfer(res)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, res.Err()
+}
+
+func scanScheduledTransfer(res *sql.Rows) (*schedule.Transfer, error) {
+	var t schedule.Transfer
+	if err := res.Scan(&t.ID, &t.UserId, &t.AccountId, &t.Amount, &t.ToIban, &t.ToOwner, &t.Description, &t.Cron, &t.Status, &t.NextRunAt, &t.LastRunAt, &t.LastError, &t.Runs, &t.Failures, &t.CreatedAt, &t.UpdatedAt, &t.ClaimedUntil); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
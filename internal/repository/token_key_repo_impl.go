@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/9ssi7/bank/pkg/token"
+	"github.com/redis/go-redis/v9"
+)
+
+const tokenKeysKey = "token_keys"
+
+// TokenKeyRedisRepo shares the rotated signing keys of the token service between the instances,
+// they are kept in one hash by kid so two instances rotating at once do not overwrite each other.
+// The private keys come sealed by the keyset, the store never sees them in the clear.
+type TokenKeyRedisRepo struct {
+	db *redis.Client
+}
+
+func NewTokenKeyRedisRepo(db *redis.Client) *TokenKeyRedisRepo {
+	return &TokenKeyRedisRepo{
+		db: db,
+	}
+}
+
+func (r *TokenKeyRedisRepo) LoadKeys(ctx context.Context) ([]token.StoredKey, error) {
+	res, err := r.db.HGetAll(ctx, tokenKeysKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]token.StoredKey, 0, len(res))
+	for _, v := range res {
+		var k token.StoredKey
+		if err := json.Unmarshal([]byte(v), &k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (r *TokenKeyRedisRepo) SaveKey(ctx context.Context, key token.StoredKey) error {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return r.db.HSet(ctx, tokenKeysKey, key.Kid, b).Err()
+}
+
+func (r *TokenKeyRedisRepo) DeleteKey(ctx context.Context, kid string) error {
+	return r.db.HDel(ctx, tokenKeysKey, kid).Err()
+}
+
+// TokenDenylistRedisRepo keeps the ids of the revoked tokens until the tokens expire.
+type TokenDenylistRedisRepo struct {
+	db *redis.Client
+}
+
+func NewTokenDenylistRedisRepo(db *redis.Client) *TokenDenylistRedisRepo {
+	return &TokenDenylistRedisRepo{
+		db: db,
+	}
+}
+
+func (r *TokenDenylistRedisRepo) Deny(ctx context.Context, jti string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return r.db.SetEx(ctx, r.calcKey(jti), 1, ttl).Err()
+}
+
+func (r *TokenDenylistRedisRepo) IsDenied(ctx context.Context, jti string) (bool, error) {
+	n, err := r.db.Exists(ctx, r.calcKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *TokenDenylistRedisRepo) calcKey(jti string) string {
+	return "token_denied" + "__" + jti
+}
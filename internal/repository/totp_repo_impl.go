@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/auth"
+	"github.com/9ssi7/bank/pkg/seal"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TotpSqlRepo keeps the secrets sealed with the key encryption key, bound to the user they belong to.
+type TotpSqlRepo struct {
+	txnSqlRepo
+	db     *sql.DB
+	sealer *seal.Sealer
+}
+
+func NewTotpSqlRepo(db *sql.DB, sealer *seal.Sealer) *TotpSqlRepo {
+	return &TotpSqlRepo{
+		db:         db,
+		sealer:     sealer,
+		txnSqlRepo: newTxnSqlRepo(db),
+	}
+}
+
+func (r *TotpSqlRepo) Save(ctx context.Context, trc trace.Tracer, opts auth.TotpSaveOpts) error {
+	ctx, span := trc.Start(ctx, "TotpSqlRepo.Save")
+	defer span.End()
+	t := opts.Totp
+	sealed, err := r.sealer.Seal([]byte(t.Secret), t.UserId[:])
+	if err != nil {
+		return err
+	}
+	// saving drops the secret a row kept in the clear from before the secrets were sealed
+	q := "INSERT INTO user_totps (user_id, secret, secret_sealed, last_step, failures, locked_until, confirmed_at, created_at, updated_at) VALUES ($1, NULL, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (user_id) DO UPDATE SET secret = NULL, secret_sealed = $2, last_step = $3, failures = $4, locked_until = $5, confirmed_at = $6, created_at = $7, updated_at = $8"
+	_, err = r.adapter.GetCurrent().ExecContext(ctx, q, t.UserId, sealed, t.LastStep, t.Failures, t.LockedUntil, t.ConfirmedAt, t.CreatedAt, t.UpdatedAt)
+	return err
+}
+
+func (r *TotpSqlRepo) FindByUserId(ctx context.Context, trc trace.Tracer, opts auth.TotpFindByUserIdOpts) (*auth.Totp, error) {
+	ctx, span := trc.Start(ctx, "TotpSqlRepo.FindByUserId")
+	defer span.End()
+	var t auth.Totp
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT user_id, secret, secret_sealed, last_step, failures, locked_until, confirmed_at, created_at, updated_at FROM user_totps WHERE user_id = $1", opts.UserId)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	if res.Next() {
+		var secret sql.NullString
+		var sealed []byte
+		if err := res.Scan(&t.UserId, &secret, &sealed, &t.LastStep, &t.Failures, &t.LockedUntil, &t.ConfirmedAt, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		t.Secret = secret.String
+		if sealed != nil {
+			plain, err := r.sealer.Open(sealed, t.UserId[:])
+			if err != nil {
+				return nil, err
+			}
+			t.Secret = string(plain)
+		}
+	}
+	return &t, res.Err()
+}
+
+func (r *TotpSqlRepo) Delete(ctx context.Context, trc trace.Tracer, opts auth.TotpDeleteOpts) error {
+	ctx, span := trc.Start(ctx, "TotpSqlRepo.Delete")
+	defer span.End()
+	if _, err := r.adapter.GetCurrent().ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", opts.UserId); err != nil {
+		return err
+	}
+	_, err := r.adapter.GetCurrent().ExecContext(ctx, "DELETE FROM user_totps WHERE user_id = $1", opts.UserId)
+	return err
+}
+
+func (r *TotpSqlRepo) SaveRecoveryCodes(ctx context.Context, trc trace.Tracer, opts auth.TotpSaveRecoveryCodesOpts) error {
+	ctx, span := trc.Start(ctx, "TotpSqlRepo.SaveRecoveryCodes")
+	defer span.End()
+	if _, err := r.adapter.GetCurrent().ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", opts.UserId); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, h := range opts.Hashes {
+		_, err := r.adapter.GetCurrent().ExecContext(ctx, "INSERT INTO user_recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)", uuid.New(), opts.UserId, h, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the code used and reports whether it was there unused, a code works once.
+func (r *TotpSqlRepo) UseRecoveryCode(ctx context.Context, trc trace.Tracer, opts auth.TotpUseRecoveryCodeOpts) (bool, error) {
+	ctx, span := trc.Start(ctx, "TotpSqlRepo.UseRecoveryCode")
+	defer span.End()
+	res, err := r.adapter.GetCurrent().ExecContext(ctx, "UPDATE user_recovery_codes SET used_at = $3 WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL", opts.UserId, opts.Hash, time.Now())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
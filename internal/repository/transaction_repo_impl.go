@@ -3,16 +3,21 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/9ssi7/bank/internal/domain/account"
+	"github.com/9ssi7/bank/internal/domain/limit"
 	"github.com/9ssi7/bank/pkg/list"
 	"github.com/9ssi7/bank/pkg/query"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
 	"go.opentelemetry.io/otel/trace"
 )
 
+const transactionColumns = "id, sender_id, receiver_id, amount, description, kind, created_at, COALESCE(currency, ''), COALESCE(receiver_amount, amount), COALESCE(receiver_currency, ''), fx_rate, fx_rate_id, parent_id, reference_id"
+
 type TransactionSqlRepo struct {
-	syncRepo
 	txnSqlRepo
 	db *sql.DB
 }
@@ -21,21 +26,25 @@ func NewTransactionSqlRepo(db *sql.DB) *TransactionSqlRepo {
 	return &TransactionSqlRepo{
 		db:         db,
 		txnSqlRepo: newTxnSqlRepo(db),
-		syncRepo:   newSyncRepo(),
 	}
 }
 
 func (r *TransactionSqlRepo) Save(ctx context.Context, trc trace.Tracer, opts account.TransactionSaveOpts) error {
 	ctx, span := trc.Start(ctx, "TransactionSqlRepo.Save")
 	defer span.End()
-	r.syncRepo.Lock()
-	defer r.syncRepo.Unlock()
-	q := "INSERT INTO transactions (id, sender_id, receiver_id, amount, description, kind, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)"
-	if opts.Transaction.ID == uuid.Nil {
-		opts.Transaction.ID = uuid.New()
-		q = "UPDATE transactions SET sender_id = $2, receiver_id = $3, amount = $4, description = $5, kind = $6, created_at = $7 WHERE id = $1"
-	}
-	_, err := r.adapter.GetCurrent().ExecContext(ctx, q, opts.Transaction.ID, opts.Transaction.SenderId, opts.Transaction.ReceiverId, opts.Transaction.Amount, opts.Transaction.Description, opts.Transaction.Kind, opts.Transaction.CreatedAt)
+	t := opts.Transaction
+	q := "UPDATE transactions SET sender_id = $2, receiver_id = $3, amount = $4, description = $5, kind = $6, created_at = $7, currency = $8, receiver_amount = $9, receiver_currency = $10, fx_rate = $11, fx_rate_id = $12, parent_id = $13, reference_id = $14 WHERE id = $1"
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+		q = "INSERT INTO transactions (id, sender_id, receiver_id, amount, description, kind, created_at, currency, receiver_amount, receiver_currency, fx_rate, fx_rate_id, parent_id, reference_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)"
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	_, err := r.adapter.GetCurrent().ExecContext(ctx, q, t.ID, t.SenderId, t.ReceiverId, t.Amount, t.Description, t.Kind, t.CreatedAt, t.Currency, t.ReceiverAmount, t.ReceiverCurrency, t.FxRate, t.FxRateId, t.ParentId, t.ReferenceId)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == pqUniqueViolation && pqErr.Constraint == "idx_transactions_reference_id" {
+		return account.ErrAlreadyReversed
+	}
 	return err
 }
 
@@ -79,14 +88,14 @@ func (r *TransactionSqlRepo) Filter(ctx context.Context, trc trace.Tracer, opts
 			Skip:   false,
 		},
 	})
-	q := "SELECT * FROM transactions WHERE " + conds
+	q := "SELECT " + transactionColumns + " FROM transactions WHERE " + conds
 	res, err = r.adapter.GetCurrent().QueryContext(ctx, q, vals...)
 	if err != nil {
 		return nil, err
 	}
 	for res.Next() {
 		var t account.Transaction
-		res.Scan(&t.ID, &t.SenderId, &t.ReceiverId, &t.Amount, &t.Description, &t.Kind, &t.CreatedAt)
+		res.Scan(&t.ID, &t.SenderId, &t.ReceiverId, &t.Amount, &t.Description, &t.Kind, &t.CreatedAt, &t.Currency, &t.ReceiverAmount, &t.ReceiverCurrency, &t.FxRate, &t.FxRateId, &t.ParentId, &t.ReferenceId)
 		transactions = append(transactions, &t)
 	}
 	res.Close()
@@ -99,3 +108,102 @@ func (r *TransactionSqlRepo) Filter(ctx context.Context, trc trace.Tracer, opts
 		TotalPage:     opts.Pagi.TotalPage(total),
 	}, nil
 }
+
+func (r *TransactionSqlRepo) Stream(ctx context.Context, trc trace.Tracer, opts account.TransactionStreamOpts, fn func(*account.Transaction) error) error {
+	ctx, span := trc.Start(ctx, "TransactionSqlRepo.Stream")
+	defer span.End()
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+transactionColumns+" FROM transactions WHERE (sender_id = $1 OR receiver_id = $1) AND created_at >= $2 AND created_at < $3 ORDER BY created_at, id", opts.AccountId, opts.From, opts.To)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	for res.Next() {
+		var t account.Transaction
+		if err := res.Scan(&t.ID, &t.SenderId, &t.ReceiverId, &t.Amount, &t.Description, &t.Kind, &t.CreatedAt, &t.Currency, &t.ReceiverAmount, &t.ReceiverCurrency, &t.FxRate, &t.FxRateId, &t.ParentId, &t.ReferenceId); err != nil {
+			return err
+		}
+		if err := fn(&t); err != nil {
+			return err
+		}
+	}
+	return res.Err()
+}
+
+func (r *TransactionSqlRepo) FindById(ctx context.Context, trc trace.Tracer, opts account.TransactionFindByIdOpts) (*account.Transaction, error) {
+	ctx, span := trc.Start(ctx, "TransactionSqlRepo.FindById")
+	defer span.End()
+	return r.findOne(ctx, "SELECT "+transactionColumns+" FROM transactions WHERE id = $1", opts.ID)
+}
+
+func (r *TransactionSqlRepo) FindByReferenceId(ctx context.Context, trc trace.Tracer, opts account.TransactionFindByReferenceIdOpts) (*account.Transaction, error) {
+	ctx, span := trc.Start(ctx, "TransactionSqlRepo.FindByReferenceId")
+	defer span.End()
+	return r.findOne(ctx, "SELECT "+transactionColumns+" FROM transactions WHERE reference_id = $1", opts.ReferenceId)
+}
+
+func (r *TransactionSqlRepo) ListByParentId(ctx context.Context, trc trace.Tracer, opts account.TransactionListByParentIdOpts) ([]*account.Transaction, error) {
+	ctx, span := trc.Start(ctx, "TransactionSqlRepo.ListByParentId")
+	defer span.End()
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, "SELECT "+transactionColumns+" FROM transactions WHERE parent_id = $1 ORDER BY created_at, id", opts.ParentId)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	transactions := make([]*account.Transaction, 0)
+	for res.Next() {
+		var t account.Transaction
+		if err := res.Scan(&t.ID, &t.SenderId, &t.ReceiverId, &t.Amount, &t.Description, &t.Kind, &t.CreatedAt, &t.Currency, &t.ReceiverAmount, &t.ReceiverCurrency, &t.FxRate, &t.FxRateId, &t.ParentId, &t.ReferenceId); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, &t)
+	}
+	return transactions, res.Err()
+}
+
+func (r *TransactionSqlRepo) Usage(ctx context.Context, trc trace.Tracer, opts account.TransactionUsageOpts) (*limit.Usage, error) {
+	ctx, span := trc.Start(ctx, "TransactionSqlRepo.Usage")
+	defer span.End()
+	scope := "sender_id = $1"
+	holdScope := "account_id = $1"
+	id := opts.AccountId
+	if opts.UserId != uuid.Nil {
+		scope = "sender_id IN (SELECT id FROM accounts WHERE user_id = $1) AND (kind = 'withdrawal' OR receiver_id NOT IN (SELECT id FROM accounts WHERE user_id = $1))"
+		holdScope = "account_id IN (SELECT id FROM accounts WHERE user_id = $1)"
+		id = opts.UserId
+	}
+	// active holds count as the withdrawals they become, a captured one is counted by its transaction,
+	// a reversed transfer gave the money back and counts no more
+	q := "SELECT COUNT(*) FILTER (WHERE created_at >= $2), COALESCE(SUM(amount) FILTER (WHERE created_at >= $2), 0), COUNT(*), COALESCE(SUM(amount), 0) FROM (" +
+		"SELECT amount, created_at FROM transactions t WHERE " + scope + " AND kind IN ('transfer', 'withdrawal') AND currency = $3 AND created_at >= $4" +
+		" AND NOT EXISTS (SELECT 1 FROM transactions r WHERE r.reference_id = t.id)" +
+		" UNION ALL SELECT amount, created_at FROM holds WHERE " + holdScope + " AND status = 'active' AND currency = $3 AND created_at >= $4" +
+		") spent"
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, q, id, opts.Day, opts.Currency, opts.Month)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	u := limit.Usage{DailyAmount: decimal.Zero, MonthlyAmount: decimal.Zero}
+	if res.Next() {
+		if err := res.Scan(&u.DailyCount, &u.DailyAmount, &u.MonthlyCount, &u.MonthlyAmount); err != nil {
+			return nil, err
+		}
+	}
+	return &u, res.Err()
+}
+
+// findOne returns the transaction of the query, or an empty one when there is none.
+func (r *TransactionSqlRepo) findOne(ctx context.Context, q string, args ...interface{}) (*account.Transaction, error) {
+	var t account.Transaction
+	res, err := r.adapter.GetCurrent().QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	if res.Next() {
+		if err := res.Scan(&t.ID, &t.SenderId, &t.ReceiverId, &t.Amount, &t.Description, &t.Kind, &t.CreatedAt, &t.Currency, &t.ReceiverAmount, &t.ReceiverCurrency, &t.FxRate, &t.FxRateId, &t.ParentId, &t.ReferenceId); err != nil {
+			return nil, err
+		}
+	}
+	return &t, res.Err()
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/auth"
+	"github.com/9ssi7/bank/pkg/rescode"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type WebauthnRedisRepo struct {
+	db *redis.Client
+}
+
+func NewWebauthnRedisRepo(db *redis.Client) *WebauthnRedisRepo {
+	return &WebauthnRedisRepo{
+		db: db,
+	}
+}
+
+func (r *WebauthnRedisRepo) Save(ctx context.Context, trc trace.Tracer, opts auth.WebauthnSaveOpts) error {
+	ctx, span := trc.Start(ctx, "WebauthnRedisRepo.Save")
+	defer span.End()
+	b, err := json.Marshal(opts.Challenge)
+	if err != nil {
+		return rescode.Failed(err)
+	}
+	if err = r.db.SetEx(ctx, r.calcKey(opts.Challenge.DeviceId, opts.Token), b, 5*time.Minute).Err(); err != nil {
+		return rescode.Failed(err)
+	}
+	return nil
+}
+
+// Take removes the challenge as it reads it, so a ceremony can not be finished twice.
+func (r *WebauthnRedisRepo) Take(ctx context.Context, trc trace.Tracer, opts auth.WebauthnTakeOpts) (*auth.WebauthnChallenge, error) {
+	ctx, span := trc.Start(ctx, "WebauthnRedisRepo.Take")
+	defer span.End()
+	res, err := r.db.GetDel(ctx, r.calcKey(opts.DeviceId, opts.Token)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, auth.WebauthnInvalid(errors.New("challenge expired or already used"))
+	}
+	if err != nil {
+		return nil, rescode.Failed(err)
+	}
+	var c auth.WebauthnChallenge
+	if err = json.Unmarshal([]byte(res), &c); err != nil {
+		return nil, rescode.Failed(err)
+	}
+	return &c, nil
+}
+
+func (r *WebauthnRedisRepo) calcKey(deviceId string, token string) string {
+	return "webauthn" + "__" + token + "__" + deviceId
+}
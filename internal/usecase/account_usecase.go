@@ -2,14 +2,26 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"time"
 
 	"github.com/9ssi7/bank/internal/domain/account"
+	"github.com/9ssi7/bank/internal/domain/auth"
+	"github.com/9ssi7/bank/internal/domain/fee"
+	"github.com/9ssi7/bank/internal/domain/fx"
+	"github.com/9ssi7/bank/internal/domain/limit"
+	"github.com/9ssi7/bank/internal/domain/outbox"
 	"github.com/9ssi7/bank/internal/domain/user"
-	"github.com/9ssi7/bank/internal/infra/eventer"
+	"github.com/9ssi7/bank/pkg/iban"
 	"github.com/9ssi7/bank/pkg/list"
+	"github.com/9ssi7/bank/pkg/money"
+	"github.com/9ssi7/bank/pkg/pain"
 	"github.com/9ssi7/bank/pkg/rescode"
+	"github.com/9ssi7/bank/pkg/retry"
+	"github.com/9ssi7/bank/pkg/state"
+	"github.com/9ssi7/bank/pkg/statement"
 	"github.com/9ssi7/txn"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
@@ -17,10 +29,22 @@ import (
 )
 
 type AccountUseCase struct {
-	EventSrv        *eventer.Srv
+	OutboxRepo      outbox.Repo
 	AccountRepo     account.Repo
 	TransactionRepo account.TransactionRepo
+	LedgerRepo      account.LedgerRepo
+	HoldRepo        account.HoldRepo
 	UserRepo        user.Repo
+	FxRepo          fx.Repo
+	FxProvider      fx.Provider
+	FeePolicy       *fee.Policy
+	LimitPolicy     *limit.Policy
+	Ibans           *iban.Generator
+
+	// Transfers above the threshold of their currency wait for a code mailed to the user,
+	// currencies without a threshold never do.
+	VerifyRepo       auth.VerifyRepo
+	StepUpThresholds map[string]decimal.Decimal
 }
 
 type AccountActivateOpts struct {
@@ -31,18 +55,20 @@ type AccountActivateOpts struct {
 func (u *AccountUseCase) Activate(ctx context.Context, trc trace.Tracer, opts AccountActivateOpts) error {
 	ctx, span := trc.Start(ctx, "AccountUseCase.Activate")
 	defer span.End()
-	acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{
-		ID:     opts.AccountId,
-		UserId: opts.UserId,
+	return retryOnConflict(func() error {
+		acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{
+			ID:     opts.AccountId,
+			UserId: opts.UserId,
+		})
+		if err != nil {
+			return err
+		}
+		acc.Activate()
+		if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
+			return err
+		}
+		return nil
 	})
-	if err != nil {
-		return err
-	}
-	acc.Activate()
-	if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
-		return err
-	}
-	return nil
 }
 
 type AccountCreateOpts struct {
@@ -52,307 +78,1314 @@ type AccountCreateOpts struct {
 	Currency string
 }
 
-func (u *AccountUseCase) Create(ctx context.Context, trc trace.Tracer, opts AccountCreateOpts) (*uuid.UUID, error) {
-	ctx, span := trc.Start(ctx, "AccountUseCase.Create")
-	defer span.End()
-	acc := account.New(account.Config{
-		UserId:   opts.UserId,
-		Name:     opts.Name,
-		Owner:    opts.Owner,
-		Currency: opts.Currency,
-	})
-	if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
-		return nil, err
+func (u *AccountUseCase) Create(ctx context.Context, trc trace.Tracer, opts AccountCreateOpts) (*uuid.UUID, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Create")
+	defer span.End()
+	acc := account.New(account.Config{
+		UserId:   opts.UserId,
+		Name:     opts.Name,
+		Owner:    opts.Owner,
+		Currency: opts.Currency,
+	})
+	// generated ibans are random, a collision with an existing one is retried with a new iban
+	err := retry.Run(func() error {
+		acc.Iban = u.Ibans.New()
+		return u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc})
+	}, retry.Config{
+		MaxRetries: 5,
+		RetryIf: func(err error) bool {
+			return errors.Is(err, account.ErrIbanTaken)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &acc.ID, nil
+}
+
+type AccountCreditOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	UserEmail string
+	UserName  string
+	Amount    string
+}
+
+func (u *AccountUseCase) Credit(ctx context.Context, trc trace.Tracer, opts AccountCreditOpts) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Credit")
+	defer span.End()
+	return retryOnConflict(func() error {
+		txn, err := u.beginTxn(ctx)
+		if err != nil {
+			return err
+		}
+		onError := func(ctx context.Context, err error) error {
+			txn.Rollback(ctx)
+			return err
+		}
+		acc, err := u.lockOwned(ctx, trc, opts.UserId, opts.AccountId)
+		if err != nil {
+			return onError(ctx, err)
+		}
+		if !acc.IsAvailable() {
+			return onError(ctx, account.NotAvailable(errors.New("sender account not available")))
+		}
+		amount, err := parseAmount(opts.Amount, acc.Currency)
+		if err != nil {
+			return onError(ctx, err)
+		}
+		fee := u.FeePolicy.Compute(amount.Amount, acc.Currency, account.TransactionKindDeposit.String())
+		if fee.GreaterThan(acc.AvailableBalance.Add(amount.Amount)) {
+			return onError(ctx, account.BalanceInsufficient(errors.New("balance does not cover the deposit fee")))
+		}
+		tx := account.NewTransaction(account.TransactionConfig{
+			SenderId:    acc.ID,
+			ReceiverId:  acc.ID, // receiver id is the same as sender id because it is a deposit
+			Amount:      amount.Amount,
+			Currency:    acc.Currency,
+			Description: "Load balance",
+			Kind:        account.TransactionKindDeposit,
+		})
+		if err := u.TransactionRepo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx}); err != nil {
+			return onError(ctx, err)
+		}
+		entry := account.NewJournalEntry(account.JournalEntryConfig{TransactionId: tx.ID, Description: tx.Description})
+		entry.Debit(account.LedgerCashAccountId, amount.Amount, acc.Currency)
+		entry.Credit(acc.ID, amount.Amount, acc.Currency)
+		if err := u.post(ctx, trc, entry); err != nil {
+			return onError(ctx, err)
+		}
+		if err := u.chargeFee(ctx, trc, acc, tx, fee); err != nil {
+			return onError(ctx, err)
+		}
+		if err := u.reconcile(ctx, trc, acc); err != nil {
+			return onError(ctx, err)
+		}
+		err = enqueue(ctx, trc, u.OutboxRepo, account.SubjectTransferIncoming, &account.EventTranfserIncoming{
+			Name:        opts.UserName,
+			Amount:      amount.String(),
+			Currency:    acc.Currency,
+			Email:       opts.UserEmail,
+			Account:     acc.Name,
+			Description: "Load balance",
+		})
+		if err != nil {
+			return onError(ctx, err)
+		}
+		if err := txn.Commit(ctx); err != nil {
+			return onError(ctx, err)
+		}
+		return nil
+	})
+}
+
+type AccountDebitOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	UserEmail string
+	UserName  string
+	Amount    string
+}
+
+func (u *AccountUseCase) Debit(ctx context.Context, trc trace.Tracer, opts AccountDebitOpts) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Debit")
+	defer span.End()
+	return retryOnConflict(func() error {
+		txn, err := u.beginTxn(ctx)
+		if err != nil {
+			return err
+		}
+		onError := func(ctx context.Context, err error) error {
+			txn.Rollback(ctx)
+			return err
+		}
+		acc, err := u.lockOwned(ctx, trc, opts.UserId, opts.AccountId)
+		if err != nil {
+			return onError(ctx, err)
+		}
+		if !acc.IsAvailable() {
+			return onError(ctx, account.NotAvailable(errors.New("sender account not available")))
+		}
+		amount, err := parseAmount(opts.Amount, acc.Currency)
+		if err != nil {
+			return onError(ctx, err)
+		}
+		fee := u.FeePolicy.Compute(amount.Amount, acc.Currency, account.TransactionKindWithdrawal.String())
+		amountToPay := amount.Amount.Add(fee)
+		if !acc.CanCredit(amountToPay) {
+			return onError(ctx, account.BalanceInsufficient(errors.New("sender account balance insufficient")))
+		}
+		if err := u.checkLimits(ctx, trc, acc, amount.Amount, true); err != nil {
+			return onError(ctx, err)
+		}
+		tx := account.NewTransaction(account.TransactionConfig{
+			SenderId:    acc.ID,
+			ReceiverId:  acc.ID, // receiver id is the same as sender id because it is a withdrawal
+			Amount:      amount.Amount,
+			Currency:    acc.Currency,
+			Description: "Withdraw balance",
+			Kind:        account.TransactionKindWithdrawal,
+		})
+		if err := u.TransactionRepo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx}); err != nil {
+			return onError(ctx, err)
+		}
+		entry := account.NewJournalEntry(account.JournalEntryConfig{TransactionId: tx.ID, Description: tx.Description})
+		entry.Debit(acc.ID, amount.Amount, acc.Currency)
+		entry.Credit(account.LedgerCashAccountId, amount.Amount, acc.Currency)
+		if err := u.post(ctx, trc, entry); err != nil {
+			return onError(ctx, err)
+		}
+		if err := u.chargeFee(ctx, trc, acc, tx, fee); err != nil {
+			return onError(ctx, err)
+		}
+		if err := u.reconcile(ctx, trc, acc); err != nil {
+			return onError(ctx, err)
+		}
+		err = enqueue(ctx, trc, u.OutboxRepo, account.SubjectTransferOutgoing, &account.EventTranfserOutgoing{
+			Name:        opts.UserName,
+			Amount:      money.Money{Amount: amountToPay, Currency: acc.Currency}.String(),
+			Email:       opts.UserEmail,
+			Currency:    acc.Currency,
+			Account:     acc.Name,
+			Description: "Withdraw balance",
+		})
+		if err != nil {
+			return onError(ctx, err)
+		}
+		if err := txn.Commit(ctx); err != nil {
+			return onError(ctx, err)
+		}
+		return nil
+	})
+}
+
+type AccountAuthorizeOpts struct {
+	UserId      uuid.UUID
+	AccountId   uuid.UUID
+	Amount      string
+	Description string
+
+	// ExpiresIn is how long the money stays reserved, zero takes account.DefaultHoldExpiry.
+	ExpiresIn time.Duration
+}
+
+// Authorize reserves an amount and the withdrawal fee for it, lowering the available balance until
+// the hold is captured, voided or expires. It returns the id of the hold.
+func (u *AccountUseCase) Authorize(ctx context.Context, trc trace.Tracer, opts AccountAuthorizeOpts) (*uuid.UUID, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Authorize")
+	defer span.End()
+	if opts.ExpiresIn <= 0 {
+		opts.ExpiresIn = account.DefaultHoldExpiry
+	}
+	var hold *account.Hold
+	err := retryOnConflict(func() error {
+		txn, err := u.beginTxn(ctx)
+		if err != nil {
+			return err
+		}
+		onError := func(ctx context.Context, err error) error {
+			txn.Rollback(ctx)
+			return err
+		}
+		acc, err := u.lockOwned(ctx, trc, opts.UserId, opts.AccountId)
+		if err != nil {
+			return onError(ctx, err)
+		}
+		if !acc.IsAvailable() {
+			return onError(ctx, account.NotAvailable(errors.New("account not available")))
+		}
+		amount, err := parseAmount(opts.Amount, acc.Currency)
+		if err != nil {
+			return onError(ctx, err)
+		}
+		fee := u.FeePolicy.Compute(amount.Amount, acc.Currency, account.TransactionKindWithdrawal.String())
+		if !acc.CanCredit(amount.Amount.Add(fee)) {
+			return onError(ctx, account.BalanceInsufficient(errors.New("available balance insufficient")))
+		}
+		// the capture only books what the hold reserved, so the limits are checked here
+		if err := u.checkLimits(ctx, trc, acc, amount.Amount, true); err != nil {
+			return onError(ctx, err)
+		}
+		hold = account.NewHold(account.HoldConfig{
+			AccountId:   acc.ID,
+			Amount:      amount.Amount,
+			Fee:         fee,
+			Currency:    acc.Currency,
+			Description: opts.Description,
+			ExpiresAt:   time.Now().Add(opts.ExpiresIn),
+		})
+		if err := u.HoldRepo.Save(ctx, trc, account.HoldSaveOpts{Hold: hold}); err != nil {
+			return onError(ctx, err)
+		}
+		if err := u.reconcile(ctx, trc, acc); err != nil {
+			return onError(ctx, err)
+		}
+		if err := txn.Commit(ctx); err != nil {
+			return onError(ctx, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hold.ID, nil
+}
+
+type AccountCaptureOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	HoldId    uuid.UUID
+	UserEmail string
+	UserName  string
+
+	// Amount may be less than the held amount, the rest is released. Empty captures all of it.
+	Amount string
+}
+
+// Capture books a withdrawal of the held amount, or of a part of it, and settles the hold.
+// The fee is computed for the captured amount and never exceeds the fee reserved by the hold.
+func (u *AccountUseCase) Capture(ctx context.Context, trc trace.Tracer, opts AccountCaptureOpts) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Capture")
+	defer span.End()
+	return retryOnConflict(func() error {
+		txn, err := u.beginTxn(ctx)
+		if err != nil {
+			return err
+		}
+		onError := func(ctx context.Context, err error) error {
+			txn.Rollback(ctx)
+			return err
+		}
+		acc, hold, err := u.lockHold(ctx, trc, opts.UserId, opts.AccountId, opts.HoldId)
+		if err != nil {
+			return onError(ctx, err)
+		}
+		amount := hold.Amount
+		if opts.Amount != "" {
+			m, err := parseAmount(opts.Amount, acc.Currency)
+			if err != nil {
+				return onError(ctx, err)
+			}
+			if m.Amount.GreaterThan(hold.Amount) {
+				return onError(ctx, account.HoldAmountExceeded(errors.New("capture exceeds the held amount")))
+			}
+			amount = m.Amount
+		}
+		fee := decimal.Min(u.FeePolicy.Compute(amount, acc.Currency, account.TransactionKindWithdrawal.String()), hold.Fee)
+		tx := account.NewTransaction(account.TransactionConfig{
+			SenderId:    acc.ID,
+			ReceiverId:  acc.ID, // receiver id is the same as sender id because it is a withdrawal
+			Amount:      amount,
+			Currency:    acc.Currency,
+			Description: hold.Description,
+			Kind:        account.TransactionKindWithdrawal,
+		})
+		if err := u.TransactionRepo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx}); err != nil {
+			return onError(ctx, err)
+		}
+		// the hold is settled first, so reconcile no longer counts it as reserved
+		hold.Capture(amount, tx.ID)
+		if err := u.HoldRepo.Save(ctx, trc, account.HoldSaveOpts{Hold: hold}); err != nil {
+			return onError(ctx, err)
+		}
+		entry := account.NewJournalEntry(account.JournalEntryConfig{TransactionId: tx.ID, Description: tx.Description})
+		entry.Debit(acc.ID, amount, acc.Currency)
+		entry.Credit(account.LedgerCashAccountId, amount, acc.Currency)
+		if err := u.post(ctx, trc, entry); err != nil {
+			return onError(ctx, err)
+		}
+		if err := u.chargeFee(ctx, trc, acc, tx, fee); err != nil {
+			return onError(ctx, err)
+		}
+		if err := u.reconcile(ctx, trc, acc); err != nil {
+			return onError(ctx, err)
+		}
+		err = enqueue(ctx, trc, u.OutboxRepo, account.SubjectTransferOutgoing, &account.EventTranfserOutgoing{
+			Name:        opts.UserName,
+			Amount:      money.Money{Amount: amount.Add(fee), Currency: acc.Currency}.String(),
+			Email:       opts.UserEmail,
+			Currency:    acc.Currency,
+			Account:     acc.Name,
+			Description: hold.Description,
+		})
+		if err != nil {
+			return onError(ctx, err)
+		}
+		if err := txn.Commit(ctx); err != nil {
+			return onError(ctx, err)
+		}
+		return nil
+	})
+}
+
+type AccountVoidOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	HoldId    uuid.UUID
+}
+
+// Void releases a hold without moving any money.
+func (u *AccountUseCase) Void(ctx context.Context, trc trace.Tracer, opts AccountVoidOpts) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Void")
+	defer span.End()
+	return retryOnConflict(func() error {
+		txn, err := u.beginTxn(ctx)
+		if err != nil {
+			return err
+		}
+		acc, hold, err := u.lockHold(ctx, trc, opts.UserId, opts.AccountId, opts.HoldId)
+		if err != nil {
+			txn.Rollback(ctx)
+			return err
+		}
+		hold.Void()
+		if err := u.release(ctx, trc, acc, hold); err != nil {
+			txn.Rollback(ctx)
+			return err
+		}
+		if err := txn.Commit(ctx); err != nil {
+			txn.Rollback(ctx)
+			return err
+		}
+		return nil
+	})
+}
+
+type AccountReleaseExpiredOpts struct {
+	Limit int
+}
+
+// ReleaseExpired expires the active holds past their expiry, each in its own transaction,
+// and returns how many it released.
+func (u *AccountUseCase) ReleaseExpired(ctx context.Context, trc trace.Tracer, opts AccountReleaseExpiredOpts) (int, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.ReleaseExpired")
+	defer span.End()
+	holds, err := u.HoldRepo.ListExpired(ctx, trc, account.HoldListExpiredOpts{Now: time.Now(), Limit: opts.Limit})
+	if err != nil {
+		return 0, err
+	}
+	released := 0
+	for _, h := range holds {
+		err := retryOnConflict(func() error {
+			txn, err := u.beginTxn(ctx)
+			if err != nil {
+				return err
+			}
+			// the account is locked before the hold is read again, the same order capture and void take
+			locked, err := u.lock(ctx, trc, h.AccountId)
+			if err != nil {
+				txn.Rollback(ctx)
+				return err
+			}
+			hold, err := u.HoldRepo.FindByAccountIdAndId(ctx, trc, account.HoldFindByAccountIdAndIdOpts{AccountId: h.AccountId, ID: h.ID})
+			if err != nil {
+				txn.Rollback(ctx)
+				return err
+			}
+			if !hold.IsActive() {
+				// captured or voided since it was listed
+				txn.Rollback(ctx)
+				return nil
+			}
+			hold.Expire()
+			if err := u.release(ctx, trc, locked[h.AccountId], hold); err != nil {
+				txn.Rollback(ctx)
+				return err
+			}
+			if err := txn.Commit(ctx); err != nil {
+				txn.Rollback(ctx)
+				return err
+			}
+			released++
+			return nil
+		})
+		if err != nil {
+			span.RecordError(err)
+		}
+	}
+	return released, nil
+}
+
+// lockHold locks the account of the user and loads one of its holds, which has to be active and unexpired.
+func (u *AccountUseCase) lockHold(ctx context.Context, trc trace.Tracer, userId uuid.UUID, accountId uuid.UUID, holdId uuid.UUID) (*account.Account, *account.Hold, error) {
+	acc, err := u.lockOwned(ctx, trc, userId, accountId)
+	if err != nil {
+		return nil, nil, err
+	}
+	hold, err := u.HoldRepo.FindByAccountIdAndId(ctx, trc, account.HoldFindByAccountIdAndIdOpts{AccountId: acc.ID, ID: holdId})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !hold.IsActive() || hold.IsExpired(time.Now()) {
+		return nil, nil, account.HoldNotActive(errors.New("hold is not active"))
+	}
+	return acc, hold, nil
+}
+
+// release stores a hold that stopped reserving money and gives the amount back to the available balance.
+func (u *AccountUseCase) release(ctx context.Context, trc trace.Tracer, acc *account.Account, hold *account.Hold) error {
+	if err := u.HoldRepo.Save(ctx, trc, account.HoldSaveOpts{Hold: hold}); err != nil {
+		return err
+	}
+	return u.reconcile(ctx, trc, acc)
+}
+
+type AccountLimitsOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+}
+
+// Limits reports the usage of the account and of its user against the limits applying to them,
+// scopes without a limit are left out.
+func (u *AccountUseCase) Limits(ctx context.Context, trc trace.Tracer, opts AccountLimitsOpts) ([]*limit.Status, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Limits")
+	defer span.End()
+	acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
+	if err != nil {
+		return nil, err
+	}
+	if acc.ID == uuid.Nil {
+		return nil, account.NotFound(errors.New("account not found"))
+	}
+	day, month := limit.Window(time.Now())
+	usageOpts := map[limit.Scope]account.TransactionUsageOpts{
+		limit.ScopeAccount: {AccountId: acc.ID, Currency: acc.Currency, Day: day, Month: month},
+		limit.ScopeUser:    {UserId: acc.UserId, Currency: acc.Currency, Day: day, Month: month},
+	}
+	statuses := make([]*limit.Status, 0, 2)
+	for _, scope := range []limit.Scope{limit.ScopeAccount, limit.ScopeUser} {
+		r := u.LimitPolicy.For(scope, acc.Currency)
+		if r == nil {
+			continue
+		}
+		usage, err := u.TransactionRepo.Usage(ctx, trc, usageOpts[scope])
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, limit.NewStatus(r, acc.Currency, *usage))
+	}
+	return statuses, nil
+}
+
+type AccountFeeQuoteOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	Amount    string
+	Kind      account.TransactionKind
+	ToIban    string
+	ToOwner   string
+}
+
+// FeeQuote tells what a transaction would cost without executing it.
+// Transfers are quoted free when the receiver, if given, belongs to the same user.
+func (u *AccountUseCase) FeeQuote(ctx context.Context, trc trace.Tracer, opts AccountFeeQuoteOpts) (*fee.Quote, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.FeeQuote")
+	defer span.End()
+	acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
+	if err != nil {
+		return nil, err
+	}
+	amount, err := parseAmount(opts.Amount, acc.Currency)
+	if err != nil {
+		return nil, err
+	}
+	charged := true
+	if opts.Kind == account.TransactionKindTransfer && opts.ToIban != "" {
+		target, err := u.AccountRepo.FindByIbanAndOwner(ctx, trc, account.FindByIbanAndOwnerOpts{Iban: opts.ToIban, Owner: opts.ToOwner})
+		if err != nil {
+			return nil, account.NotFound(err)
+		}
+		charged = target.UserId != acc.UserId
+	}
+	f := decimal.Zero
+	if charged {
+		f = u.FeePolicy.Compute(amount.Amount, acc.Currency, opts.Kind.String())
+	}
+	return &fee.Quote{
+		Amount:   amount.String(),
+		Fee:      money.Money{Amount: f, Currency: acc.Currency}.String(),
+		Currency: acc.Currency,
+	}, nil
+}
+
+type AccountFreezeOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+}
+
+func (u *AccountUseCase) Freeze(ctx context.Context, trc trace.Tracer, opts AccountFreezeOpts) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Freeze")
+	defer span.End()
+	return retryOnConflict(func() error {
+		acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
+		if err != nil {
+			return err
+		}
+		acc.Freeze()
+		if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+type AccountLockOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+}
+
+func (u *AccountUseCase) Lock(ctx context.Context, trc trace.Tracer, opts AccountLockOpts) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Lock")
+	defer span.End()
+	return retryOnConflict(func() error {
+		acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
+		if err != nil {
+			return err
+		}
+		acc.Lock()
+		if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+type AccountSuspendOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+}
+
+func (u *AccountUseCase) Suspend(ctx context.Context, trc trace.Tracer, opts AccountSuspendOpts) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Suspend")
+	defer span.End()
+	return retryOnConflict(func() error {
+		acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
+		if err != nil {
+			return err
+		}
+		acc.Suspend()
+		if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+type AccountTransferMoneyOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	UserEmail string
+	UserName  string
+	Amount    string
+	ToIban    string
+	ToOwner   string
+	Desc      string
+}
+
+// TransferMoney moves the money right away, or holds a transfer above the step-up threshold back
+// and returns the token ConfirmTransfer takes along with the code mailed to the user.
+func (u *AccountUseCase) TransferMoney(ctx context.Context, trc trace.Tracer, opts AccountTransferMoneyOpts) (*string, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.TransferMoney")
+	defer span.End()
+	acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
+	if err != nil {
+		return nil, err
+	}
+	if acc.ID == uuid.Nil {
+		return nil, account.NotFound(errors.New("sender account not found"))
+	}
+	amount, err := parseAmount(opts.Amount, acc.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if u.requiresStepUp(amount) {
+		return u.holdTransfer(ctx, trc, opts, amount)
+	}
+	return nil, u.executeTransfer(ctx, trc, opts)
+}
+
+// holdTransfer keeps the transfer with a verification code until it is confirmed or the code expires.
+func (u *AccountUseCase) holdTransfer(ctx context.Context, trc trace.Tracer, opts AccountTransferMoneyOpts, amount money.Money) (*string, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.holdTransfer")
+	defer span.End()
+	payload, err := json.Marshal(opts)
+	if err != nil {
+		return nil, rescode.Failed(err)
+	}
+	verifyToken := uuid.New().String()
+	verify := auth.NewVerify(auth.VerifyConfig{
+		UserId:   opts.UserId,
+		DeviceId: state.GetDeviceId(ctx),
+		Locale:   state.GetLocale(ctx),
+		Purpose:  auth.VerifyPurposeTransfer,
+		Payload:  payload,
+	})
+	if err := u.VerifyRepo.Save(ctx, trc, auth.VerifySaveOpts{Token: verifyToken, Verify: verify}); err != nil {
+		return nil, err
+	}
+	err = enqueue(ctx, trc, u.OutboxRepo, account.SubjectTransferPending, &account.EventTransferPending{
+		Email:    opts.UserEmail,
+		Name:     opts.UserName,
+		Code:     verify.Code,
+		Amount:   amount.String(),
+		Currency: amount.Currency,
+		ToIban:   opts.ToIban,
+		ToOwner:  opts.ToOwner,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &verifyToken, nil
+}
+
+type AccountConfirmTransferOpts struct {
+	UserId      uuid.UUID
+	VerifyToken string
+	Code        string
+}
+
+// ConfirmTransfer executes the transfer held back by TransferMoney once the code matches,
+// the transfer is checked again as if it was just requested.
+func (u *AccountUseCase) ConfirmTransfer(ctx context.Context, trc trace.Tracer, opts AccountConfirmTransferOpts) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.ConfirmTransfer")
+	defer span.End()
+	verify, err := checkVerify(ctx, trc, u.VerifyRepo, opts.VerifyToken, auth.VerifyPurposeTransfer, func(v *auth.Verify) (bool, error) {
+		return opts.Code == v.Code, nil
+	})
+	if err != nil {
+		return err
+	}
+	if verify.UserId != opts.UserId {
+		return auth.VerificationInvalid(errors.New("verification of another user"))
+	}
+	var t AccountTransferMoneyOpts
+	if err := json.Unmarshal(verify.Payload, &t); err != nil {
+		return rescode.Failed(err)
+	}
+	return u.executeTransfer(ctx, trc, t)
+}
+
+// requiresStepUp reports whether the amount is above the step-up threshold of its currency.
+func (u *AccountUseCase) requiresStepUp(amount money.Money) bool {
+	threshold, ok := u.StepUpThresholds[amount.Currency]
+	return ok && amount.Amount.GreaterThan(threshold)
+}
+
+// executeTransfer books the transfer in a transaction of its own, without step-up verification.
+func (u *AccountUseCase) executeTransfer(ctx context.Context, trc trace.Tracer, opts AccountTransferMoneyOpts) error {
+	return retryOnConflict(func() error {
+		txn, err := u.beginTxn(ctx)
+		if err != nil {
+			return err
+		}
+		if err := u.transfer(ctx, trc, opts); err != nil {
+			txn.Rollback(ctx)
+			return err
+		}
+		if err := txn.Commit(ctx); err != nil {
+			txn.Rollback(ctx)
+			return err
+		}
+		return nil
+	})
+}
+
+// transfer moves money within the transaction begun by the caller, it neither commits nor rolls back.
+func (u *AccountUseCase) transfer(ctx context.Context, trc trace.Tracer, opts AccountTransferMoneyOpts) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.transfer")
+	defer span.End()
+	target, err := u.AccountRepo.FindByIbanAndOwner(ctx, trc, account.FindByIbanAndOwnerOpts{Iban: opts.ToIban, Owner: opts.ToOwner})
+	if err != nil {
+		return account.NotFound(err)
+	}
+	if target.ID == opts.AccountId {
+		return account.TransferToSameAccount(errors.New("transfer to same account"))
+	}
+	locked, err := u.lock(ctx, trc, opts.AccountId, target.ID)
+	if err != nil {
+		return err
+	}
+	fromAccount, toAccount := locked[opts.AccountId], locked[target.ID]
+	if fromAccount.UserId != opts.UserId {
+		return account.NotFound(errors.New("sender account not found"))
+	}
+	if !fromAccount.IsAvailable() {
+		return account.NotAvailable(errors.New("sender account not available"))
+	}
+	if !toAccount.IsAvailable() {
+		return account.ToAccNotAvailable(errors.New("to account not available"))
+	}
+	amount, err := parseAmount(opts.Amount, fromAccount.Currency)
+	if err != nil {
+		return err
+	}
+	amountToTransfer := amount.Amount
+	amountToReceive := amountToTransfer
+	var rate *fx.Rate
+	if fromAccount.Currency != toAccount.Currency {
+		rate, err = u.quote(ctx, trc, fromAccount.Currency, toAccount.Currency)
+		if err != nil {
+			return err
+		}
+		amountToReceive = rate.Convert(amountToTransfer)
+		if !amountToReceive.IsPositive() {
+			return fx.AmountTooSmall(errors.New("converted amount rounds to zero"))
+		}
+	}
+	fee := decimal.Zero
+	if fromAccount.UserId != toAccount.UserId {
+		// transfers between the accounts of the same user are free
+		fee = u.FeePolicy.Compute(amountToTransfer, fromAccount.Currency, account.TransactionKindTransfer.String())
+	}
+	amountToPay := amountToTransfer.Add(fee)
+
+	if !fromAccount.CanCredit(amountToPay) {
+		return account.BalanceInsufficient(errors.New("sender account balance insufficient"))
+	}
+	// money moved between the accounts of a user does not leave the user
+	if err := u.checkLimits(ctx, trc, fromAccount, amountToTransfer, fromAccount.UserId != toAccount.UserId); err != nil {
+		return err
+	}
+
+	tx := account.NewTransaction(account.TransactionConfig{
+		SenderId:    fromAccount.ID,
+		ReceiverId:  toAccount.ID,
+		Amount:      amountToTransfer,
+		Currency:    fromAccount.Currency,
+		Description: opts.Desc,
+		Kind:        account.TransactionKindTransfer,
+	})
+	if rate != nil {
+		tx.Exchange(rate.ID, rate.Rate, amountToReceive, toAccount.Currency)
+	}
+	if err := u.TransactionRepo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx}); err != nil {
+		return err
+	}
+	entry := account.NewJournalEntry(account.JournalEntryConfig{TransactionId: tx.ID, Description: opts.Desc})
+	if rate == nil {
+		entry.Debit(fromAccount.ID, amountToTransfer, fromAccount.Currency)
+		entry.Credit(toAccount.ID, amountToTransfer, toAccount.Currency)
+	} else {
+		// the fx account buys the sent currency and sells the received one, keeping each currency balanced
+		entry.Debit(fromAccount.ID, amountToTransfer, fromAccount.Currency)
+		entry.Credit(account.LedgerFxAccountId, amountToTransfer, fromAccount.Currency)
+		entry.Debit(account.LedgerFxAccountId, amountToReceive, toAccount.Currency)
+		entry.Credit(toAccount.ID, amountToReceive, toAccount.Currency)
+	}
+	if err := u.post(ctx, trc, entry); err != nil {
+		return err
+	}
+	if err := u.chargeFee(ctx, trc, fromAccount, tx, fee); err != nil {
+		return err
+	}
+	if err := u.reconcile(ctx, trc, fromAccount, toAccount); err != nil {
+		return err
+	}
+	if toAccount.UserId != fromAccount.UserId {
+		toUser, err := u.UserRepo.FindById(ctx, trc, user.FindByIdOpts{ID: toAccount.UserId})
+		if err != nil {
+			return err
+		}
+		err = enqueue(ctx, trc, u.OutboxRepo, account.SubjectTransferIncoming, &account.EventTranfserIncoming{
+			Email:       toUser.Email,
+			Name:        toUser.Name,
+			Amount:      money.Money{Amount: amountToReceive, Currency: toAccount.Currency}.String(),
+			Currency:    toAccount.Currency,
+			Account:     toAccount.Name,
+			Description: opts.Desc,
+		})
+		if err != nil {
+			return err
+		}
+		err = enqueue(ctx, trc, u.OutboxRepo, account.SubjectTransferOutgoing, &account.EventTranfserOutgoing{
+			Amount:      money.Money{Amount: amountToPay, Currency: fromAccount.Currency}.String(),
+			Email:       opts.UserEmail,
+			Name:        opts.UserName,
+			Currency:    fromAccount.Currency,
+			Account:     fromAccount.Name,
+			Description: opts.Desc,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BulkTransferMaxLines caps the number of payments a single bulk transfer may carry.
+const BulkTransferMaxLines = 1000
+
+type AccountBulkTransferOpts struct {
+	UserId    uuid.UUID
+	UserEmail string
+	UserName  string
+	Batch     *pain.Batch
+
+	// Atomic books every payment in one transaction, a single failure rejects the whole batch.
+	Atomic bool
+}
+
+// BulkTransfer executes the payments of a batch and reports a status for every one of them, in order.
+// Payments are validated upfront, in atomic mode an invalid payment rejects the batch before any money moves.
+func (u *AccountUseCase) BulkTransfer(ctx context.Context, trc trace.Tracer, opts AccountBulkTransferOpts) ([]pain.Status, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.BulkTransfer")
+	defer span.End()
+	if len(opts.Batch.Payments) == 0 || len(opts.Batch.Payments) > BulkTransferMaxLines {
+		return nil, account.BulkInvalid(errors.New("batch must carry between 1 and 1000 payments"))
+	}
+	statuses := make([]pain.Status, len(opts.Batch.Payments))
+	transfers := make([]AccountTransferMoneyOpts, len(opts.Batch.Payments))
+	valid := true
+	for i, p := range opts.Batch.Payments {
+		statuses[i] = pain.Status{EndToEndId: p.EndToEndId}
+		t, err := u.bulkLine(ctx, trc, opts, p)
+		if err != nil {
+			statuses[i].Status, statuses[i].Reason = pain.StatusRejected, rejectReason(err)
+			valid = false
+			continue
+		}
+		transfers[i] = *t
+	}
+	if opts.Atomic {
+		if !valid {
+			return rejectRest(statuses), nil
+		}
+		return u.bulkAtomic(ctx, trc, statuses, transfers)
+	}
+	for i, t := range transfers {
+		if statuses[i].Status != "" {
+			continue
+		}
+		if err := u.executeTransfer(ctx, trc, t); err != nil {
+			statuses[i].Status, statuses[i].Reason = pain.StatusRejected, rejectReason(err)
+			continue
+		}
+		statuses[i].Status = pain.StatusAccepted
+	}
+	return statuses, nil
+}
+
+// bulkLine checks a payment of a batch without touching any balance and turns it into a transfer.
+func (u *AccountUseCase) bulkLine(ctx context.Context, trc trace.Tracer, opts AccountBulkTransferOpts, p pain.Payment) (*AccountTransferMoneyOpts, error) {
+	if !iban.Validate(p.DebtorIban) || !iban.Validate(p.CreditorIban) {
+		return nil, account.IbanInvalid(errors.New("iban invalid"))
+	}
+	acc, err := u.AccountRepo.FindByUserIdAndIban(ctx, trc, account.FindByUserIdAndIbanOpts{UserId: opts.UserId, Iban: p.DebtorIban})
+	if err != nil {
+		return nil, err
+	}
+	if acc.ID == uuid.Nil {
+		return nil, account.NotFound(errors.New("debtor account not found"))
+	}
+	if p.Currency != "" && p.Currency != acc.Currency {
+		return nil, account.CurrencyMismatch(errors.New("payment currency differs from the debtor account"))
+	}
+	amount, err := parseAmount(p.Amount, acc.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if u.requiresStepUp(amount) {
+		// nobody is there to confirm a line of a batch
+		return nil, account.StepUpRequired(errors.New("payment above the step-up threshold"))
+	}
+	return &AccountTransferMoneyOpts{
+		UserId:    opts.UserId,
+		AccountId: acc.ID,
+		UserEmail: opts.UserEmail,
+		UserName:  opts.UserName,
+		Amount:    p.Amount,
+		ToIban:    p.CreditorIban,
+		ToOwner:   p.CreditorName,
+		Desc:      p.Remittance,
+	}, nil
+}
+
+// bulkAtomic books every transfer in one transaction, the first failing transfer rolls all of them back.
+func (u *AccountUseCase) bulkAtomic(ctx context.Context, trc trace.Tracer, statuses []pain.Status, transfers []AccountTransferMoneyOpts) ([]pain.Status, error) {
+	err := retryOnConflict(func() error {
+		txn, err := u.beginTxn(ctx)
+		if err != nil {
+			return err
+		}
+		if err := u.lockBatch(ctx, trc, transfers); err != nil {
+			txn.Rollback(ctx)
+			return err
+		}
+		for i, t := range transfers {
+			if err := u.transfer(ctx, trc, t); err != nil {
+				txn.Rollback(ctx)
+				if errors.Is(err, account.ErrVersionConflict) {
+					return err
+				}
+				statuses[i].Status, statuses[i].Reason = pain.StatusRejected, rejectReason(err)
+				return nil
+			}
+		}
+		if err := txn.Commit(ctx); err != nil {
+			txn.Rollback(ctx)
+			return err
+		}
+		for i := range statuses {
+			statuses[i].Status = pain.StatusAccepted
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rejectRest(statuses), nil
+}
+
+// lockBatch locks every account the transfers of a batch touch in one go, in the id order of FindByIdsForUpdate.
+// Locking them line by line would have two batches crossing the same accounts wait on each other.
+// An account that can not be found is not locked, its line fails on its own when it runs.
+func (u *AccountUseCase) lockBatch(ctx context.Context, trc trace.Tracer, transfers []AccountTransferMoneyOpts) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.lockBatch")
+	defer span.End()
+	ids := make([]uuid.UUID, 0, len(transfers)*2)
+	seen := make(map[uuid.UUID]bool, len(transfers)*2)
+	add := func(id uuid.UUID) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, t := range transfers {
+		add(t.AccountId)
+		target, err := u.AccountRepo.FindByIbanAndOwner(ctx, trc, account.FindByIbanAndOwnerOpts{Iban: t.ToIban, Owner: t.ToOwner})
+		if err != nil || target.ID == uuid.Nil {
+			continue
+		}
+		add(target.ID)
+	}
+	_, err := u.AccountRepo.FindByIdsForUpdate(ctx, trc, account.FindByIdsForUpdateOpts{IDs: ids})
+	return err
+}
+
+// rejectReason is the status reason reported for a failed payment, internal errors are not exposed.
+func rejectReason(err error) string {
+	var rc *rescode.RC
+	if errors.As(err, &rc) {
+		return rc.Message
+	}
+	return "internal_error"
+}
+
+// rejectRest marks every payment without a status as rejected because of the batch.
+func rejectRest(statuses []pain.Status) []pain.Status {
+	for i := range statuses {
+		if statuses[i].Status == "" {
+			statuses[i].Status, statuses[i].Reason = pain.StatusRejected, "batch_rejected"
+		}
 	}
-	return &acc.ID, nil
+	return statuses
 }
 
-type AccountCreditOpts struct {
-	UserId    uuid.UUID
-	AccountId uuid.UUID
-	UserEmail string
-	UserName  string
-	Amount    string
+type AccountReverseOpts struct {
+	UserId        uuid.UUID
+	AccountId     uuid.UUID
+	TransactionId uuid.UUID
+	Desc          string
 }
 
-func (u *AccountUseCase) Credit(ctx context.Context, trc trace.Tracer, opts AccountCreditOpts) error {
-	ctx, span := trc.Start(ctx, "AccountUseCase.Credit")
+// Reverse refunds a transfer received by the account of the user, the money goes back to the sender
+// along with the fees the sender paid for it. A transaction is reversed at most once.
+func (u *AccountUseCase) Reverse(ctx context.Context, trc trace.Tracer, opts AccountReverseOpts) (*uuid.UUID, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Reverse")
 	defer span.End()
-	acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{
-		UserId: opts.UserId,
-		ID:     opts.AccountId,
+	var reversal *account.Transaction
+	err := retryOnConflict(func() error {
+		txn, err := u.beginTxn(ctx)
+		if err != nil {
+			return err
+		}
+		reversal, err = u.reverse(ctx, trc, opts)
+		if err != nil {
+			txn.Rollback(ctx)
+			return err
+		}
+		if err := txn.Commit(ctx); err != nil {
+			txn.Rollback(ctx)
+			return err
+		}
+		return nil
 	})
 	if err != nil {
-		return err
-	}
-	if !acc.IsAvailable() {
-		return account.NotAvailable(errors.New("sender account not available"))
+		return nil, err
 	}
-	amountDec, err := decimal.NewFromString(opts.Amount)
+	return &reversal.ID, nil
+}
+
+func (u *AccountUseCase) reverse(ctx context.Context, trc trace.Tracer, opts AccountReverseOpts) (*account.Transaction, error) {
+	original, err := u.TransactionRepo.FindById(ctx, trc, account.TransactionFindByIdOpts{ID: opts.TransactionId})
 	if err != nil {
-		return rescode.Failed(err)
+		return nil, err
 	}
-	acc.Credit(amountDec)
-	if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
-		return err
+	if original.ID == uuid.Nil || original.ReceiverId != opts.AccountId {
+		return nil, account.TransactionNotFound(errors.New("transaction not found"))
 	}
-	tx := account.NewTransaction(account.TransactionConfig{
-		SenderId:    acc.ID,
-		ReceiverId:  acc.ID, // receiver id is the same as sender id because it is a deposit
-		Amount:      amountDec,
-		Description: "Load balance",
-		Kind:        account.TransactionKindDeposit,
-	})
-	if err := u.TransactionRepo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx}); err != nil {
-		return err
+	if !original.IsReversible() {
+		return nil, account.TransactionNotReversible(errors.New("only transfers can be reversed"))
 	}
-	err = u.EventSrv.Publish(ctx, account.SubjectTransferIncoming, &account.EventTranfserIncoming{
-		Name:        opts.UserName,
-		Amount:      amountDec.String(),
-		Currency:    acc.Currency,
-		Email:       opts.UserEmail,
-		Account:     acc.Name,
-		Description: "Load balance",
-	})
+	locked, err := u.lock(ctx, trc, original.SenderId, original.ReceiverId)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
-}
-
-type AccountDebitOpts struct {
-	UserId    uuid.UUID
-	AccountId uuid.UUID
-	UserEmail string
-	UserName  string
-	Amount    string
-}
-
-func (u *AccountUseCase) Debit(ctx context.Context, trc trace.Tracer, opts AccountDebitOpts) error {
-	ctx, span := trc.Start(ctx, "AccountUseCase.Debit")
-	defer span.End()
-	acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
+	payer, payee := locked[original.ReceiverId], locked[original.SenderId]
+	if payer.UserId != opts.UserId {
+		return nil, account.TransactionNotFound(errors.New("transaction not found"))
+	}
+	// checked under the account locks, so concurrent reversals of the same transaction queue up here
+	existing, err := u.TransactionRepo.FindByReferenceId(ctx, trc, account.TransactionFindByReferenceIdOpts{ReferenceId: original.ID})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if !acc.IsAvailable() {
-		return account.NotAvailable(errors.New("sender account not available"))
+	if existing.ID != uuid.Nil {
+		return nil, account.TransactionAlreadyReversed(errors.New("transaction already reversed"))
 	}
-	amountDec, err := decimal.NewFromString(opts.Amount)
-	if err != nil {
-		return rescode.Failed(err)
+	if !payer.IsAvailable() {
+		return nil, account.NotAvailable(errors.New("receiver account not available"))
 	}
-	if !acc.CanCredit(amountDec) {
-		return account.BalanceInsufficient(errors.New("sender account balance insufficient"))
+	if !payee.IsAvailable() {
+		return nil, account.ToAccNotAvailable(errors.New("sender account not available"))
 	}
-	acc.Debit(amountDec)
-	if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
-		return err
+	if !payer.CanCredit(original.ReceiverAmount) {
+		return nil, account.BalanceInsufficient(errors.New("receiver account balance insufficient"))
 	}
-	tx := account.NewTransaction(account.TransactionConfig{
-		SenderId:    acc.ID,
-		ReceiverId:  acc.ID, // receiver id is the same as sender id because it is a withdrawal
-		Amount:      amountDec,
-		Description: "Withdraw balance",
-		Kind:        account.TransactionKindWithdrawal,
-	})
-	if err := u.TransactionRepo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx}); err != nil {
-		return err
+	desc := opts.Desc
+	if desc == "" {
+		desc = "Reversal of " + original.Description
 	}
-	err = u.EventSrv.Publish(ctx, account.SubjectTransferOutgoing, &account.EventTranfserOutgoing{
-		Name:        opts.UserName,
-		Amount:      amountDec.String(),
-		Email:       opts.UserEmail,
-		Currency:    acc.Currency,
-		Account:     acc.Name,
-		Description: "Withdraw balance",
-	})
-	if err != nil {
-		return err
+	reversal := original.Reverse(desc)
+	if err := u.saveReversal(ctx, trc, reversal); err != nil {
+		return nil, err
 	}
-	return nil
-}
-
-type AccountFreezeOpts struct {
-	UserId    uuid.UUID
-	AccountId uuid.UUID
-}
-
-func (u *AccountUseCase) Freeze(ctx context.Context, trc trace.Tracer, opts AccountFreezeOpts) error {
-	ctx, span := trc.Start(ctx, "AccountUseCase.Freeze")
-	defer span.End()
-	acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
+	entry := account.NewJournalEntry(account.JournalEntryConfig{TransactionId: reversal.ID, Description: desc})
+	if reversal.Currency == reversal.ReceiverCurrency {
+		entry.Debit(payer.ID, reversal.Amount, reversal.Currency)
+		entry.Credit(payee.ID, reversal.ReceiverAmount, reversal.ReceiverCurrency)
+	} else {
+		// the fx account takes both currencies back at the original rate
+		entry.Debit(payer.ID, reversal.Amount, reversal.Currency)
+		entry.Credit(account.LedgerFxAccountId, reversal.Amount, reversal.Currency)
+		entry.Debit(account.LedgerFxAccountId, reversal.ReceiverAmount, reversal.ReceiverCurrency)
+		entry.Credit(payee.ID, reversal.ReceiverAmount, reversal.ReceiverCurrency)
+	}
+	if err := u.post(ctx, trc, entry); err != nil {
+		return nil, err
+	}
+	fees, err := u.TransactionRepo.ListByParentId(ctx, trc, account.TransactionListByParentIdOpts{ParentId: original.ID})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	acc.Freeze()
-	if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
-		return err
+	for _, f := range fees {
+		if f.Kind != account.TransactionKindFee {
+			continue
+		}
+		refund := f.Reverse("Process Fee Refund")
+		refund.ParentId = &reversal.ID
+		if err := u.saveReversal(ctx, trc, refund); err != nil {
+			return nil, err
+		}
+		entry := account.NewJournalEntry(account.JournalEntryConfig{TransactionId: refund.ID, Description: refund.Description})
+		entry.Debit(account.LedgerFeeAccountId, refund.Amount, refund.Currency)
+		entry.Credit(f.SenderId, refund.Amount, refund.Currency)
+		if err := u.post(ctx, trc, entry); err != nil {
+			return nil, err
+		}
 	}
-	return nil
+	if err := u.reconcile(ctx, trc, payer, payee); err != nil {
+		return nil, err
+	}
+	return reversal, nil
 }
 
-type AccountLockOpts struct {
-	UserId    uuid.UUID
-	AccountId uuid.UUID
+// saveReversal saves a reversal, turning the unique reference violation of a concurrent reversal into its rescode.
+func (u *AccountUseCase) saveReversal(ctx context.Context, trc trace.Tracer, reversal *account.Transaction) error {
+	err := u.TransactionRepo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: reversal})
+	if errors.Is(err, account.ErrAlreadyReversed) {
+		return account.TransactionAlreadyReversed(err)
+	}
+	return err
 }
 
-func (u *AccountUseCase) Lock(ctx context.Context, trc trace.Tracer, opts AccountLockOpts) error {
-	ctx, span := trc.Start(ctx, "AccountUseCase.Lock")
-	defer span.End()
-	acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
+// parseAmount reads a positive amount of the given currency, refusing more decimals than its minor unit.
+func parseAmount(amount string, currency string) (money.Money, error) {
+	m, err := money.Parse(amount, currency)
 	if err != nil {
-		return err
+		return m, account.AmountInvalid(err)
 	}
-	acc.Lock()
-	if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
-		return err
+	if !m.IsPositive() {
+		return m, account.AmountInvalid(errors.New("amount must be positive"))
 	}
-	return nil
+	return m, nil
 }
 
-type AccountSuspendOpts struct {
-	UserId    uuid.UUID
-	AccountId uuid.UUID
+// retryOnConflict reruns fn while it fails because an account row changed underneath it.
+func retryOnConflict(fn retry.RetryFunc) error {
+	return retry.Run(fn, retry.Config{
+		MaxRetries: 3,
+		WaitTime:   10 * time.Millisecond,
+		RetryIf: func(err error) bool {
+			return errors.Is(err, account.ErrVersionConflict)
+		},
+	})
 }
 
-func (u *AccountUseCase) Suspend(ctx context.Context, trc trace.Tracer, opts AccountSuspendOpts) error {
-	ctx, span := trc.Start(ctx, "AccountUseCase.Suspend")
-	defer span.End()
-	acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
-	if err != nil {
-		return err
-	}
-	acc.Suspend()
-	if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
-		return err
+// beginTxn starts a transaction over every repo that takes part in moving money.
+// The repos commit in the order they are registered, the account repo goes last so the
+// account rows stay locked until the postings, holds and messages of the move are committed,
+// a move waiting on the lock reads the ledger, holds and usage with them in.
+func (u *AccountUseCase) beginTxn(ctx context.Context) (txn.Tx, error) {
+	tx := txn.New()
+	tx.Register(u.TransactionRepo.GetTxnAdapter())
+	tx.Register(u.LedgerRepo.GetTxnAdapter())
+	tx.Register(u.HoldRepo.GetTxnAdapter())
+	tx.Register(u.OutboxRepo.GetTxnAdapter())
+	tx.Register(u.FxRepo.GetTxnAdapter())
+	tx.Register(u.AccountRepo.GetTxnAdapter())
+	if err := tx.Begin(ctx); err != nil {
+		return nil, err
 	}
-	return nil
-}
-
-type AccountTransferMoneyOpts struct {
-	UserId    uuid.UUID
-	AccountId uuid.UUID
-	UserEmail string
-	UserName  string
-	Amount    string
-	ToIban    string
-	ToOwner   string
-	Desc      string
+	return tx, nil
 }
 
-func (u *AccountUseCase) TransferMoney(ctx context.Context, trc trace.Tracer, opts AccountTransferMoneyOpts) error {
-	ctx, span := trc.Start(ctx, "AccountUseCase.TransferMoney")
+// lock loads the given accounts with their rows locked until the transaction ends.
+// Every id has to exist, otherwise the whole lookup fails with not found.
+func (u *AccountUseCase) lock(ctx context.Context, trc trace.Tracer, ids ...uuid.UUID) (map[uuid.UUID]*account.Account, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.lock")
 	defer span.End()
-
-	txn := txn.New()
-	txn.Register(u.AccountRepo.GetTxnAdapter())
-	txn.Register(u.TransactionRepo.GetTxnAdapter())
-	if err := txn.Begin(ctx); err != nil {
-		return err
-	}
-	onError := func(ctx context.Context, err error) error {
-		txn.Rollback(ctx)
-		return err
-	}
-	toAccount, err := u.AccountRepo.FindByIbanAndOwner(ctx, trc, account.FindByIbanAndOwnerOpts{Iban: opts.ToIban, Owner: opts.ToOwner})
+	accounts, err := u.AccountRepo.FindByIdsForUpdate(ctx, trc, account.FindByIdsForUpdateOpts{IDs: ids})
 	if err != nil {
-		return onError(ctx, account.NotFound(err))
+		return nil, err
 	}
-	fromAccount, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
-	if err != nil {
-		return onError(ctx, err)
+	locked := make(map[uuid.UUID]*account.Account, len(accounts))
+	for _, acc := range accounts {
+		locked[acc.ID] = acc
 	}
-	if !fromAccount.IsAvailable() {
-		return onError(ctx, account.NotAvailable(errors.New("sender account not available")))
+	for _, id := range ids {
+		if _, ok := locked[id]; !ok {
+			return nil, account.NotFound(errors.New("account not found"))
+		}
 	}
-	if !toAccount.IsAvailable() {
-		return onError(ctx, account.ToAccNotAvailable(errors.New("to account not available")))
+	return locked, nil
+}
+
+// lockOwned locks a single account, making sure it belongs to the given user.
+func (u *AccountUseCase) lockOwned(ctx context.Context, trc trace.Tracer, userId uuid.UUID, accountId uuid.UUID) (*account.Account, error) {
+	locked, err := u.lock(ctx, trc, accountId)
+	if err != nil {
+		return nil, err
 	}
-	if fromAccount.ID == toAccount.ID {
-		return onError(ctx, account.TransferToSameAccount(errors.New("transfer to same account")))
+	acc := locked[accountId]
+	if acc.UserId != userId {
+		return nil, account.NotFound(errors.New("account not found"))
 	}
-	if fromAccount.Currency != toAccount.Currency {
-		return onError(ctx, account.CurrencyMismatch(errors.New("currency mismatch")))
+	return acc, nil
+}
+
+// quote takes the current rate between two currencies and keeps a snapshot of it.
+func (u *AccountUseCase) quote(ctx context.Context, trc trace.Tracer, base string, quote string) (*fx.Rate, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.quote")
+	defer span.End()
+	if u.FxProvider == nil {
+		return nil, account.CurrencyMismatch(errors.New("currency mismatch"))
 	}
-	amountToTransfer, err := decimal.NewFromString(opts.Amount)
+	rate, err := u.FxProvider.Rate(ctx, base, quote)
 	if err != nil {
-		return onError(ctx, err)
-	}
-	amountToPay := amountToTransfer
-	if fromAccount.UserId != toAccount.UserId {
-		// process fee
-		amountToPay = amountToTransfer.Add(decimal.NewFromInt(int64(1)))
+		return nil, err
 	}
-
-	if !fromAccount.CanCredit(amountToPay) {
-		return onError(ctx, account.BalanceInsufficient(errors.New("sender account balance insufficient")))
+	if err := u.FxRepo.Save(ctx, trc, fx.SaveOpts{Rate: rate}); err != nil {
+		return nil, err
 	}
+	return rate, nil
+}
 
-	tx := account.NewTransaction(account.TransactionConfig{
-		SenderId:    fromAccount.ID,
-		ReceiverId:  toAccount.ID,
-		Amount:      amountToTransfer,
-		Description: opts.Desc,
-		Kind:        account.TransactionKindTransfer,
-	})
-	if err := u.TransactionRepo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx}); err != nil {
-		return onError(ctx, err)
-	}
-	if !amountToPay.Equal(amountToTransfer) {
-		feeTx := account.NewTransaction(account.TransactionConfig{
-			SenderId:    fromAccount.ID,
-			ReceiverId:  fromAccount.ID, // receiver id is the same as sender id because it is a fee
-			Amount:      decimal.NewFromInt(int64(1)),
-			Description: "Process Fee",
-			Kind:        account.TransactionKindFee,
-		})
-		if err := u.TransactionRepo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: feeTx}); err != nil {
-			return onError(ctx, err)
+// post writes the given journal entries, refusing any entry that is not balanced.
+func (u *AccountUseCase) post(ctx context.Context, trc trace.Tracer, entries ...*account.JournalEntry) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.post")
+	defer span.End()
+	for _, e := range entries {
+		if !e.IsBalanced() {
+			return account.LedgerUnbalanced(errors.New("journal entry is not balanced"))
+		}
+		if err := u.LedgerRepo.Save(ctx, trc, account.LedgerSaveOpts{Entry: e}); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	fromAccount.Debit(amountToPay)
-	if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: fromAccount}); err != nil {
-		return onError(ctx, err)
+// chargeFee books the fee of a transaction as a fee transaction linked to it, zero fees are not booked.
+func (u *AccountUseCase) chargeFee(ctx context.Context, trc trace.Tracer, acc *account.Account, parent *account.Transaction, fee decimal.Decimal) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.chargeFee")
+	defer span.End()
+	if !fee.IsPositive() {
+		return nil
 	}
-	toAccount.Credit(amountToTransfer)
-	if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: toAccount}); err != nil {
-		return onError(ctx, err)
+	feeTx := account.NewTransaction(account.TransactionConfig{
+		SenderId:    acc.ID,
+		ReceiverId:  acc.ID, // receiver id is the same as sender id because it is a fee
+		Amount:      fee,
+		Currency:    acc.Currency,
+		Description: "Process Fee",
+		Kind:        account.TransactionKindFee,
+		ParentId:    &parent.ID,
+	})
+	if err := u.TransactionRepo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: feeTx}); err != nil {
+		return err
 	}
+	entry := account.NewJournalEntry(account.JournalEntryConfig{TransactionId: feeTx.ID, Description: feeTx.Description})
+	entry.Debit(acc.ID, fee, acc.Currency)
+	entry.Credit(account.LedgerFeeAccountId, fee, acc.Currency)
+	return u.post(ctx, trc, entry)
+}
 
-	if err := txn.Commit(ctx); err != nil {
-		txn.Rollback(ctx)
-		return onError(ctx, err)
+// checkLimits refuses an outgoing amount breaking the account limit or, when leavesUser is set, the user limit.
+// It runs under the account lock, so the account usage can not change before the money moves.
+func (u *AccountUseCase) checkLimits(ctx context.Context, trc trace.Tracer, acc *account.Account, amount decimal.Decimal, leavesUser bool) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.checkLimits")
+	defer span.End()
+	day, month := limit.Window(time.Now())
+	if r := u.LimitPolicy.For(limit.ScopeAccount, acc.Currency); r != nil {
+		usage, err := u.TransactionRepo.Usage(ctx, trc, account.TransactionUsageOpts{AccountId: acc.ID, Currency: acc.Currency, Day: day, Month: month})
+		if err != nil {
+			return err
+		}
+		if err := r.Check(amount, *usage); err != nil {
+			return account.LimitExceeded(err)
+		}
 	}
-
-	if toAccount.UserId != fromAccount.UserId {
-		toUser, err := u.UserRepo.FindById(ctx, trc, user.FindByIdOpts{ID: toAccount.UserId})
+	if r := u.LimitPolicy.For(limit.ScopeUser, acc.Currency); r != nil && leavesUser {
+		usage, err := u.TransactionRepo.Usage(ctx, trc, account.TransactionUsageOpts{UserId: acc.UserId, Currency: acc.Currency, Day: day, Month: month})
 		if err != nil {
 			return err
 		}
-		err = u.EventSrv.Publish(ctx, account.SubjectTransferIncoming, &account.EventTranfserIncoming{
-			Email:       toUser.Email,
-			Name:        toUser.Name,
-			Amount:      amountToTransfer.String(),
-			Currency:    toAccount.Currency,
-			Account:     toAccount.Name,
-			Description: opts.Desc,
-		})
+		if err := r.Check(amount, *usage); err != nil {
+			return account.LimitExceeded(err)
+		}
+	}
+	return nil
+}
+
+// reconcile derives the balance of the given accounts from their postings and the available
+// balance from their active holds, then stores both.
+func (u *AccountUseCase) reconcile(ctx context.Context, trc trace.Tracer, accounts ...*account.Account) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.reconcile")
+	defer span.End()
+	for _, acc := range accounts {
+		balance, err := u.LedgerRepo.Balance(ctx, trc, account.LedgerBalanceOpts{AccountId: acc.ID})
 		if err != nil {
 			return err
 		}
-		err = u.EventSrv.Publish(ctx, account.SubjectTransferOutgoing, &account.EventTranfserOutgoing{
-			Amount:      amountToPay.String(),
-			Email:       opts.UserEmail,
-			Name:        opts.UserName,
-			Currency:    fromAccount.Currency,
-			Account:     fromAccount.Name,
-			Description: opts.Desc,
-		})
+		reserved, err := u.HoldRepo.Reserved(ctx, trc, account.HoldReservedOpts{AccountId: acc.ID})
 		if err != nil {
 			return err
 		}
+		acc.Reconcile(balance, reserved)
+		if err := u.AccountRepo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -379,6 +1412,8 @@ func (u *AccountUseCase) List(ctx context.Context, trc trace.Tracer, opts Accoun
 			Currency: a.Currency,
 			Balance:  a.Balance.String(),
 			Status:   a.Status.String(),
+
+			AvailableBalance: a.AvailableBalance.String(),
 		})
 	}
 	return &list.PagiResponse[*account.AccountListItem]{
@@ -418,6 +1453,7 @@ func (u *AccountUseCase) ListTransactions(ctx context.Context, trc trace.Tracer,
 		d := &account.TransactionListItem{
 			ID:          e.ID,
 			Amount:      e.Amount.String(),
+			Currency:    e.Currency,
 			Description: e.Description,
 			Kind:        e.Kind.String(),
 			CreatedAt:   e.CreatedAt.Format(time.RFC3339),
@@ -430,6 +1466,8 @@ func (u *AccountUseCase) ListTransactions(ctx context.Context, trc trace.Tracer,
 		} else {
 			d.Direction = "incoming"
 			d.AccountId = &e.SenderId
+			d.Amount = e.ReceiverAmount.String()
+			d.Currency = e.ReceiverCurrency
 		}
 		if d.AccountId != nil {
 			a, err := u.AccountRepo.FindById(ctx, trc, account.FindByIdOpts{ID: *d.AccountId})
@@ -449,3 +1487,107 @@ func (u *AccountUseCase) ListTransactions(ctx context.Context, trc trace.Tracer,
 		Page:          txs.Page,
 	}, nil
 }
+
+type AccountStatementOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	From      time.Time
+	To        time.Time
+}
+
+// Statement prepares the statement of a range with its opening and closing balances,
+// WriteStatement then streams its transactions.
+func (u *AccountUseCase) Statement(ctx context.Context, trc trace.Tracer, opts AccountStatementOpts) (*account.Statement, error) {
+	ctx, span := trc.Start(ctx, "AccountUseCase.Statement")
+	defer span.End()
+	if !opts.From.Before(opts.To) {
+		return nil, account.StatementRangeInvalid(errors.New("statement range must end after it starts"))
+	}
+	acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: opts.UserId, ID: opts.AccountId})
+	if err != nil {
+		return nil, err
+	}
+	if acc.ID == uuid.Nil {
+		return nil, account.NotFound(errors.New("account not found"))
+	}
+	opening, err := u.LedgerRepo.Balance(ctx, trc, account.LedgerBalanceOpts{AccountId: acc.ID, Before: opts.From})
+	if err != nil {
+		return nil, err
+	}
+	closing, err := u.LedgerRepo.Balance(ctx, trc, account.LedgerBalanceOpts{AccountId: acc.ID, Before: opts.To})
+	if err != nil {
+		return nil, err
+	}
+	return &account.Statement{
+		ID:        uuid.New(),
+		Account:   acc,
+		From:      opts.From,
+		To:        opts.To,
+		Opening:   opening,
+		Closing:   closing,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+type AccountWriteStatementOpts struct {
+	Statement *account.Statement
+	Format    statement.Format
+	Writer    io.Writer
+}
+
+// WriteStatement streams the transactions of a prepared statement in the given format.
+func (u *AccountUseCase) WriteStatement(ctx context.Context, trc trace.Tracer, opts AccountWriteStatementOpts) error {
+	ctx, span := trc.Start(ctx, "AccountUseCase.WriteStatement")
+	defer span.End()
+	w, err := statement.New(opts.Format, opts.Writer)
+	if err != nil {
+		return err
+	}
+	st, acc := opts.Statement, opts.Statement.Account
+	err = w.Header(statement.Header{
+		ID:        st.ID.String(),
+		Iban:      acc.Iban,
+		Owner:     acc.Owner,
+		Currency:  acc.Currency,
+		From:      st.From,
+		To:        st.To,
+		Opening:   st.Opening,
+		Closing:   st.Closing,
+		CreatedAt: st.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	counterparties := make(map[uuid.UUID]string)
+	err = u.TransactionRepo.Stream(ctx, trc, account.TransactionStreamOpts{AccountId: acc.ID, From: st.From, To: st.To}, func(t *account.Transaction) error {
+		line := statement.Line{
+			ID:          t.ID.String(),
+			BookedAt:    t.CreatedAt,
+			Amount:      t.SignedFor(acc.ID),
+			Currency:    acc.Currency,
+			Kind:        t.Kind.String(),
+			Description: t.Description,
+		}
+		if !t.IsItself() {
+			other := t.SenderId
+			if t.IsUserSender(acc.ID) {
+				other = t.ReceiverId
+			}
+			owner, ok := counterparties[other]
+			if !ok {
+				a, err := u.AccountRepo.FindById(ctx, trc, account.FindByIdOpts{ID: other})
+				if err != nil {
+					return err
+				}
+				owner = a.Owner
+				counterparties[other] = owner
+			}
+			line.Counterparty = owner
+		}
+		return w.Line(line)
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -2,24 +2,34 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"slices"
+	"time"
 
 	"github.com/9ssi7/bank/internal/domain/auth"
+	"github.com/9ssi7/bank/internal/domain/outbox"
 	"github.com/9ssi7/bank/internal/domain/user"
 	"github.com/9ssi7/bank/pkg/agent"
 	"github.com/9ssi7/bank/pkg/rescode"
 	"github.com/9ssi7/bank/pkg/state"
 	"github.com/9ssi7/bank/pkg/token"
+	"github.com/9ssi7/bank/pkg/totp"
+	"github.com/9ssi7/bank/pkg/webauthn"
+	"github.com/9ssi7/txn"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type TokenSrv interface {
 	GenerateAccessToken(ctx context.Context, u token.User) (string, error)
-	GenerateRefreshToken(ctx context.Context, u token.User) (string, error)
+	GenerateRefreshToken(ctx context.Context, u token.User, family string) (string, error)
 	Parse(ctx context.Context, token string) (*token.UserClaim, error)
 	Verify(ctx context.Context, token string) (bool, error)
 	VerifyAndParse(ctx context.Context, token string) (*token.UserClaim, error)
+	Revoke(ctx context.Context, token string) error
+	Rotate(ctx context.Context) error
+	JWKS() token.JWKS
 }
 
 type EventSrv interface {
@@ -28,10 +38,23 @@ type EventSrv interface {
 
 type AuthUseCase struct {
 	TokenSrv    TokenSrv
-	EventSrv    EventSrv
+	OutboxRepo  outbox.Repo
 	VerifyRepo  auth.VerifyRepo
 	UserRepo    user.Repo
 	SessionRepo auth.SessionRepo
+	TotpRepo    auth.TotpRepo
+
+	RefreshFamilyRepo auth.RefreshFamilyRepo
+
+	// DevicePolicy decides on the calls of a session from another address, the zero policy rejects them.
+	DevicePolicy auth.DevicePolicy
+
+	// TotpIssuer names the bank in the authenticator apps of the users.
+	TotpIssuer string
+
+	PasskeyRepo  auth.PasskeyRepo
+	WebauthnRepo auth.WebauthnRepo
+	Webauthn     *webauthn.Config
 }
 
 type AuthLoginVerifyCheckOpts struct {
@@ -84,7 +107,7 @@ func (u *AuthUseCase) LoginStart(ctx context.Context, trc trace.Tracer, opts Aut
 	if err := u.VerifyRepo.Save(ctx, trc, auth.VerifySaveOpts{Token: verifyToken, Verify: verify}); err != nil {
 		return nil, err
 	}
-	err = u.EventSrv.Publish(ctx, auth.SubjectLoginStarted, &auth.EventLoginStarted{
+	err = enqueue(ctx, trc, u.OutboxRepo, auth.SubjectLoginStarted, &auth.EventLoginStarted{
 		Email:  usr.Email,
 		Code:   verify.Code,
 		Device: opts.Device,
@@ -97,32 +120,21 @@ func (u *AuthUseCase) LoginStart(ctx context.Context, trc trace.Tracer, opts Aut
 
 type AuthLoginVerifyOpts struct {
 	Code        string
+	Factor      auth.Factor
 	VerifyToken string
 	Device      agent.Device
 }
 
+// LoginVerify takes the emailed code, or a code of the authenticator app or a recovery code of a user who enrolled one.
 func (u *AuthUseCase) LoginVerify(ctx context.Context, trc trace.Tracer, opts AuthLoginVerifyOpts) (*string, *string, error) {
 	ctx, span := trc.Start(ctx, "AuthUseCase.LoginVerify")
 	defer span.End()
-	verify, err := u.VerifyRepo.Find(ctx, trc, auth.VerifyFindOpts{Token: opts.VerifyToken, DeviceId: state.GetDeviceId(ctx)})
-	if err != nil {
-		return nil, nil, err
-	}
-	if verify.IsExpired() {
-		return nil, nil, auth.VerificationExpired(errors.New("verification expired"))
-	}
-	if verify.IsExceeded() {
-		return nil, nil, auth.VerificationExceeded(errors.New("verification exceeded"))
-	}
-	if opts.Code != verify.Code {
-		verify.IncTryCount()
-		err = u.VerifyRepo.Save(ctx, trc, auth.VerifySaveOpts{Token: opts.VerifyToken, Verify: verify})
-		if err != nil {
-			return nil, nil, err
+	verify, err := checkVerify(ctx, trc, u.VerifyRepo, opts.VerifyToken, auth.VerifyPurposeLogin, func(v *auth.Verify) (bool, error) {
+		if opts.Factor == auth.FactorTotp || opts.Factor == auth.FactorRecovery {
+			return u.checkSecondFactor(ctx, trc, v.UserId, opts.Factor, opts.Code)
 		}
-		return nil, nil, auth.VerificationInvalid(errors.New("verification invalid"))
-	}
-	err = u.VerifyRepo.Delete(ctx, trc, auth.VerifyDeleteOpts{Token: opts.VerifyToken, DeviceId: state.GetDeviceId(ctx)})
+		return opts.Code == v.Code, nil
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -130,26 +142,33 @@ func (u *AuthUseCase) LoginVerify(ctx context.Context, trc trace.Tracer, opts Au
 	if err != nil {
 		return nil, nil, err
 	}
+	return u.startSession(ctx, trc, user, opts.Device)
+}
+
+// startSession mints the tokens of the user and keeps them as the session of the device.
+func (u *AuthUseCase) startSession(ctx context.Context, trc trace.Tracer, usr *user.User, device agent.Device) (*string, *string, error) {
 	claims := token.User{
-		ID:    user.ID,
-		Name:  user.Name,
-		Email: user.Email,
+		ID:    usr.ID,
+		Name:  usr.Name,
+		Email: usr.Email,
 	}
 	accessToken, err := u.TokenSrv.GenerateAccessToken(ctx, claims)
 	if err != nil {
 		return nil, nil, rescode.Failed(err)
 	}
-	refreshToken, err := u.TokenSrv.GenerateRefreshToken(ctx, claims)
+	family := uuid.New().String()
+	refreshToken, err := u.TokenSrv.GenerateRefreshToken(ctx, claims, family)
 	if err != nil {
 		return nil, nil, rescode.Failed(err)
 	}
 	ses := auth.NewSession(auth.SessionConfig{
-		Device:       opts.Device,
+		Device:       device,
 		DeviceId:     state.GetDeviceId(ctx),
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		Family:       family,
 	})
-	if err = u.SessionRepo.Save(ctx, trc, auth.SessionSaveOpts{UserId: user.ID, Session: ses}); err != nil {
+	if err = u.SessionRepo.Save(ctx, trc, auth.SessionSaveOpts{UserId: usr.ID, Session: ses}); err != nil {
 		return nil, nil, err
 	}
 	return &accessToken, &refreshToken, nil
@@ -162,37 +181,117 @@ type AuthRefreshTokenOpts struct {
 	IpAddr     string
 }
 
-func (u *AuthUseCase) RefreshToken(ctx context.Context, trc trace.Tracer, opts AuthRefreshTokenOpts) (*string, error) {
+// RefreshToken rotates both tokens of the session, the refresh token presented can not be used again.
+// Presenting one the family already rotated out revokes the session of the family, see revokeReusedRefresh.
+func (u *AuthUseCase) RefreshToken(ctx context.Context, trc trace.Tracer, opts AuthRefreshTokenOpts) (*string, *string, error) {
 	ctx, span := trc.Start(ctx, "AuthUseCase.RefreshToken")
 	defer span.End()
+	claims, err := u.TokenSrv.Parse(ctx, opts.RefreshTkn)
+	if err != nil {
+		return nil, nil, rescode.Failed(err)
+	}
+	if err := u.checkRefreshReuse(ctx, trc, claims, opts.RefreshTkn, opts.IpAddr); err != nil {
+		return nil, nil, err
+	}
 	session, notFound, err := u.SessionRepo.Find(ctx, trc, auth.SessionFindOpts{UserId: opts.UserId, DeviceId: state.GetDeviceId(ctx)})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if notFound {
-		return nil, auth.InvalidRefreshOrAccessTokens(errors.New("invalid refresh with access token and ip"))
+		return nil, nil, auth.InvalidRefreshOrAccessTokens(errors.New("invalid refresh with access token and ip"))
+	}
+	if !session.IsRefreshValid(opts.AccessTkn, opts.RefreshTkn) {
+		return nil, nil, auth.InvalidRefreshOrAccessTokens(errors.New("invalid refresh with access token and ip"))
+	}
+	if err := u.checkDevice(ctx, trc, claims.User, session, opts.IpAddr, auth.InvalidRefreshOrAccessTokens); err != nil {
+		return nil, nil, err
 	}
-	if !session.IsRefreshValid(opts.AccessTkn, opts.RefreshTkn, opts.IpAddr) {
-		return nil, auth.InvalidRefreshOrAccessTokens(errors.New("invalid refresh with access token and ip"))
+	if session.Family == "" {
+		session.Family = uuid.New().String()
+	}
+	fresh, err := u.RefreshFamilyRepo.MarkUsed(ctx, trc, auth.RefreshFamilyMarkUsedOpts{Family: session.Family, Token: opts.RefreshTkn})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !fresh {
+		// Another call rotated the very same token a moment ago.
+		return nil, nil, u.revokeReusedRefresh(ctx, trc, opts.UserId, session.Family, opts.IpAddr)
 	}
 	user, err := u.UserRepo.FindById(ctx, trc, user.FindByIdOpts{ID: opts.UserId})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	claims := token.User{
+	claim := token.User{
 		ID:    user.ID,
 		Name:  user.Name,
 		Email: user.Email,
 	}
-	accessToken, err := u.TokenSrv.GenerateAccessToken(ctx, claims)
+	accessToken, err := u.TokenSrv.GenerateAccessToken(ctx, claim)
 	if err != nil {
-		return nil, rescode.Failed(err)
+		return nil, nil, rescode.Failed(err)
+	}
+	refreshToken, err := u.TokenSrv.GenerateRefreshToken(ctx, claim, session.Family)
+	if err != nil {
+		return nil, nil, rescode.Failed(err)
 	}
-	session.Refresh(accessToken)
+	session.Rotate(accessToken, refreshToken)
 	if err := u.SessionRepo.Save(ctx, trc, auth.SessionSaveOpts{UserId: user.ID, Session: session}); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return &accessToken, nil
+	return &accessToken, &refreshToken, nil
+}
+
+// checkRefreshReuse fails with RefreshTokenReused when the family of the token already rotated it out.
+func (u *AuthUseCase) checkRefreshReuse(ctx context.Context, trc trace.Tracer, claims *token.UserClaim, refreshTkn string, ipAddr string) error {
+	if claims == nil || claims.Family == "" {
+		return nil
+	}
+	used, err := u.RefreshFamilyRepo.IsUsed(ctx, trc, auth.RefreshFamilyIsUsedOpts{Family: claims.Family, Token: refreshTkn})
+	if err != nil {
+		return err
+	}
+	if !used {
+		return nil
+	}
+	return u.revokeReusedRefresh(ctx, trc, claims.User.ID, claims.Family, ipAddr)
+}
+
+// revokeReusedRefresh destroys every session of the family, whichever device holds it, and lets the user know.
+// A reused refresh token means it leaked, there is no telling whether the thief or the user holds the current one.
+func (u *AuthUseCase) revokeReusedRefresh(ctx context.Context, trc trace.Tracer, userId uuid.UUID, family string, ipAddr string) error {
+	ctx, span := trc.Start(ctx, "AuthUseCase.revokeReusedRefresh")
+	defer span.End()
+	sessions, err := u.SessionRepo.FindAllByUser(ctx, trc, auth.FindAllByUserOpts{UserId: userId})
+	if err != nil {
+		return err
+	}
+	var revoked *auth.Session
+	for _, s := range sessions {
+		if s == nil || s.Family != family {
+			continue
+		}
+		if err := u.endSession(ctx, trc, userId, s); err != nil {
+			return err
+		}
+		revoked = s
+	}
+	usr, err := u.UserRepo.FindById(ctx, trc, user.FindByIdOpts{ID: userId})
+	if err != nil {
+		return err
+	}
+	event := auth.EventRefreshTokenReused{
+		Email:     usr.Email,
+		Name:      usr.Name,
+		IpAddress: ipAddr,
+	}
+	if revoked != nil {
+		event.DeviceName = revoked.DeviceName
+		event.DeviceOS = revoked.DeviceOS
+	}
+	if err := enqueue(ctx, trc, u.OutboxRepo, auth.SubjectRefreshTokenReused, &event); err != nil {
+		return err
+	}
+	return auth.RefreshTokenReused(errors.New("refresh token reused, session revoked"))
 }
 
 type AuthRegisterOpts struct {
@@ -214,17 +313,30 @@ func (u *AuthUseCase) Register(ctx context.Context, trc trace.Tracer, opts AuthR
 		Name:  opts.Name,
 		Email: opts.Email,
 	})
+	tx := txn.New()
+	tx.Register(u.UserRepo.GetTxnAdapter())
+	tx.Register(u.OutboxRepo.GetTxnAdapter())
+	if err := tx.Begin(ctx); err != nil {
+		return err
+	}
+	onError := func(ctx context.Context, err error) error {
+		tx.Rollback(ctx)
+		return err
+	}
 	err = u.UserRepo.Save(ctx, trc, user.SaveOpts{User: usr})
 	if err != nil {
-		return err
+		return onError(ctx, err)
 	}
-	err = u.EventSrv.Publish(ctx, user.SubjectCreated, &user.EventCreated{
+	err = enqueue(ctx, trc, u.OutboxRepo, user.SubjectCreated, &user.EventCreated{
 		Name:      opts.Name,
 		Email:     opts.Email,
 		TempToken: *usr.TempToken,
 	})
 	if err != nil {
-		return err
+		return onError(ctx, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return onError(ctx, err)
 	}
 	return nil
 }
@@ -260,6 +372,9 @@ func (u *AuthUseCase) VerifyAccess(ctx context.Context, trc trace.Tracer, opts A
 	} else {
 		claims, err = u.TokenSrv.VerifyAndParse(ctx, opts.AccessTkn)
 	}
+	if errors.Is(err, token.ErrRevoked) {
+		return nil, auth.InvalidAccess(err)
+	}
 	if err != nil {
 		return nil, rescode.Failed(err)
 	}
@@ -270,9 +385,12 @@ func (u *AuthUseCase) VerifyAccess(ctx context.Context, trc trace.Tracer, opts A
 	if notExists {
 		return nil, auth.InvalidAccess(errors.New("invalid access with token and ip"))
 	}
-	if !session.IsAccessValid(opts.AccessTkn, opts.IpAddr) {
+	if !session.IsAccessValid(opts.AccessTkn) {
 		return nil, auth.InvalidAccess(errors.New("invalid access with token and ip"))
 	}
+	if err := u.checkDevice(ctx, trc, claims.User, session, opts.IpAddr, auth.InvalidAccess); err != nil {
+		return nil, err
+	}
 	return claims, nil
 }
 
@@ -296,6 +414,9 @@ func (u *AuthUseCase) VerifyRefresh(ctx context.Context, trc trace.Tracer, opts
 	if !isValid {
 		return nil, auth.InvalidOrExpiredToken(errors.New("invalid or expired refresh token"))
 	}
+	if err := u.checkRefreshReuse(ctx, trc, claims, opts.RefreshTkn, opts.IpAddr); err != nil {
+		return nil, err
+	}
 	session, notFound, err := u.SessionRepo.Find(ctx, trc, auth.SessionFindOpts{UserId: claims.User.ID, DeviceId: state.GetDeviceId(ctx)})
 	if err != nil {
 		return nil, err
@@ -303,8 +424,672 @@ func (u *AuthUseCase) VerifyRefresh(ctx context.Context, trc trace.Tracer, opts
 	if notFound {
 		return nil, auth.InvalidRefreshToken(errors.New("invalid refresh with access token and ip"))
 	}
-	if !session.IsRefreshValid(opts.AccessTkn, opts.RefreshTkn, opts.IpAddr) {
+	if !session.IsRefreshValid(opts.AccessTkn, opts.RefreshTkn) {
 		return nil, auth.InvalidRefreshToken(errors.New("invalid refresh with access token and ip"))
 	}
+	if err := u.checkDevice(ctx, trc, claims.User, session, opts.IpAddr, auth.InvalidRefreshToken); err != nil {
+		return nil, err
+	}
 	return claims, nil
 }
+
+// checkDevice holds the address of the call against the device policy. A move the policy takes is
+// recorded on the session, one it does not is rejected with reject or sent for reverification.
+func (u *AuthUseCase) checkDevice(ctx context.Context, trc trace.Tracer, usr token.User, session *auth.Session, ip string, reject rescode.RcCreator) error {
+	switch u.DevicePolicy.Evaluate(session, ip, time.Now()) {
+	case auth.DeviceAllow:
+		if session.IpAddress == ip {
+			return nil
+		}
+		session.MoveTo(ip)
+		return u.SessionRepo.Save(ctx, trc, auth.SessionSaveOpts{UserId: usr.ID, Session: session})
+	case auth.DeviceReverify:
+		return u.requireReverify(ctx, trc, usr, session, ip)
+	default:
+		return reject(errors.New("invalid access with token and ip"))
+	}
+}
+
+// requireReverify emails a code the session moves to the address with, see ReverifySession.
+// A move already waiting for its code is not sent again while the code lives.
+func (u *AuthUseCase) requireReverify(ctx context.Context, trc trace.Tracer, usr token.User, session *auth.Session, ip string) error {
+	ctx, span := trc.Start(ctx, "AuthUseCase.requireReverify")
+	defer span.End()
+	if session.PendingIp == ip && session.PendingVerifyToken != "" {
+		exists, err := u.VerifyRepo.IsExists(ctx, trc, auth.VerifyIsExistsOpts{Token: session.PendingVerifyToken, DeviceId: session.DeviceId})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return reverifyRequired(session.PendingVerifyToken)
+		}
+	}
+	payload, err := json.Marshal(auth.SessionReverifyPayload{IpAddress: ip})
+	if err != nil {
+		return rescode.Failed(err)
+	}
+	verifyToken := uuid.New().String()
+	verify := auth.NewVerify(auth.VerifyConfig{
+		UserId:   usr.ID,
+		DeviceId: session.DeviceId,
+		Locale:   state.GetLocale(ctx),
+		Purpose:  auth.VerifyPurposeSession,
+		Payload:  payload,
+	})
+	if err := u.VerifyRepo.Save(ctx, trc, auth.VerifySaveOpts{Token: verifyToken, Verify: verify}); err != nil {
+		return err
+	}
+	err = enqueue(ctx, trc, u.OutboxRepo, auth.SubjectSessionReverify, &auth.EventSessionReverify{
+		Email: usr.Email,
+		Code:  verify.Code,
+		Device: agent.Device{
+			Name: session.DeviceName,
+			Type: session.DeviceType,
+			OS:   session.DeviceOS,
+			IP:   ip,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	session.AwaitReverify(ip, verifyToken)
+	if err := u.SessionRepo.Save(ctx, trc, auth.SessionSaveOpts{UserId: usr.ID, Session: session}); err != nil {
+		return err
+	}
+	return reverifyRequired(verifyToken)
+}
+
+func reverifyRequired(verifyToken string) error {
+	return auth.ReverifyRequired(errors.New("session moved to another address")).SetData(rescode.R{
+		"isReverifyRequired": true,
+		"verify_token":       verifyToken,
+	})
+}
+
+type AuthReverifySessionOpts struct {
+	VerifyToken string
+	Code        string
+	IpAddr      string
+}
+
+// ReverifySession moves the session of the device to the address its emailed code was sent for,
+// the code has to be given from that very address.
+func (u *AuthUseCase) ReverifySession(ctx context.Context, trc trace.Tracer, opts AuthReverifySessionOpts) error {
+	ctx, span := trc.Start(ctx, "AuthUseCase.ReverifySession")
+	defer span.End()
+	var payload auth.SessionReverifyPayload
+	verify, err := checkVerify(ctx, trc, u.VerifyRepo, opts.VerifyToken, auth.VerifyPurposeSession, func(v *auth.Verify) (bool, error) {
+		if err := json.Unmarshal(v.Payload, &payload); err != nil {
+			return false, rescode.Failed(err)
+		}
+		return v.Code == opts.Code && payload.IpAddress == opts.IpAddr, nil
+	})
+	if err != nil {
+		return err
+	}
+	session, notFound, err := u.SessionRepo.Find(ctx, trc, auth.SessionFindOpts{UserId: verify.UserId, DeviceId: state.GetDeviceId(ctx)})
+	if err != nil {
+		return err
+	}
+	if notFound {
+		return auth.SessionNotFound(errors.New("session not found"))
+	}
+	session.MoveTo(payload.IpAddress)
+	return u.SessionRepo.Save(ctx, trc, auth.SessionSaveOpts{UserId: verify.UserId, Session: session})
+}
+
+type AuthSessionsOpts struct {
+	UserId uuid.UUID
+}
+
+// Sessions lists the devices the user is logged in with, the last used one first.
+func (u *AuthUseCase) Sessions(ctx context.Context, trc trace.Tracer, opts AuthSessionsOpts) ([]*auth.SessionListItem, error) {
+	ctx, span := trc.Start(ctx, "AuthUseCase.Sessions")
+	defer span.End()
+	sessions, err := u.SessionRepo.FindAllByUser(ctx, trc, auth.FindAllByUserOpts{UserId: opts.UserId})
+	if err != nil {
+		return nil, err
+	}
+	current := state.GetDeviceId(ctx)
+	result := make([]*auth.SessionListItem, 0, len(sessions))
+	for _, s := range sessions {
+		if s == nil {
+			continue
+		}
+		result = append(result, &auth.SessionListItem{
+			DeviceId:   s.DeviceId,
+			DeviceName: s.DeviceName,
+			DeviceType: s.DeviceType,
+			DeviceOS:   s.DeviceOS,
+			IpAddress:  s.IpAddress,
+			LastLogin:  s.LastLogin,
+			CreatedAt:  s.CreatedAt,
+			Current:    s.DeviceId == current,
+		})
+	}
+	slices.SortFunc(result, func(a, b *auth.SessionListItem) int {
+		return b.LastLogin.Compare(a.LastLogin)
+	})
+	return result, nil
+}
+
+type AuthRevokeSessionOpts struct {
+	UserId   uuid.UUID
+	DeviceId string
+}
+
+// RevokeSession logs the user out of one of the devices of it, the tokens of that device stop working at once.
+func (u *AuthUseCase) RevokeSession(ctx context.Context, trc trace.Tracer, opts AuthRevokeSessionOpts) error {
+	ctx, span := trc.Start(ctx, "AuthUseCase.RevokeSession")
+	defer span.End()
+	session, notFound, err := u.SessionRepo.Find(ctx, trc, auth.SessionFindOpts{UserId: opts.UserId, DeviceId: opts.DeviceId})
+	if err != nil {
+		return err
+	}
+	if notFound {
+		return auth.SessionNotFound(errors.New("session not found"))
+	}
+	return u.endSession(ctx, trc, opts.UserId, session)
+}
+
+type AuthRevokeOtherSessionsOpts struct {
+	UserId uuid.UUID
+}
+
+// RevokeOtherSessions logs the user out everywhere but the device the call comes from.
+func (u *AuthUseCase) RevokeOtherSessions(ctx context.Context, trc trace.Tracer, opts AuthRevokeOtherSessionsOpts) error {
+	ctx, span := trc.Start(ctx, "AuthUseCase.RevokeOtherSessions")
+	defer span.End()
+	sessions, err := u.SessionRepo.FindAllByUser(ctx, trc, auth.FindAllByUserOpts{UserId: opts.UserId})
+	if err != nil {
+		return err
+	}
+	current := state.GetDeviceId(ctx)
+	for _, s := range sessions {
+		if s == nil || s.DeviceId == current {
+			continue
+		}
+		if err := u.endSession(ctx, trc, opts.UserId, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type AuthLogoutOpts struct {
+	UserId uuid.UUID
+}
+
+// Logout ends the session of the device the call comes from.
+func (u *AuthUseCase) Logout(ctx context.Context, trc trace.Tracer, opts AuthLogoutOpts) error {
+	ctx, span := trc.Start(ctx, "AuthUseCase.Logout")
+	defer span.End()
+	session, notFound, err := u.SessionRepo.Find(ctx, trc, auth.SessionFindOpts{UserId: opts.UserId, DeviceId: state.GetDeviceId(ctx)})
+	if err != nil {
+		return err
+	}
+	if notFound {
+		return nil
+	}
+	return u.endSession(ctx, trc, opts.UserId, session)
+}
+
+// endSession denies the tokens of the session before it destroys it, so the services verifying
+// the tokens on their own with the keyset stop taking them too.
+func (u *AuthUseCase) endSession(ctx context.Context, trc trace.Tracer, userId uuid.UUID, session *auth.Session) error {
+	for _, t := range []string{session.AccessToken, session.RefreshToken} {
+		if t == "" {
+			continue
+		}
+		if err := u.TokenSrv.Revoke(ctx, t); err != nil {
+			return rescode.Failed(err)
+		}
+	}
+	return u.SessionRepo.Destroy(ctx, trc, auth.SessionDestroyOpts{UserId: userId, DeviceId: session.DeviceId})
+}
+
+// Jwks returns the public keys the access tokens may be signed with.
+func (u *AuthUseCase) Jwks(ctx context.Context, trc trace.Tracer) token.JWKS {
+	_, span := trc.Start(ctx, "AuthUseCase.Jwks")
+	defer span.End()
+	return u.TokenSrv.JWKS()
+}
+
+// RotateKeys replaces the signing key when it is due and picks up the keys other instances rotated in.
+func (u *AuthUseCase) RotateKeys(ctx context.Context, trc trace.Tracer) error {
+	ctx, span := trc.Start(ctx, "AuthUseCase.RotateKeys")
+	defer span.End()
+	if err := u.TokenSrv.Rotate(ctx); err != nil {
+		return rescode.Failed(err)
+	}
+	return nil
+}
+
+type AuthTotpEnrollOpts struct {
+	UserId uuid.UUID
+	Email  string
+	Name   string
+}
+
+// TotpEnroll starts over the enrolment of an authenticator app and emails the code TotpConfirm takes along with
+// a code of the app, so a stolen access token alone can not add one.
+func (u *AuthUseCase) TotpEnroll(ctx context.Context, trc trace.Tracer, opts AuthTotpEnrollOpts) (*auth.TotpEnrollment, error) {
+	ctx, span := trc.Start(ctx, "AuthUseCase.TotpEnroll")
+	defer span.End()
+	t, err := u.TotpRepo.FindByUserId(ctx, trc, auth.TotpFindByUserIdOpts{UserId: opts.UserId})
+	if err != nil {
+		return nil, err
+	}
+	if t.IsConfirmed() {
+		return nil, auth.TotpAlreadyEnrolled(errors.New("totp already enrolled"))
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, rescode.Failed(err)
+	}
+	verifyToken := uuid.New().String()
+	verify := auth.NewVerify(auth.VerifyConfig{
+		UserId:   opts.UserId,
+		DeviceId: state.GetDeviceId(ctx),
+		Locale:   state.GetLocale(ctx),
+		Purpose:  auth.VerifyPurposeTotp,
+	})
+	if err := u.VerifyRepo.Save(ctx, trc, auth.VerifySaveOpts{Token: verifyToken, Verify: verify}); err != nil {
+		return nil, err
+	}
+	tx := txn.New()
+	tx.Register(u.TotpRepo.GetTxnAdapter())
+	tx.Register(u.OutboxRepo.GetTxnAdapter())
+	if err := tx.Begin(ctx); err != nil {
+		return nil, err
+	}
+	onError := func(ctx context.Context, err error) (*auth.TotpEnrollment, error) {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	if err := u.TotpRepo.Save(ctx, trc, auth.TotpSaveOpts{Totp: auth.NewTotp(opts.UserId, secret)}); err != nil {
+		return onError(ctx, err)
+	}
+	err = enqueue(ctx, trc, u.OutboxRepo, auth.SubjectTotpEnrollStarted, &auth.EventTotpEnrollStarted{
+		Email: opts.Email,
+		Name:  opts.Name,
+		Code:  verify.Code,
+	})
+	if err != nil {
+		return onError(ctx, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return onError(ctx, err)
+	}
+	return &auth.TotpEnrollment{
+		Secret:      secret,
+		Uri:         totp.URI(u.TotpIssuer, opts.Email, secret),
+		VerifyToken: verifyToken,
+	}, nil
+}
+
+type AuthTotpConfirmOpts struct {
+	UserId      uuid.UUID
+	Email       string
+	Name        string
+	VerifyToken string
+	EmailCode   string
+	Code        string
+}
+
+// TotpConfirm completes the enrolment with the emailed code and a code of the app, and returns the recovery codes,
+// they are shown this once. A wrong pair uses up a try of the emailed code.
+func (u *AuthUseCase) TotpConfirm(ctx context.Context, trc trace.Tracer, opts AuthTotpConfirmOpts) ([]string, error) {
+	ctx, span := trc.Start(ctx, "AuthUseCase.TotpConfirm")
+	defer span.End()
+	t, err := u.TotpRepo.FindByUserId(ctx, trc, auth.TotpFindByUserIdOpts{UserId: opts.UserId})
+	if err != nil {
+		return nil, err
+	}
+	if t.UserId == uuid.Nil {
+		return nil, auth.TotpNotEnrolled(errors.New("totp enrolment not started"))
+	}
+	if t.IsConfirmed() {
+		return nil, auth.TotpAlreadyEnrolled(errors.New("totp already enrolled"))
+	}
+	now := time.Now()
+	_, err = checkVerify(ctx, trc, u.VerifyRepo, opts.VerifyToken, auth.VerifyPurposeTotp, func(v *auth.Verify) (bool, error) {
+		return v.UserId == opts.UserId && v.Code == opts.EmailCode && t.Check(opts.Code, now), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.Confirm(now)
+	codes, err := auth.NewRecoveryCodes()
+	if err != nil {
+		return nil, rescode.Failed(err)
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = auth.HashRecoveryCode(c)
+	}
+	tx := txn.New()
+	tx.Register(u.TotpRepo.GetTxnAdapter())
+	tx.Register(u.OutboxRepo.GetTxnAdapter())
+	if err := tx.Begin(ctx); err != nil {
+		return nil, err
+	}
+	onError := func(ctx context.Context, err error) ([]string, error) {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	if err := u.TotpRepo.Save(ctx, trc, auth.TotpSaveOpts{Totp: t}); err != nil {
+		return onError(ctx, err)
+	}
+	if err := u.TotpRepo.SaveRecoveryCodes(ctx, trc, auth.TotpSaveRecoveryCodesOpts{UserId: opts.UserId, Hashes: hashes}); err != nil {
+		return onError(ctx, err)
+	}
+	err = enqueue(ctx, trc, u.OutboxRepo, auth.SubjectTotpEnrolled, &auth.EventTotpEnrolled{
+		Email: opts.Email,
+		Name:  opts.Name,
+	})
+	if err != nil {
+		return onError(ctx, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return onError(ctx, err)
+	}
+	return codes, nil
+}
+
+type AuthTotpDisableOpts struct {
+	UserId uuid.UUID
+	Code   string
+	Factor auth.Factor
+}
+
+// TotpDisable removes the app and its recovery codes, it takes a code of the app or a recovery code.
+func (u *AuthUseCase) TotpDisable(ctx context.Context, trc trace.Tracer, opts AuthTotpDisableOpts) error {
+	ctx, span := trc.Start(ctx, "AuthUseCase.TotpDisable")
+	defer span.End()
+	ok, err := u.checkSecondFactor(ctx, trc, opts.UserId, opts.Factor, opts.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return auth.VerificationInvalid(errors.New("totp code invalid"))
+	}
+	return u.TotpRepo.Delete(ctx, trc, auth.TotpDeleteOpts{UserId: opts.UserId})
+}
+
+// checkSecondFactor reports whether the code is a fresh code of the confirmed app of the user,
+// or one of the recovery codes not used yet. An accepted code can not be used again, and the app
+// is locked for a while once too many codes were rejected in a row.
+func (u *AuthUseCase) checkSecondFactor(ctx context.Context, trc trace.Tracer, userId uuid.UUID, factor auth.Factor, code string) (bool, error) {
+	ctx, span := trc.Start(ctx, "AuthUseCase.checkSecondFactor")
+	defer span.End()
+	t, err := u.TotpRepo.FindByUserId(ctx, trc, auth.TotpFindByUserIdOpts{UserId: userId})
+	if err != nil {
+		return false, err
+	}
+	if !t.IsConfirmed() {
+		return false, auth.TotpNotEnrolled(errors.New("totp not enrolled"))
+	}
+	now := time.Now()
+	if t.IsLocked(now) {
+		return false, auth.VerificationExceeded(errors.New("too many totp codes rejected"))
+	}
+	var ok bool
+	if factor == auth.FactorRecovery {
+		ok, err = u.TotpRepo.UseRecoveryCode(ctx, trc, auth.TotpUseRecoveryCodeOpts{UserId: userId, Hash: auth.HashRecoveryCode(code)})
+		if err != nil {
+			return false, err
+		}
+	} else {
+		ok = t.Check(code, now)
+	}
+	if ok {
+		t.Pass(now)
+	} else {
+		t.Fail(now)
+	}
+	return ok, u.TotpRepo.Save(ctx, trc, auth.TotpSaveOpts{Totp: t})
+}
+
+type AuthWebauthnRegisterStartOpts struct {
+	UserId uuid.UUID
+	Email  string
+	Name   string
+}
+
+// WebauthnRegisterStart starts the registration of a passkey for the user, it returns the token
+// WebauthnRegisterFinish takes along with the options for the authenticator. A code is emailed for
+// the registration under the same token.
+func (u *AuthUseCase) WebauthnRegisterStart(ctx context.Context, trc trace.Tracer, opts AuthWebauthnRegisterStartOpts) (*string, *webauthn.CreationOptions, error) {
+	ctx, span := trc.Start(ctx, "AuthUseCase.WebauthnRegisterStart")
+	defer span.End()
+	passkeys, err := u.PasskeyRepo.ListByUserId(ctx, trc, auth.PasskeyListByUserIdOpts{UserId: opts.UserId})
+	if err != nil {
+		return nil, nil, err
+	}
+	exclude := make([][]byte, 0, len(passkeys))
+	for _, p := range passkeys {
+		exclude = append(exclude, p.CredentialId)
+	}
+	challenge, err := u.saveChallenge(ctx, trc, auth.WebauthnCeremonyRegister, opts.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+	verify := auth.NewVerify(auth.VerifyConfig{
+		UserId:   opts.UserId,
+		DeviceId: state.GetDeviceId(ctx),
+		Locale:   state.GetLocale(ctx),
+		Purpose:  auth.VerifyPurposePasskey,
+	})
+	if err := u.VerifyRepo.Save(ctx, trc, auth.VerifySaveOpts{Token: challenge.token, Verify: verify}); err != nil {
+		return nil, nil, err
+	}
+	err = enqueue(ctx, trc, u.OutboxRepo, auth.SubjectPasskeyStarted, &auth.EventPasskeyStarted{
+		Email: opts.Email,
+		Name:  opts.Name,
+		Code:  verify.Code,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	options := u.Webauthn.NewCreationOptions(webauthn.UserEntity{
+		ID:          opts.UserId[:],
+		Name:        opts.Email,
+		DisplayName: opts.Name,
+	}, challenge.Challenge, exclude)
+	return &challenge.token, options, nil
+}
+
+type AuthWebauthnRegisterFinishOpts struct {
+	UserId      uuid.UUID
+	Email       string
+	Name        string
+	Token       string
+	Code        string
+	Factor      auth.Factor
+	PasskeyName string
+	Credential  *webauthn.RegistrationResponse
+}
+
+// WebauthnRegisterFinish saves the passkey once the emailed code, or a code of the authenticator app or a
+// recovery code of a user who enrolled one, confirmed the registration. A wrong code uses up a try and
+// leaves the challenge for the next one.
+func (u *AuthUseCase) WebauthnRegisterFinish(ctx context.Context, trc trace.Tracer, opts AuthWebauthnRegisterFinishOpts) (*auth.Passkey, error) {
+	ctx, span := trc.Start(ctx, "AuthUseCase.WebauthnRegisterFinish")
+	defer span.End()
+	_, err := checkVerify(ctx, trc, u.VerifyRepo, opts.Token, auth.VerifyPurposePasskey, func(v *auth.Verify) (bool, error) {
+		if v.UserId != opts.UserId {
+			return false, nil
+		}
+		if opts.Factor == auth.FactorTotp || opts.Factor == auth.FactorRecovery {
+			return u.checkSecondFactor(ctx, trc, v.UserId, opts.Factor, opts.Code)
+		}
+		return opts.Code == v.Code, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := u.WebauthnRepo.Take(ctx, trc, auth.WebauthnTakeOpts{Token: opts.Token, DeviceId: state.GetDeviceId(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Ceremony != auth.WebauthnCeremonyRegister || challenge.UserId != opts.UserId {
+		return nil, auth.WebauthnInvalid(errors.New("challenge of another ceremony"))
+	}
+	cred, err := u.Webauthn.VerifyRegistration(challenge.Challenge, opts.Credential)
+	if err != nil {
+		return nil, auth.WebauthnInvalid(err)
+	}
+	passkey := auth.NewPasskey(auth.PasskeyConfig{
+		UserId:       opts.UserId,
+		Name:         opts.PasskeyName,
+		CredentialId: cred.ID,
+		PublicKey:    cred.PublicKey,
+		SignCount:    cred.SignCount,
+		Aaguid:       cred.AAGUID,
+	})
+	tx := txn.New()
+	tx.Register(u.PasskeyRepo.GetTxnAdapter())
+	tx.Register(u.OutboxRepo.GetTxnAdapter())
+	if err := tx.Begin(ctx); err != nil {
+		return nil, err
+	}
+	onError := func(ctx context.Context, err error) (*auth.Passkey, error) {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	err = u.PasskeyRepo.Save(ctx, trc, auth.PasskeySaveOpts{Passkey: passkey})
+	if errors.Is(err, auth.ErrPasskeyTaken) {
+		return onError(ctx, auth.PasskeyAlreadyRegistered(err))
+	}
+	if err != nil {
+		return onError(ctx, err)
+	}
+	err = enqueue(ctx, trc, u.OutboxRepo, auth.SubjectPasskeyAdded, &auth.EventPasskeyAdded{
+		Email:       opts.Email,
+		Name:        opts.Name,
+		PasskeyName: passkey.Name,
+	})
+	if err != nil {
+		return onError(ctx, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return onError(ctx, err)
+	}
+	return passkey, nil
+}
+
+// WebauthnLoginStart starts a login with any passkey of the relying party, the authenticator lets the user pick one.
+func (u *AuthUseCase) WebauthnLoginStart(ctx context.Context, trc trace.Tracer) (*string, *webauthn.RequestOptions, error) {
+	ctx, span := trc.Start(ctx, "AuthUseCase.WebauthnLoginStart")
+	defer span.End()
+	challenge, err := u.saveChallenge(ctx, trc, auth.WebauthnCeremonyLogin, uuid.Nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &challenge.token, u.Webauthn.NewRequestOptions(challenge.Challenge), nil
+}
+
+type AuthWebauthnLoginFinishOpts struct {
+	Token      string
+	Credential *webauthn.AssertionResponse
+	Device     agent.Device
+}
+
+// WebauthnLoginFinish checks the assertion of the passkey and starts a session the way LoginVerify does.
+func (u *AuthUseCase) WebauthnLoginFinish(ctx context.Context, trc trace.Tracer, opts AuthWebauthnLoginFinishOpts) (*string, *string, error) {
+	ctx, span := trc.Start(ctx, "AuthUseCase.WebauthnLoginFinish")
+	defer span.End()
+	challenge, err := u.WebauthnRepo.Take(ctx, trc, auth.WebauthnTakeOpts{Token: opts.Token, DeviceId: state.GetDeviceId(ctx)})
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge.Ceremony != auth.WebauthnCeremonyLogin {
+		return nil, nil, auth.WebauthnInvalid(errors.New("challenge of another ceremony"))
+	}
+	passkey, err := u.PasskeyRepo.FindByCredentialId(ctx, trc, auth.PasskeyFindByCredentialIdOpts{CredentialId: opts.Credential.RawID})
+	if err != nil {
+		return nil, nil, err
+	}
+	if passkey.ID == uuid.Nil {
+		return nil, nil, auth.WebauthnInvalid(errors.New("unknown credential"))
+	}
+	signCount, err := u.Webauthn.VerifyAssertion(challenge.Challenge, &webauthn.Credential{
+		ID:        passkey.CredentialId,
+		PublicKey: passkey.PublicKey,
+		SignCount: passkey.SignCount,
+	}, opts.Credential)
+	if err != nil {
+		return nil, nil, auth.WebauthnInvalid(err)
+	}
+	usr, err := u.UserRepo.FindById(ctx, trc, user.FindByIdOpts{ID: passkey.UserId})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !usr.IsActive {
+		return nil, nil, user.Disabled(errors.New("user disabled"))
+	}
+	passkey.Use(signCount, time.Now())
+	if err := u.PasskeyRepo.Save(ctx, trc, auth.PasskeySaveOpts{Passkey: passkey}); err != nil {
+		return nil, nil, err
+	}
+	return u.startSession(ctx, trc, usr, opts.Device)
+}
+
+// pendingChallenge is a saved challenge along with the token it is kept under.
+type pendingChallenge struct {
+	*auth.WebauthnChallenge
+	token string
+}
+
+func (u *AuthUseCase) saveChallenge(ctx context.Context, trc trace.Tracer, ceremony auth.WebauthnCeremony, userId uuid.UUID) (*pendingChallenge, error) {
+	b, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, rescode.Failed(err)
+	}
+	c := &pendingChallenge{
+		WebauthnChallenge: &auth.WebauthnChallenge{
+			Ceremony:  ceremony,
+			Challenge: b,
+			UserId:    userId,
+			DeviceId:  state.GetDeviceId(ctx),
+		},
+		token: uuid.New().String(),
+	}
+	if err := u.WebauthnRepo.Save(ctx, trc, auth.WebauthnSaveOpts{Token: c.token, Challenge: c.WebauthnChallenge}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkVerify spends the verification of the token when match accepts it, a rejected code uses up a try.
+func checkVerify(ctx context.Context, trc trace.Tracer, repo auth.VerifyRepo, verifyToken string, purpose auth.VerifyPurpose, match func(*auth.Verify) (bool, error)) (*auth.Verify, error) {
+	verify, err := repo.Find(ctx, trc, auth.VerifyFindOpts{Token: verifyToken, DeviceId: state.GetDeviceId(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	if !verify.IsFor(purpose) {
+		return nil, auth.VerificationInvalid(errors.New("verification sent for another purpose"))
+	}
+	if verify.IsExpired() {
+		return nil, auth.VerificationExpired(errors.New("verification expired"))
+	}
+	if verify.IsExceeded() {
+		return nil, auth.VerificationExceeded(errors.New("verification exceeded"))
+	}
+	ok, err := match(verify)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		verify.IncTryCount()
+		if err := repo.Save(ctx, trc, auth.VerifySaveOpts{Token: verifyToken, Verify: verify}); err != nil {
+			return nil, err
+		}
+		return nil, auth.VerificationInvalid(errors.New("verification invalid"))
+	}
+	if err := repo.Delete(ctx, trc, auth.VerifyDeleteOpts{Token: verifyToken, DeviceId: state.GetDeviceId(ctx)}); err != nil {
+		return nil, err
+	}
+	return verify, nil
+}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/idempotency"
+	"github.com/9ssi7/bank/pkg/retry"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type IdempotencyUseCase struct {
+	Repo idempotency.Repo
+}
+
+// idempotencyStuckFor is how long a key stays in progress when the response could not be stored,
+// the client is answered InProgress until then and may send the request again after.
+const idempotencyStuckFor = time.Minute
+
+type IdempotencyBeginOpts struct {
+	UserId   uuid.UUID
+	Key      string
+	BodyHash string
+}
+
+// Begin claims the key for the request. It returns the stored record when the
+// request was already completed and should be replayed, or nil when the caller
+// owns the key and has to run the request.
+func (u *IdempotencyUseCase) Begin(ctx context.Context, trc trace.Tracer, opts IdempotencyBeginOpts) (*idempotency.Record, error) {
+	ctx, span := trc.Start(ctx, "IdempotencyUseCase.Begin")
+	defer span.End()
+	started, err := u.Repo.Start(ctx, trc, idempotency.StartOpts{
+		UserId: opts.UserId,
+		Key:    opts.Key,
+		Record: idempotency.New(idempotency.Config{BodyHash: opts.BodyHash}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if started {
+		return nil, nil
+	}
+	record, notExists, err := u.Repo.Find(ctx, trc, idempotency.FindOpts{UserId: opts.UserId, Key: opts.Key})
+	if err != nil {
+		return nil, err
+	}
+	if notExists {
+		// the key expired between start and find, let the client try again
+		return nil, idempotency.InProgress(errors.New("idempotency key expired while claiming"))
+	}
+	if !record.Matches(opts.BodyHash) {
+		return nil, idempotency.KeyReused(errors.New("idempotency key reused with a different request"))
+	}
+	if !record.Completed {
+		return nil, idempotency.InProgress(errors.New("idempotency key request in progress"))
+	}
+	return record, nil
+}
+
+type IdempotencyCompleteOpts struct {
+	UserId      uuid.UUID
+	Key         string
+	BodyHash    string
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Complete stores the response to replay for the key. When it can not be stored after a few tries,
+// the key is let go after idempotencyStuckFor instead of answering InProgress for the whole retention.
+func (u *IdempotencyUseCase) Complete(ctx context.Context, trc trace.Tracer, opts IdempotencyCompleteOpts) error {
+	ctx, span := trc.Start(ctx, "IdempotencyUseCase.Complete")
+	defer span.End()
+	record := idempotency.New(idempotency.Config{BodyHash: opts.BodyHash})
+	record.Complete(opts.Status, opts.ContentType, opts.Body)
+	err := retry.Run(func() error {
+		return u.Repo.Save(ctx, trc, idempotency.SaveOpts{UserId: opts.UserId, Key: opts.Key, Record: record})
+	}, retry.Config{MaxRetries: 3, WaitTime: 100 * time.Millisecond})
+	if err == nil {
+		return nil
+	}
+	span.RecordError(err)
+	if expErr := u.Repo.Expire(ctx, trc, idempotency.ExpireOpts{UserId: opts.UserId, Key: opts.Key, In: idempotencyStuckFor}); expErr != nil {
+		span.RecordError(expErr)
+		return errors.Join(err, expErr)
+	}
+	return err
+}
+
+type IdempotencyReleaseOpts struct {
+	UserId uuid.UUID
+	Key    string
+}
+
+// Release frees the key of a failed request, so the client can retry it.
+func (u *IdempotencyUseCase) Release(ctx context.Context, trc trace.Tracer, opts IdempotencyReleaseOpts) error {
+	ctx, span := trc.Start(ctx, "IdempotencyUseCase.Release")
+	defer span.End()
+	return u.Repo.Delete(ctx, trc, idempotency.DeleteOpts{UserId: opts.UserId, Key: opts.Key})
+}
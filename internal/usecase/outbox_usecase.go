@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/outbox"
+	"github.com/9ssi7/bank/pkg/retry"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type OutboxUseCase struct {
+	EventSrv EventSrv
+	Repo     outbox.Repo
+}
+
+const (
+	outboxPublishRetries = 3
+	outboxPublishTimeout = 5 * time.Second
+	outboxPublishWait    = 100 * time.Millisecond
+
+	// outboxClaimFor keeps a batch off the other relays until the relay gets to each message.
+	outboxClaimFor = time.Minute
+
+	// outboxMessageClaimFor outlasts every publish attempt of a message and saving its outcome,
+	// the claim is renewed for it right before the message is published.
+	outboxMessageClaimFor = outboxPublishRetries*(outboxPublishTimeout+outboxPublishWait) + outboxPublishTimeout
+)
+
+type OutboxRelayOpts struct {
+	Limit       int
+	MaxAttempts int
+}
+
+// Relay publishes a batch of pending messages and marks them as sent.
+// A message that could not be published stays pending for the next run until it failed
+// MaxAttempts times, then it is marked failed and left for an operator.
+// The batch is claimed up front, so no row stays locked while the event stream is called.
+// Each message renews its claim before it is published, a message whose claim lapsed and was
+// taken by another relay meanwhile is left to that relay.
+func (u *OutboxUseCase) Relay(ctx context.Context, trc trace.Tracer, opts OutboxRelayOpts) (int, error) {
+	ctx, span := trc.Start(ctx, "OutboxUseCase.Relay")
+	defer span.End()
+	messages, err := u.Repo.Claim(ctx, trc, outbox.ClaimOpts{
+		Limit:       opts.Limit,
+		MaxAttempts: opts.MaxAttempts,
+		ClaimFor:    outboxClaimFor,
+	})
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, m := range messages {
+		held, err := u.Repo.Renew(ctx, trc, outbox.RenewOpts{Message: m, ClaimFor: outboxMessageClaimFor})
+		if err != nil {
+			return sent, err
+		}
+		if !held {
+			continue
+		}
+		err = retry.Run(func() error {
+			ctx, cancel := context.WithTimeout(ctx, outboxPublishTimeout)
+			defer cancel()
+			return u.EventSrv.Publish(ctx, m.Subject, json.RawMessage(m.Payload))
+		}, retry.Config{MaxRetries: outboxPublishRetries, WaitTime: outboxPublishWait})
+		if err != nil {
+			span.RecordError(err)
+			m.Fail(err, opts.MaxAttempts)
+		} else {
+			m.MarkSent()
+			sent++
+		}
+		if err := u.Repo.Save(ctx, trc, outbox.SaveOpts{Message: m}); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// enqueue stores an event in the outbox, it is published once the surrounding transaction commits.
+func enqueue(ctx context.Context, trc trace.Tracer, repo outbox.Repo, subject string, data interface{}) error {
+	p, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return repo.Save(ctx, trc, outbox.SaveOpts{Message: outbox.New(outbox.Config{Subject: subject, Payload: p})})
+}
@@ -0,0 +1,351 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/account"
+	"github.com/9ssi7/bank/internal/domain/outbox"
+	"github.com/9ssi7/bank/internal/domain/schedule"
+	"github.com/9ssi7/bank/internal/domain/user"
+	"github.com/9ssi7/bank/pkg/cron"
+	"github.com/9ssi7/bank/pkg/list"
+	"github.com/9ssi7/bank/pkg/money"
+	"github.com/9ssi7/txn"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ScheduledTransferUseCase struct {
+	Repo           schedule.Repo
+	OutboxRepo     outbox.Repo
+	AccountRepo    account.Repo
+	UserRepo       user.Repo
+	AccountUseCase *AccountUseCase
+}
+
+type ScheduledTransferCreateOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	Amount    string
+	ToIban    string
+	ToOwner   string
+	Desc      string
+	Cron      string
+	RunAt     *time.Time
+}
+
+func (u *ScheduledTransferUseCase) Create(ctx context.Context, trc trace.Tracer, opts ScheduledTransferCreateOpts) (*uuid.UUID, error) {
+	ctx, span := trc.Start(ctx, "ScheduledTransferUseCase.Create")
+	defer span.End()
+	acc, err := u.findAccount(ctx, trc, opts.UserId, opts.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := u.parseAmount(opts.Amount, acc.Currency)
+	if err != nil {
+		return nil, err
+	}
+	next, err := firstRun(opts.Cron, opts.RunAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	t := schedule.New(schedule.Config{
+		UserId:      opts.UserId,
+		AccountId:   opts.AccountId,
+		Amount:      amount.Amount,
+		ToIban:      opts.ToIban,
+		ToOwner:     opts.ToOwner,
+		Description: opts.Desc,
+		Cron:        opts.Cron,
+		NextRunAt:   next,
+	})
+	if err := u.Repo.Save(ctx, trc, schedule.SaveOpts{Transfer: t}); err != nil {
+		return nil, err
+	}
+	return &t.ID, nil
+}
+
+type ScheduledTransferUpdateOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	ID        uuid.UUID
+	Amount    string
+	ToIban    string
+	ToOwner   string
+	Desc      string
+	Cron      string
+	RunAt     *time.Time
+}
+
+func (u *ScheduledTransferUseCase) Update(ctx context.Context, trc trace.Tracer, opts ScheduledTransferUpdateOpts) error {
+	ctx, span := trc.Start(ctx, "ScheduledTransferUseCase.Update")
+	defer span.End()
+	acc, err := u.findAccount(ctx, trc, opts.UserId, opts.AccountId)
+	if err != nil {
+		return err
+	}
+	amount, err := u.parseAmount(opts.Amount, acc.Currency)
+	if err != nil {
+		return err
+	}
+	next, err := firstRun(opts.Cron, opts.RunAt, time.Now())
+	if err != nil {
+		return err
+	}
+	return u.edit(ctx, trc, schedule.FindByAccountIdAndIdOpts{UserId: opts.UserId, AccountId: opts.AccountId, ID: opts.ID}, func(t *schedule.Transfer) {
+		t.Amount = amount.Amount
+		t.ToIban = opts.ToIban
+		t.ToOwner = opts.ToOwner
+		t.Description = opts.Desc
+		t.Cron = opts.Cron
+		t.NextRunAt = next
+	})
+}
+
+type ScheduledTransferCancelOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	ID        uuid.UUID
+}
+
+func (u *ScheduledTransferUseCase) Cancel(ctx context.Context, trc trace.Tracer, opts ScheduledTransferCancelOpts) error {
+	ctx, span := trc.Start(ctx, "ScheduledTransferUseCase.Cancel")
+	defer span.End()
+	return u.edit(ctx, trc, schedule.FindByAccountIdAndIdOpts{UserId: opts.UserId, AccountId: opts.AccountId, ID: opts.ID}, func(t *schedule.Transfer) {
+		t.Cancel()
+	})
+}
+
+// edit changes an active transfer under its row lock, so it can not interleave with a worker claiming it.
+// A transfer a worker is executing can not be changed until the run is recorded, the run would overwrite it.
+func (u *ScheduledTransferUseCase) edit(ctx context.Context, trc trace.Tracer, find schedule.FindByAccountIdAndIdOpts, change func(t *schedule.Transfer)) error {
+	tx := txn.New()
+	tx.Register(u.Repo.GetTxnAdapter())
+	if err := tx.Begin(ctx); err != nil {
+		return err
+	}
+	onError := func(ctx context.Context, err error) error {
+		tx.Rollback(ctx)
+		return err
+	}
+	t, err := u.Repo.FindByAccountIdAndIdForUpdate(ctx, trc, find)
+	if err != nil {
+		return onError(ctx, err)
+	}
+	if !t.IsActive() {
+		return onError(ctx, schedule.NotActive(errors.New("scheduled transfer is not active")))
+	}
+	if t.IsClaimed(time.Now()) {
+		return onError(ctx, schedule.Running(errors.New("scheduled transfer is running")))
+	}
+	change(t)
+	if err := u.Repo.Save(ctx, trc, schedule.SaveOpts{Transfer: t}); err != nil {
+		return onError(ctx, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return onError(ctx, err)
+	}
+	return nil
+}
+
+type ScheduledTransferGetOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	ID        uuid.UUID
+}
+
+func (u *ScheduledTransferUseCase) Get(ctx context.Context, trc trace.Tracer, opts ScheduledTransferGetOpts) (*schedule.Transfer, error) {
+	ctx, span := trc.Start(ctx, "ScheduledTransferUseCase.Get")
+	defer span.End()
+	return u.Repo.FindByAccountIdAndId(ctx, trc, schedule.FindByAccountIdAndIdOpts{UserId: opts.UserId, AccountId: opts.AccountId, ID: opts.ID})
+}
+
+type ScheduledTransferListOpts struct {
+	UserId    uuid.UUID
+	AccountId uuid.UUID
+	Pagi      list.PagiRequest
+}
+
+func (u *ScheduledTransferUseCase) List(ctx context.Context, trc trace.Tracer, opts ScheduledTransferListOpts) (*list.PagiResponse[*schedule.Transfer], error) {
+	ctx, span := trc.Start(ctx, "ScheduledTransferUseCase.List")
+	defer span.End()
+	return u.Repo.ListByAccountId(ctx, trc, schedule.ListByAccountIdOpts{UserId: opts.UserId, AccountId: opts.AccountId, Pagi: &opts.Pagi})
+}
+
+// scheduleRunClaimFor is how long a worker holds a transfer it executes, past it the transfer can be edited again.
+const scheduleRunClaimFor = 5 * time.Minute
+
+type ScheduledTransferRunDueOpts struct {
+	Limit int
+}
+
+// RunDue executes a batch of due transfers and returns how many of them succeeded.
+// Transfers are claimed and moved to their next run first, so concurrent workers never pick the same one.
+func (u *ScheduledTransferUseCase) RunDue(ctx context.Context, trc trace.Tracer, opts ScheduledTransferRunDueOpts) (int, error) {
+	ctx, span := trc.Start(ctx, "ScheduledTransferUseCase.RunDue")
+	defer span.End()
+	due, err := u.claim(ctx, trc, time.Now(), opts.Limit)
+	if err != nil {
+		return 0, err
+	}
+	succeeded := 0
+	for _, t := range due {
+		if err := u.run(ctx, trc, t); err != nil {
+			span.RecordError(err)
+			continue
+		}
+		succeeded++
+	}
+	return succeeded, nil
+}
+
+func (u *ScheduledTransferUseCase) claim(ctx context.Context, trc trace.Tracer, now time.Time, limit int) ([]*schedule.Transfer, error) {
+	ctx, span := trc.Start(ctx, "ScheduledTransferUseCase.claim")
+	defer span.End()
+	tx := txn.New()
+	tx.Register(u.Repo.GetTxnAdapter())
+	if err := tx.Begin(ctx); err != nil {
+		return nil, err
+	}
+	onError := func(ctx context.Context, err error) ([]*schedule.Transfer, error) {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	due, err := u.Repo.ListDue(ctx, trc, schedule.ListDueOpts{Now: now, Limit: limit})
+	if err != nil {
+		return onError(ctx, err)
+	}
+	for _, t := range due {
+		if err := t.Advance(now); err != nil {
+			return onError(ctx, err)
+		}
+		t.Claim(now.Add(scheduleRunClaimFor))
+		if err := u.Repo.Save(ctx, trc, schedule.SaveOpts{Transfer: t}); err != nil {
+			return onError(ctx, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return onError(ctx, err)
+	}
+	return due, nil
+}
+
+// run executes a claimed transfer and records the outcome, a failure is announced to the owner.
+func (u *ScheduledTransferUseCase) run(ctx context.Context, trc trace.Tracer, t *schedule.Transfer) error {
+	ctx, span := trc.Start(ctx, "ScheduledTransferUseCase.run")
+	defer span.End()
+	usr, runErr := u.UserRepo.FindById(ctx, trc, user.FindByIdOpts{ID: t.UserId})
+	var acc *account.Account
+	if runErr == nil {
+		acc, runErr = u.AccountRepo.FindById(ctx, trc, account.FindByIdOpts{ID: t.AccountId})
+	}
+	if runErr == nil && u.AccountUseCase.requiresStepUp(money.Money{Amount: t.Amount, Currency: acc.Currency}) {
+		// the threshold was lowered after the transfer was scheduled
+		runErr = account.StepUpRequired(errors.New("scheduled transfer above the step-up threshold"))
+	}
+	if runErr == nil {
+		// nobody is there to confirm a run, transfers above the step-up threshold can not be scheduled
+		runErr = u.AccountUseCase.executeTransfer(ctx, trc, AccountTransferMoneyOpts{
+			UserId:    t.UserId,
+			AccountId: t.AccountId,
+			UserEmail: usr.Email,
+			UserName:  usr.Name,
+			Amount:    t.Amount.String(),
+			ToIban:    t.ToIban,
+			ToOwner:   t.ToOwner,
+			Desc:      t.Description,
+		})
+	}
+	tx := txn.New()
+	tx.Register(u.Repo.GetTxnAdapter())
+	tx.Register(u.OutboxRepo.GetTxnAdapter())
+	if err := tx.Begin(ctx); err != nil {
+		return err
+	}
+	onError := func(ctx context.Context, err error) error {
+		tx.Rollback(ctx)
+		return err
+	}
+	now := time.Now()
+	if runErr == nil {
+		t.Succeed(now)
+	} else {
+		t.Fail(now, runErr)
+		if usr != nil && acc != nil {
+			err := enqueue(ctx, trc, u.OutboxRepo, schedule.SubjectTransferFailed, &schedule.EventTransferFailed{
+				Email:       usr.Email,
+				Name:        usr.Name,
+				Amount:      money.Money{Amount: t.Amount, Currency: acc.Currency}.String(),
+				Currency:    acc.Currency,
+				Account:     acc.Name,
+				ToIban:      t.ToIban,
+				ToOwner:     t.ToOwner,
+				Description: t.Description,
+				Reason:      runErr.Error(),
+			})
+			if err != nil {
+				return onError(ctx, err)
+			}
+		}
+	}
+	if err := u.Repo.Save(ctx, trc, schedule.SaveOpts{Transfer: t}); err != nil {
+		return onError(ctx, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return onError(ctx, err)
+	}
+	return runErr
+}
+
+// parseAmount parses the amount of a transfer to schedule. A run has nobody to confirm it,
+// so amounts above the step-up threshold are refused like the lines of a bulk transfer.
+func (u *ScheduledTransferUseCase) parseAmount(amount string, currency string) (*money.Money, error) {
+	m, err := parseAmount(amount, currency)
+	if err != nil {
+		return nil, err
+	}
+	if u.AccountUseCase.requiresStepUp(m) {
+		return nil, account.StepUpRequired(errors.New("scheduled transfer above the step-up threshold"))
+	}
+	return &m, nil
+}
+
+func (u *ScheduledTransferUseCase) findAccount(ctx context.Context, trc trace.Tracer, userId uuid.UUID, accountId uuid.UUID) (*account.Account, error) {
+	acc, err := u.AccountRepo.FindByUserIdAndId(ctx, trc, account.FindByUserIdAndIdOpts{UserId: userId, ID: accountId})
+	if err != nil {
+		return nil, err
+	}
+	if acc.ID == uuid.Nil {
+		return nil, account.NotFound(errors.New("account not found"))
+	}
+	return acc, nil
+}
+
+// firstRun resolves when a transfer runs first. One off transfers run at runAt, recurring ones
+// on the first match of the cron expression from runAt on, or from now when runAt is not given.
+func firstRun(expr string, runAt *time.Time, now time.Time) (time.Time, error) {
+	if runAt != nil && !runAt.After(now) {
+		return time.Time{}, schedule.RunAtInvalid(errors.New("run at must be in the future"))
+	}
+	if expr == "" {
+		if runAt == nil {
+			return time.Time{}, schedule.RunAtInvalid(errors.New("run at is required without a cron expression"))
+		}
+		return *runAt, nil
+	}
+	s, err := cron.Parse(expr)
+	if err != nil {
+		return time.Time{}, schedule.CronInvalid(err)
+	}
+	from := now
+	if runAt != nil {
+		from = runAt.Add(-time.Minute)
+	}
+	next := s.Next(from)
+	if next.IsZero() {
+		return time.Time{}, schedule.CronInvalid(errors.New("cron expression never matches"))
+	}
+	return next, nil
+}
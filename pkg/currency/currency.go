@@ -1,5 +1,11 @@
 package currency
 
+import (
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
 // ISO 4217 currency codes
 // https://en.wikipedia.org/wiki/ISO_4217
 // Entity,Currency,AlphabeticCode,NumericCode,MinorUnit
@@ -280,3 +286,28 @@ func IsValid(code string) bool {
 	}
 	return false
 }
+
+// MinorUnit returns the number of decimal places of the currency.
+// The second value is false for unknown currencies.
+func MinorUnit(code string) (int32, bool) {
+	for _, c := range CurrencyISO {
+		if c.AlphabeticCode == code {
+			unit, err := strconv.ParseInt(c.MinorUnit, 10, 32)
+			if err != nil {
+				return 0, false
+			}
+			return int32(unit), true
+		}
+	}
+	return 0, false
+}
+
+// Round rounds the amount half to even at the minor unit of the currency.
+// Amounts of unknown currencies are returned as is.
+func Round(amount decimal.Decimal, code string) decimal.Decimal {
+	unit, ok := MinorUnit(code)
+	if !ok {
+		return amount
+	}
+	return amount.RoundBank(unit)
+}
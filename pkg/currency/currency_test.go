@@ -1,6 +1,10 @@
 package currency
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
 
 func TestIsValid(t *testing.T) {
 	tests := []struct {
@@ -24,3 +28,49 @@ func TestIsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestMinorUnit(t *testing.T) {
+	tests := []struct {
+		code string
+		unit int32
+		ok   bool
+	}{
+		{"USD", 2, true},
+		{"JPY", 0, true},
+		{"BHD", 3, true},
+		{"CLF", 4, true},
+		{"XYZ", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			unit, ok := MinorUnit(tt.code)
+			if unit != tt.unit || ok != tt.ok {
+				t.Errorf("MinorUnit(%q) = %v, %v, want %v, %v", tt.code, unit, ok, tt.unit, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		amount string
+		code   string
+		want   string
+	}{
+		{"10.125", "USD", "10.12"},
+		{"10.135", "USD", "10.14"},
+		{"1234.5", "JPY", "1234"},
+		{"1.23456", "KWD", "1.235"},
+		{"1.23456", "XYZ", "1.23456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code+" "+tt.amount, func(t *testing.T) {
+			got := Round(decimal.RequireFromString(tt.amount), tt.code)
+			if !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("Round(%s, %q) = %s, want %s", tt.amount, tt.code, got, tt.want)
+			}
+		})
+	}
+}
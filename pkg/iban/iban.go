@@ -1,67 +1,106 @@
 package iban
 
 import (
+	"crypto/rand"
+	"errors"
 	"fmt"
-	"time"
+	"math/big"
+	"strings"
+)
 
-	"math/rand"
+var (
+	ErrUnknownCountry = errors.New("iban: unknown country")
+	ErrBankCode       = errors.New("iban: bank code does not fit the country format")
 )
 
+// Generator creates IBANs of a country for the accounts of a bank.
+type Generator struct {
+	country  Country
+	bankCode string
+}
+
+// NewGenerator returns a generator for the given country whose IBANs carry the bank code.
+func NewGenerator(country string, bankCode string) (*Generator, error) {
+	c, ok := Lookup(country)
+	if !ok {
+		return nil, ErrUnknownCountry
+	}
+	if len(bankCode) != c.Bank || !c.matches(bankCode) {
+		return nil, ErrBankCode
+	}
+	return &Generator{country: c, bankCode: bankCode}, nil
+}
+
+// New returns a random IBAN with valid check digits. Randomness does not make it unique,
+// callers storing IBANs have to check them against the ones in use.
+func (g *Generator) New() string {
+	var b strings.Builder
+	b.WriteString(g.bankCode)
+	i := 0
+	for _, s := range g.country.segments() {
+		for j := 0; j < s.length; j++ {
+			if i >= len(g.bankCode) {
+				b.WriteByte(random(s.kind))
+			}
+			i++
+		}
+	}
+	bban := b.String()
+	return g.country.Code + checkDigits(g.country.Code, bban) + bban
+}
+
+var defaultGenerator = &Generator{country: registry["TR"], bankCode: "00000"}
+
+// New returns a Turkish IBAN of the default generator, use a Generator to choose the country and bank.
 func New() string {
-	return generateIBAN("tr") // add i18n support
+	return defaultGenerator.New()
 }
 
+// Validate reports whether the iban, in its electronic format without spaces, has the length and
+// structure registered for its country and passes the ISO 13616 mod-97 check.
 func Validate(iban string) bool {
 	return validateIBAN(iban)
 }
 
+// Normalize turns the print format of an iban, grouped by spaces and maybe in lower case, into the electronic format.
+func Normalize(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
 func validateIBAN(iban string) bool {
-	if len(iban) != 26 && len(iban) != 27 {
+	if len(iban) < 5 {
 		return false
 	}
-	for _, char := range iban[:2] {
-		if char < 'A' || char > 'Z' {
-			return false
-		}
+	c, ok := Lookup(iban[:2])
+	if !ok || len(iban) != c.Length {
+		return false
 	}
-	for _, char := range iban[2:] {
-		if char < '0' || char > '9' {
-			return false
-		}
+	if iban[2] < '0' || iban[2] > '9' || iban[3] < '0' || iban[3] > '9' {
+		return false
+	}
+	if !c.matches(iban[4:]) {
+		return false
 	}
-	return true
+	return mod97(numeric(iban[4:]+iban[:4])) == 1
 }
 
-func generateIBAN(countryCode string) string {
-	rand.New(rand.NewSource(time.Now().UnixNano()))
-	var ibanFormat string
-	switch countryCode {
-	case "en":
-		ibanFormat = "US%02d%04d%017d"
-	default:
-		ibanFormat = "TR%02d%04d%016d"
-	}
-	iban := fmt.Sprintf(ibanFormat,
-		rand.Intn(100),               // Bank code (random)
-		rand.Intn(10000),             // Branch code (random)
-		rand.Intn(10000000000000000), // Account number (random)
-	)
-	iban += generateCheckDigits(iban)
-	return iban
+// checkDigits computes the two check digits of an iban of the country with the given bban.
+func checkDigits(country string, bban string) string {
+	remainder := mod97(numeric(bban + country + "00"))
+	return fmt.Sprintf("%02d", 98-remainder)
 }
 
-func generateCheckDigits(iban string) string {
-	iban = iban[4:] + iban[:4]
-	numericIBAN := ""
-	for _, char := range iban {
+// numeric replaces every letter by two digits, A being 10 and Z being 35.
+func numeric(s string) string {
+	var b strings.Builder
+	for _, char := range strings.ToUpper(s) {
 		if char >= 'A' && char <= 'Z' {
-			numericIBAN += fmt.Sprintf("%d", int(char-'A')+10)
+			fmt.Fprintf(&b, "%d", int(char-'A')+10)
 		} else {
-			numericIBAN += string(char)
+			b.WriteRune(char)
 		}
 	}
-	remainder := mod97(numericIBAN)
-	return fmt.Sprintf("%02d", 98-remainder)
+	return b.String()
 }
 
 func mod97(number string) int {
@@ -71,3 +110,21 @@ func mod97(number string) int {
 	}
 	return remainder
 }
+
+func random(kind byte) byte {
+	switch kind {
+	case 'a':
+		return 'A' + byte(randomInt(26))
+	default:
+		// alphanumeric positions get digits as well, they read better on paper
+		return '0' + byte(randomInt(10))
+	}
+}
+
+func randomInt(max int64) int64 {
+	n, err := rand.Int(rand.Reader, big.NewInt(max))
+	if err != nil {
+		panic(err)
+	}
+	return n.Int64()
+}
@@ -7,6 +7,7 @@ import (
 func TestIbanGeneration(t *testing.T) {
 	type args struct {
 		countryCode string
+		bankCode    string
 		expectedLen int
 	}
 	tests := []struct {
@@ -16,26 +17,61 @@ func TestIbanGeneration(t *testing.T) {
 		{
 			name: "Test TR IBAN",
 			args: args{
-				countryCode: "tr",
+				countryCode: "TR",
+				bankCode:    "00061",
 				expectedLen: 26,
 			},
 		},
 		{
-			name: "Test EN IBAN",
+			name: "Test DE IBAN",
 			args: args{
-				countryCode: "en",
-				expectedLen: 27,
+				countryCode: "DE",
+				bankCode:    "37040044",
+				expectedLen: 22,
+			},
+		},
+		{
+			name: "Test GB IBAN",
+			args: args{
+				countryCode: "GB",
+				bankCode:    "NWBK",
+				expectedLen: 22,
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			iban := generateIBAN(tt.args.countryCode)
+			g, err := NewGenerator(tt.args.countryCode, tt.args.bankCode)
+			if err != nil {
+				t.Fatalf("NewGenerator() error = %v", err)
+			}
+			iban := g.New()
 			if len(iban) != tt.args.expectedLen {
-				t.Errorf("generateIBAN() = %v, want %v", len(iban), tt.args.expectedLen)
+				t.Errorf("New() = %v, want %v", len(iban), tt.args.expectedLen)
+			}
+			if iban[4:4+len(tt.args.bankCode)] != tt.args.bankCode {
+				t.Errorf("New() = %v, want bank code %v", iban, tt.args.bankCode)
+			}
+			if !Validate(iban) {
+				t.Errorf("New() = %v, not valid", iban)
 			}
 		})
 	}
+	if !Validate(New()) {
+		t.Errorf("New() of the default generator is not valid")
+	}
+}
+
+func TestNewGeneratorErrors(t *testing.T) {
+	if _, err := NewGenerator("XX", "0000"); err != ErrUnknownCountry {
+		t.Errorf("NewGenerator() error = %v, want %v", err, ErrUnknownCountry)
+	}
+	if _, err := NewGenerator("GB", "1234"); err != ErrBankCode {
+		t.Errorf("NewGenerator() error = %v, want %v", err, ErrBankCode)
+	}
+	if _, err := NewGenerator("DE", "123"); err != ErrBankCode {
+		t.Errorf("NewGenerator() error = %v, want %v", err, ErrBankCode)
+	}
 }
 
 func TestIbanValidate(t *testing.T) {
@@ -50,22 +86,64 @@ func TestIbanValidate(t *testing.T) {
 		{
 			name: "Test TR IBAN",
 			args: args{
-				iban:     "TR298813938771869288426798",
+				iban:     "TR330006100519786457841326",
+				expected: true,
+			},
+		},
+		{
+			name: "Test DE IBAN",
+			args: args{
+				iban:     "DE89370400440532013000",
+				expected: true,
+			},
+		},
+		{
+			name: "Test GB IBAN",
+			args: args{
+				iban:     "GB29NWBK60161331926819",
+				expected: true,
+			},
+		},
+		{
+			name: "Test NL IBAN",
+			args: args{
+				iban:     "NL91ABNA0417164300",
 				expected: true,
 			},
 		},
 		{
-			name: "Test EN IBAN",
+			name: "Test Invalid Checksum",
+			args: args{
+				iban:     "TR330006100519786457841327",
+				expected: false,
+			},
+		},
+		{
+			name: "Test Invalid Length",
+			args: args{
+				iban:     "DE8937040044053201300",
+				expected: false,
+			},
+		},
+		{
+			name: "Test Invalid Structure",
+			args: args{
+				iban:     "GB29123460161331926819",
+				expected: false,
+			},
+		},
+		{
+			name: "Test Unknown Country",
 			args: args{
 				iban:     "US021000021",
 				expected: false,
 			},
 		},
 		{
-			name: "Test Invalid IBAN",
+			name: "Test Print Format",
 			args: args{
-				iban:     "TR330006100519786457841327",
-				expected: true,
+				iban:     "DE89 3704 0044 0532 0130 00",
+				expected: false,
 			},
 		},
 	}
@@ -77,3 +155,9 @@ func TestIbanValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize("de89 3704 0044 0532 0130 00"); got != "DE89370400440532013000" {
+		t.Errorf("Normalize() = %v", got)
+	}
+}
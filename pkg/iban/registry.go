@@ -0,0 +1,96 @@
+package iban
+
+// Country describes the IBAN format of a country as published in the SWIFT IBAN registry.
+// BBAN is the structure of the basic bank account number in registry notation, e.g. "5n1n16c"
+// for five digits, a digit and sixteen alphanumerics. Bank is the length of the bank identifier
+// the BBAN starts with.
+type Country struct {
+	Code   string
+	Length int
+	BBAN   string
+	Bank   int
+}
+
+var registry = map[string]Country{
+	"AT": {Code: "AT", Length: 20, BBAN: "5n11n", Bank: 5},
+	"BE": {Code: "BE", Length: 16, BBAN: "3n7n2n", Bank: 3},
+	"CH": {Code: "CH", Length: 21, BBAN: "5n12c", Bank: 5},
+	"DE": {Code: "DE", Length: 22, BBAN: "8n10n", Bank: 8},
+	"DK": {Code: "DK", Length: 18, BBAN: "4n9n1n", Bank: 4},
+	"ES": {Code: "ES", Length: 24, BBAN: "4n4n1n1n10n", Bank: 4},
+	"FI": {Code: "FI", Length: 18, BBAN: "3n11n", Bank: 3},
+	"FR": {Code: "FR", Length: 27, BBAN: "5n5n11c2n", Bank: 5},
+	"GB": {Code: "GB", Length: 22, BBAN: "4a6n8n", Bank: 4},
+	"IE": {Code: "IE", Length: 22, BBAN: "4a6n8n", Bank: 4},
+	"IT": {Code: "IT", Length: 27, BBAN: "1a5n5n12c", Bank: 0},
+	"LU": {Code: "LU", Length: 20, BBAN: "3n13c", Bank: 3},
+	"NL": {Code: "NL", Length: 18, BBAN: "4a10n", Bank: 4},
+	"NO": {Code: "NO", Length: 15, BBAN: "4n6n1n", Bank: 4},
+	"PL": {Code: "PL", Length: 28, BBAN: "8n16n", Bank: 8},
+	"PT": {Code: "PT", Length: 25, BBAN: "4n4n11n2n", Bank: 4},
+	"SE": {Code: "SE", Length: 24, BBAN: "3n16n1n", Bank: 3},
+	"TR": {Code: "TR", Length: 26, BBAN: "5n1n16c", Bank: 5},
+}
+
+// Lookup returns the IBAN format of a country by its ISO 3166 alpha-2 code.
+func Lookup(code string) (Country, bool) {
+	c, ok := registry[code]
+	return c, ok
+}
+
+// Register adds or replaces the IBAN format of a country.
+func Register(c Country) {
+	registry[c.Code] = c
+}
+
+// segment is a run of characters of the same kind in a BBAN structure.
+type segment struct {
+	kind   byte
+	length int
+}
+
+func (c Country) segments() []segment {
+	var segs []segment
+	n := 0
+	for i := 0; i < len(c.BBAN); i++ {
+		ch := c.BBAN[i]
+		if ch >= '0' && ch <= '9' {
+			n = n*10 + int(ch-'0')
+			continue
+		}
+		segs = append(segs, segment{kind: ch, length: n})
+		n = 0
+	}
+	return segs
+}
+
+// matches reports whether the bban, or a prefix of it, follows the structure of the country.
+func (c Country) matches(bban string) bool {
+	i := 0
+	for _, s := range c.segments() {
+		for j := 0; j < s.length; j++ {
+			if i == len(bban) {
+				return true
+			}
+			if !s.accepts(bban[i]) {
+				return false
+			}
+			i++
+		}
+	}
+	return i == len(bban)
+}
+
+func (s segment) accepts(ch byte) bool {
+	isDigit := ch >= '0' && ch <= '9'
+	isUpper := ch >= 'A' && ch <= 'Z'
+	switch s.kind {
+	case 'n':
+		return isDigit
+	case 'a':
+		return isUpper
+	case 'c':
+		return isDigit || isUpper || (ch >= 'a' && ch <= 'z')
+	}
+	return false
+}
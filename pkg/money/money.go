@@ -0,0 +1,69 @@
+package money
+
+import (
+	"errors"
+
+	"github.com/9ssi7/bank/pkg/currency"
+	"github.com/shopspring/decimal"
+)
+
+// MaxScale is the largest minor unit of any ISO 4217 currency, amounts are stored with this many decimals.
+const MaxScale = 4
+
+// MaxDigits is the number of integer digits that fit into the amount columns.
+const MaxDigits = 32
+
+var (
+	ErrUnknownCurrency = errors.New("money: unknown currency")
+	ErrScale           = errors.New("money: amount has more decimals than the currency allows")
+	ErrNegative        = errors.New("money: amount is negative")
+	ErrOverflow        = errors.New("money: amount is too large")
+)
+
+var limit = decimal.New(1, MaxDigits)
+
+// Money is an amount in a currency, never holding more decimals than the minor unit of the currency.
+type Money struct {
+	Amount   decimal.Decimal
+	Currency string
+}
+
+// New validates the amount against the minor unit of the currency.
+func New(amount decimal.Decimal, code string) (Money, error) {
+	unit, ok := currency.MinorUnit(code)
+	if !ok {
+		return Money{}, ErrUnknownCurrency
+	}
+	if amount.IsNegative() {
+		return Money{}, ErrNegative
+	}
+	if !amount.Equal(amount.Truncate(unit)) {
+		return Money{}, ErrScale
+	}
+	if amount.GreaterThanOrEqual(limit) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: amount, Currency: code}, nil
+}
+
+// Parse reads a decimal string like "100.50" as an amount of the currency.
+func Parse(amount string, code string) (Money, error) {
+	d, err := decimal.NewFromString(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(d, code)
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount.IsPositive()
+}
+
+// String formats the amount with exactly the minor unit of the currency, "100.5" in USD becomes "100.50".
+func (m Money) String() string {
+	unit, ok := currency.MinorUnit(m.Currency)
+	if !ok {
+		return m.Amount.String()
+	}
+	return m.Amount.StringFixed(unit)
+}
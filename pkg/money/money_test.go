@@ -0,0 +1,67 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount string
+		code   string
+		err    error
+	}{
+		{"100.50", "USD", nil},
+		{"100.00", "JPY", nil},
+		{"100", "JPY", nil},
+		{"1.234", "KWD", nil},
+		{"1.2345", "CLF", nil},
+		{"0", "EUR", nil},
+		{"100.505", "USD", ErrScale},
+		{"100.5", "JPY", ErrScale},
+		{"1.23456", "CLF", ErrScale},
+		{"-10", "USD", ErrNegative},
+		{"100", "XYZ", ErrUnknownCurrency},
+		{"100000000000000000000000000000000", "USD", ErrOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code+" "+tt.amount, func(t *testing.T) {
+			m, err := Parse(tt.amount, tt.code)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Parse(%q, %q) error = %v, want %v", tt.amount, tt.code, err, tt.err)
+			}
+			if err == nil && m.Currency != tt.code {
+				t.Errorf("Parse(%q, %q) currency = %q", tt.amount, tt.code, m.Currency)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse("abc", "USD"); err == nil {
+		t.Error("Parse(\"abc\") expected an error")
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		amount string
+		code   string
+		want   string
+	}{
+		{"100.5", "USD", "100.50"},
+		{"100", "JPY", "100"},
+		{"1.2", "KWD", "1.200"},
+	}
+
+	for _, tt := range tests {
+		m, err := Parse(tt.amount, tt.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.String(); got != tt.want {
+			t.Errorf("Money{%s %s}.String() = %q, want %q", tt.amount, tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package pain
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSVColumns is the header of the csv equivalent of a pain.001 file, end_to_end_id may be left empty.
+var CSVColumns = []string{"end_to_end_id", "debtor_iban", "creditor_iban", "creditor_name", "amount", "currency", "remittance"}
+
+// ParseCSV reads the csv equivalent of a pain.001 file, a line without an end to end id is named after its line number.
+func ParseCSV(r io.Reader, msgId string) (*Batch, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(CSVColumns)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range CSVColumns {
+		if strings.TrimSpace(header[i]) != c {
+			return nil, errors.New("pain: csv header must be " + strings.Join(CSVColumns, ","))
+		}
+	}
+	batch := &Batch{MsgId: msgId}
+	for line := 2; ; line++ {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		p := Payment{
+			EndToEndId:   rec[0],
+			DebtorIban:   rec[1],
+			CreditorIban: rec[2],
+			CreditorName: rec[3],
+			Amount:       rec[4],
+			Currency:     rec[5],
+			Remittance:   rec[6],
+		}
+		if p.EndToEndId == "" {
+			p.EndToEndId = "LINE-" + strconv.Itoa(line)
+		}
+		batch.Payments = append(batch.Payments, p)
+	}
+	return batch, nil
+}
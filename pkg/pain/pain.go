@@ -0,0 +1,18 @@
+package pain
+
+// Payment is a single credit transfer of a bulk payment file.
+type Payment struct {
+	EndToEndId   string
+	DebtorIban   string
+	CreditorIban string
+	CreditorName string
+	Amount       string
+	Currency     string
+	Remittance   string
+}
+
+// Batch is a parsed bulk payment file.
+type Batch struct {
+	MsgId    string
+	Payments []Payment
+}
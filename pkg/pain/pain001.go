@@ -0,0 +1,58 @@
+package pain
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type pain001Document struct {
+	XMLName xml.Name `xml:"Document"`
+	MsgId   string   `xml:"CstmrCdtTrfInitn>GrpHdr>MsgId"`
+	NbOfTxs string   `xml:"CstmrCdtTrfInitn>GrpHdr>NbOfTxs"`
+	PmtInfs []struct {
+		DbtrIban     string `xml:"DbtrAcct>Id>IBAN"`
+		CdtTrfTxInfs []struct {
+			EndToEndId string `xml:"PmtId>EndToEndId"`
+			Amt        struct {
+				Ccy   string `xml:"Ccy,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"Amt>InstdAmt"`
+			CdtrNm   string `xml:"Cdtr>Nm"`
+			CdtrIban string `xml:"CdtrAcct>Id>IBAN"`
+			Ustrd    string `xml:"RmtInf>Ustrd"`
+		} `xml:"CdtTrfTxInf"`
+	} `xml:"CstmrCdtTrfInitn>PmtInf"`
+}
+
+// ParsePain001 reads a customer credit transfer initiation (pain.001), any version using the
+// common GrpHdr, PmtInf and CdtTrfTxInf layout. The number of transactions has to match the header.
+func ParsePain001(r io.Reader) (*Batch, error) {
+	var doc pain001Document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	batch := &Batch{MsgId: doc.MsgId}
+	for _, p := range doc.PmtInfs {
+		for _, tx := range p.CdtTrfTxInfs {
+			batch.Payments = append(batch.Payments, Payment{
+				EndToEndId:   strings.TrimSpace(tx.EndToEndId),
+				DebtorIban:   strings.TrimSpace(p.DbtrIban),
+				CreditorIban: strings.TrimSpace(tx.CdtrIban),
+				CreditorName: strings.TrimSpace(tx.CdtrNm),
+				Amount:       strings.TrimSpace(tx.Amt.Value),
+				Currency:     strings.TrimSpace(tx.Amt.Ccy),
+				Remittance:   strings.TrimSpace(tx.Ustrd),
+			})
+		}
+	}
+	if doc.NbOfTxs != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(doc.NbOfTxs))
+		if err != nil || n != len(batch.Payments) {
+			return nil, errors.New("pain: NbOfTxs does not match the number of transactions")
+		}
+	}
+	return batch, nil
+}
@@ -0,0 +1,85 @@
+package pain
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const pain002Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.002.001.03"
+
+// Transaction statuses of a payment status report.
+const (
+	StatusAccepted = "ACSC" // settlement completed
+	StatusRejected = "RJCT"
+)
+
+// Group statuses tell whether all, some or none of the transactions were accepted.
+const (
+	GroupAccepted          = "ACSC"
+	GroupPartiallyAccepted = "PART"
+	GroupRejected          = "RJCT"
+)
+
+// Status is the outcome of a single payment, Reason explains a rejection.
+type Status struct {
+	EndToEndId string
+	Status     string
+	Reason     string
+}
+
+type pain002Document struct {
+	XMLName     xml.Name    `xml:"Document"`
+	Xmlns       string      `xml:"xmlns,attr"`
+	MsgId       string      `xml:"CstmrPmtStsRpt>GrpHdr>MsgId"`
+	CreDtTm     string      `xml:"CstmrPmtStsRpt>GrpHdr>CreDtTm"`
+	OrgnlMsg    string      `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts>OrgnlMsgId"`
+	OrgnlNm     string      `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts>OrgnlMsgNmId"`
+	OrgnlTxs    int         `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts>OrgnlNbOfTxs"`
+	GrpSts      string      `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts>GrpSts"`
+	TxInfAndSts []pain002Tx `xml:"CstmrPmtStsRpt>OrgnlPmtInfAndSts>TxInfAndSts"`
+}
+
+type pain002Tx struct {
+	OrgnlEndToEndId string `xml:"OrgnlEndToEndId"`
+	TxSts           string `xml:"TxSts"`
+	AddtlInf        string `xml:"StsRsnInf>AddtlInf,omitempty"`
+}
+
+// WritePain002 writes a customer payment status report for the given batch.
+func WritePain002(w io.Writer, msgId string, original *Batch, statuses []Status) error {
+	doc := pain002Document{
+		Xmlns:    pain002Namespace,
+		MsgId:    msgId,
+		CreDtTm:  time.Now().UTC().Format(time.RFC3339),
+		OrgnlMsg: original.MsgId,
+		OrgnlNm:  "pain.001.001.03",
+		OrgnlTxs: len(original.Payments),
+		GrpSts:   groupStatus(statuses),
+	}
+	for _, s := range statuses {
+		doc.TxInfAndSts = append(doc.TxInfAndSts, pain002Tx{OrgnlEndToEndId: s.EndToEndId, TxSts: s.Status, AddtlInf: s.Reason})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+func groupStatus(statuses []Status) string {
+	accepted := 0
+	for _, s := range statuses {
+		if s.Status == StatusAccepted {
+			accepted++
+		}
+	}
+	switch {
+	case accepted == 0:
+		return GroupRejected
+	case accepted == len(statuses):
+		return GroupAccepted
+	}
+	return GroupPartiallyAccepted
+}
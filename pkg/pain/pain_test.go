@@ -0,0 +1,119 @@
+package pain
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const testPain001 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.03">
+  <CstmrCdtTrfInitn>
+    <GrpHdr><MsgId>MSG-1</MsgId><NbOfTxs>2</NbOfTxs></GrpHdr>
+    <PmtInf>
+      <DbtrAcct><Id><IBAN>TR000000000000000000000001</IBAN></Id></DbtrAcct>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-1</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="TRY">10.50</InstdAmt></Amt>
+        <Cdtr><Nm>Jane Doe</Nm></Cdtr>
+        <CdtrAcct><Id><IBAN>TR000000000000000000000002</IBAN></Id></CdtrAcct>
+        <RmtInf><Ustrd>Rent</Ustrd></RmtInf>
+      </CdtTrfTxInf>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-2</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="TRY">3</InstdAmt></Amt>
+        <Cdtr><Nm>John Doe</Nm></Cdtr>
+        <CdtrAcct><Id><IBAN>TR000000000000000000000003</IBAN></Id></CdtrAcct>
+      </CdtTrfTxInf>
+    </PmtInf>
+  </CstmrCdtTrfInitn>
+</Document>`
+
+func TestParsePain001(t *testing.T) {
+	b, err := ParsePain001(strings.NewReader(testPain001))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.MsgId != "MSG-1" || len(b.Payments) != 2 {
+		t.Fatalf("got %+v", b)
+	}
+	want := Payment{
+		EndToEndId:   "E2E-1",
+		DebtorIban:   "TR000000000000000000000001",
+		CreditorIban: "TR000000000000000000000002",
+		CreditorName: "Jane Doe",
+		Amount:       "10.50",
+		Currency:     "TRY",
+		Remittance:   "Rent",
+	}
+	if b.Payments[0] != want {
+		t.Errorf("got %+v, want %+v", b.Payments[0], want)
+	}
+	if b.Payments[1].DebtorIban != want.DebtorIban {
+		t.Errorf("debtor iban not inherited from payment information: %+v", b.Payments[1])
+	}
+}
+
+func TestParsePain001CountMismatch(t *testing.T) {
+	doc := strings.Replace(testPain001, "<NbOfTxs>2</NbOfTxs>", "<NbOfTxs>3</NbOfTxs>", 1)
+	if _, err := ParsePain001(strings.NewReader(doc)); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	in := "end_to_end_id,debtor_iban,creditor_iban,creditor_name,amount,currency,remittance\n" +
+		"E2E-1,TR000000000000000000000001,TR000000000000000000000002,Jane Doe,10.50,TRY,Rent\n" +
+		",TR000000000000000000000001,TR000000000000000000000003,John Doe,3,TRY,\n"
+	b, err := ParseCSV(strings.NewReader(in), "MSG-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Payments) != 2 {
+		t.Fatalf("got %d payments, want 2", len(b.Payments))
+	}
+	if b.Payments[0].Amount != "10.50" || b.Payments[0].CreditorName != "Jane Doe" {
+		t.Errorf("got %+v", b.Payments[0])
+	}
+	if b.Payments[1].EndToEndId != "LINE-3" {
+		t.Errorf("got end to end id %q, want LINE-3", b.Payments[1].EndToEndId)
+	}
+}
+
+func TestParseCSVHeader(t *testing.T) {
+	if _, err := ParseCSV(strings.NewReader("a,b,c,d,e,f,g\n"), "MSG-1"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestWritePain002(t *testing.T) {
+	cases := []struct {
+		statuses []Status
+		want     string
+	}{
+		{[]Status{{"E2E-1", StatusAccepted, ""}, {"E2E-2", StatusAccepted, ""}}, GroupAccepted},
+		{[]Status{{"E2E-1", StatusAccepted, ""}, {"E2E-2", StatusRejected, "insufficient_balance"}}, GroupPartiallyAccepted},
+		{[]Status{{"E2E-1", StatusRejected, "batch_rejected"}, {"E2E-2", StatusRejected, "insufficient_balance"}}, GroupRejected},
+	}
+	batch := &Batch{MsgId: "MSG-1", Payments: make([]Payment, 2)}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := WritePain002(&buf, "RPT-1", batch, c.statuses); err != nil {
+			t.Fatal(err)
+		}
+		var doc pain002Document
+		if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("invalid xml: %v", err)
+		}
+		if doc.GrpSts != c.want {
+			t.Errorf("got group status %s, want %s", doc.GrpSts, c.want)
+		}
+		if doc.OrgnlMsg != "MSG-1" || len(doc.TxInfAndSts) != 2 {
+			t.Errorf("got %+v", doc)
+		}
+		if c.statuses[1].Reason != "" && doc.TxInfAndSts[1].AddtlInf != c.statuses[1].Reason {
+			t.Errorf("got reason %q, want %q", doc.TxInfAndSts[1].AddtlInf, c.statuses[1].Reason)
+		}
+	}
+}
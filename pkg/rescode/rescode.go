@@ -79,3 +79,8 @@ func (r *RC) Error() string {
 func (r *RC) OriginalError() error {
 	return r.err
 }
+
+// Unwrap returns the original error, so errors.Is and errors.As can look through the RC.
+func (r *RC) Unwrap() error {
+	return r.err
+}
@@ -146,3 +146,11 @@ func TestRC_Error(t *testing.T) {
 		})
 	}
 }
+
+func TestRC_Unwrap(t *testing.T) {
+	original := errors.New("underlying error")
+	rc := New(1, 500, codes.Internal, "message")(original)
+	if !errors.Is(rc, original) {
+		t.Errorf("errors.Is() = false, want true")
+	}
+}
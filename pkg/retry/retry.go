@@ -20,6 +20,10 @@ type Config struct {
 
 	// Logger is the logger to use
 	Logger func(log string)
+
+	// RetryIf reports whether the error is worth retrying.
+	// When it is nil every error is retried
+	RetryIf func(err error) bool
 }
 
 // DefaultConfig is the default configuration for the retry package
@@ -41,6 +45,9 @@ func Run(fn RetryFunc, cfg Config) error {
 		if err == nil {
 			break
 		}
+		if cfg.RetryIf != nil && !cfg.RetryIf(err) {
+			return err
+		}
 		cfg.MaxRetries--
 		if cfg.MaxRetries == 0 {
 			return err
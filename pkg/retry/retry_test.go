@@ -74,3 +74,23 @@ func TestRun(t *testing.T) {
 		})
 	}
 }
+
+func TestRunRetryIf(t *testing.T) {
+	retry.DefaultConfig.WaitTime = 1
+	permanent := errors.New("permanent error")
+	calls := 0
+	err := retry.Run(func() error {
+		calls++
+		return permanent
+	}, retry.Config{
+		RetryIf: func(err error) bool {
+			return !errors.Is(err, permanent)
+		},
+	})
+	if !errors.Is(err, permanent) {
+		t.Errorf("Run() error = %v, want %v", err, permanent)
+	}
+	if calls != 1 {
+		t.Errorf("Run() calls = %d, want 1", calls)
+	}
+}
@@ -0,0 +1,72 @@
+// Package seal encrypts small secrets at rest with AES-256-GCM under a key encryption key.
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrKeySize is returned for a key encryption key that is not 32 bytes.
+var ErrKeySize = errors.New("a 32 byte key encryption key is required")
+
+// ErrOpen is returned when a sealed value can not be opened, it was sealed under another key,
+// another associated data or was changed since.
+var ErrOpen = errors.New("sealed value can not be opened with the key encryption key")
+
+// Sealer seals and opens values under one key encryption key.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// New returns a sealer for the 32 byte key encryption key.
+func New(kek []byte) (*Sealer, error) {
+	if len(kek) != 32 {
+		return nil, ErrKeySize
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// ReadKeyFile reads a key encryption key kept base64 encoded in the file.
+func ReadKeyFile(name string) ([]byte, error) {
+	f, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(f)))
+}
+
+// Seal returns the nonce followed by the sealed value. The associated data is not stored but
+// authenticated along with it, the value only opens with the same associated data.
+func (s *Sealer) Seal(plain, aad []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plain, aad), nil
+}
+
+// Open opens a value Seal returned for the same associated data.
+func (s *Sealer) Open(sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < s.aead.NonceSize() {
+		return nil, ErrOpen
+	}
+	nonce, sealed := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plain, err := s.aead.Open(nil, nonce, sealed, aad)
+	if err != nil {
+		return nil, ErrOpen
+	}
+	return plain, nil
+}
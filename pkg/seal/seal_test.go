@@ -0,0 +1,58 @@
+package seal
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var testKek = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSealer_Open(t *testing.T) {
+	s, err := New(testKek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := s.Seal([]byte("secret"), []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Error("Seal() kept the value in the clear")
+	}
+	plain, err := s.Open(sealed, []byte("aad"))
+	if err != nil || string(plain) != "secret" {
+		t.Errorf("Open() = %q, %v, want %q", plain, err, "secret")
+	}
+
+	other, err := New([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 1
+	tests := []struct {
+		name   string
+		sealer *Sealer
+		sealed []byte
+		aad    string
+	}{
+		{"another key", other, sealed, "aad"},
+		{"another associated data", s, sealed, "other"},
+		{"changed", s, tampered, "aad"},
+		{"too short", s, sealed[:4], "aad"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.sealer.Open(tt.sealed, []byte(tt.aad)); !errors.Is(err, ErrOpen) {
+				t.Errorf("Open() = %v, want %v", err, ErrOpen)
+			}
+		})
+	}
+}
+
+func TestNew_KeySize(t *testing.T) {
+	if _, err := New([]byte("short")); !errors.Is(err, ErrKeySize) {
+		t.Errorf("New() with a short key = %v, want %v", err, ErrKeySize)
+	}
+}
@@ -0,0 +1,134 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+type camtAmount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+type camtBalance struct {
+	XMLName   xml.Name   `xml:"Bal"`
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Dt        string     `xml:"Dt>Dt"`
+}
+
+type camtEntry struct {
+	XMLName    xml.Name   `xml:"Ntry"`
+	NtryRef    string     `xml:"NtryRef"`
+	Amt        camtAmount `xml:"Amt"`
+	CdtDbtInd  string     `xml:"CdtDbtInd"`
+	Sts        string     `xml:"Sts"`
+	BookgDt    string     `xml:"BookgDt>DtTm"`
+	ValDt      string     `xml:"ValDt>DtTm"`
+	BkTxCd     string     `xml:"BkTxCd>Prtry>Cd"`
+	EndToEndId string     `xml:"NtryDtls>TxDtls>Refs>EndToEndId"`
+	Ustrd      string     `xml:"NtryDtls>TxDtls>RmtInf>Ustrd,omitempty"`
+}
+
+// camt053Writer writes an ISO 20022 bank to customer statement, version camt.053.001.02.
+type camt053Writer struct {
+	enc *xml.Encoder
+}
+
+func newCAMT053(w io.Writer) *camt053Writer {
+	return &camt053Writer{enc: xml.NewEncoder(w)}
+}
+
+func (c *camt053Writer) Header(h Header) error {
+	if err := c.enc.EncodeToken(xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)}); err != nil {
+		return err
+	}
+	doc := xml.StartElement{Name: xml.Name{Local: "Document"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: camt053Namespace}}}
+	if err := c.enc.EncodeToken(doc); err != nil {
+		return err
+	}
+	if err := start(c.enc, "BkToCstmrStmt"); err != nil {
+		return err
+	}
+	created := h.CreatedAt.UTC().Format(time.RFC3339)
+	grpHdr := struct {
+		MsgId   string `xml:"MsgId"`
+		CreDtTm string `xml:"CreDtTm"`
+	}{h.ID, created}
+	if err := c.enc.EncodeElement(grpHdr, xml.StartElement{Name: xml.Name{Local: "GrpHdr"}}); err != nil {
+		return err
+	}
+	if err := start(c.enc, "Stmt"); err != nil {
+		return err
+	}
+	if err := c.enc.EncodeElement(h.ID, xml.StartElement{Name: xml.Name{Local: "Id"}}); err != nil {
+		return err
+	}
+	if err := c.enc.EncodeElement(created, xml.StartElement{Name: xml.Name{Local: "CreDtTm"}}); err != nil {
+		return err
+	}
+	frToDt := struct {
+		FrDtTm string `xml:"FrDtTm"`
+		ToDtTm string `xml:"ToDtTm"`
+	}{h.From.UTC().Format(time.RFC3339), h.To.UTC().Format(time.RFC3339)}
+	if err := c.enc.EncodeElement(frToDt, xml.StartElement{Name: xml.Name{Local: "FrToDt"}}); err != nil {
+		return err
+	}
+	acct := struct {
+		Iban  string `xml:"Id>IBAN"`
+		Ccy   string `xml:"Ccy"`
+		Owner string `xml:"Ownr>Nm"`
+	}{h.Iban, h.Currency, h.Owner}
+	if err := c.enc.EncodeElement(acct, xml.StartElement{Name: xml.Name{Local: "Acct"}}); err != nil {
+		return err
+	}
+	if err := c.enc.Encode(balance("OPBD", h.Opening, h.Currency, h.From)); err != nil {
+		return err
+	}
+	// the last day of the range is the one before the exclusive end
+	return c.enc.Encode(balance("CLBD", h.Closing, h.Currency, h.To.Add(-time.Nanosecond)))
+}
+
+func (c *camt053Writer) Line(l Line) error {
+	booked := l.BookedAt.UTC().Format(time.RFC3339)
+	return c.enc.Encode(camtEntry{
+		NtryRef:    l.ID,
+		Amt:        camtAmount{Ccy: l.Currency, Value: formatAmount(l.Amount.Abs(), l.Currency)},
+		CdtDbtInd:  indicator(l.Amount),
+		Sts:        "BOOK",
+		BookgDt:    booked,
+		ValDt:      booked,
+		BkTxCd:     l.Kind,
+		EndToEndId: l.ID,
+		Ustrd:      l.Description,
+	})
+}
+
+func (c *camt053Writer) Close() error {
+	if err := end(c.enc, "Stmt", "BkToCstmrStmt", "Document"); err != nil {
+		return err
+	}
+	return c.enc.Flush()
+}
+
+func balance(code string, amount decimal.Decimal, currency string, at time.Time) camtBalance {
+	return camtBalance{
+		Code:      code,
+		Amt:       camtAmount{Ccy: currency, Value: formatAmount(amount.Abs(), currency)},
+		CdtDbtInd: indicator(amount),
+		Dt:        at.Format(time.DateOnly),
+	}
+}
+
+func indicator(amount decimal.Decimal) string {
+	if amount.IsNegative() {
+		return "DBIT"
+	}
+	return "CRDT"
+}
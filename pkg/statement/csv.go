@@ -0,0 +1,44 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// csvWriter writes one row per line with the running balance,
+// enclosed by an opening and a closing balance row.
+type csvWriter struct {
+	w       *csv.Writer
+	header  Header
+	balance decimal.Decimal
+}
+
+func newCSV(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Header(h Header) error {
+	c.header = h
+	c.balance = h.Opening
+	if err := c.w.Write([]string{"booked_at", "id", "kind", "description", "counterparty", "amount", "currency", "balance"}); err != nil {
+		return err
+	}
+	return c.w.Write([]string{h.From.Format(time.RFC3339), "", "opening_balance", "", "", "", h.Currency, formatAmount(h.Opening, h.Currency)})
+}
+
+func (c *csvWriter) Line(l Line) error {
+	c.balance = c.balance.Add(l.Amount)
+	return c.w.Write([]string{l.BookedAt.Format(time.RFC3339), l.ID, l.Kind, l.Description, l.Counterparty, formatAmount(l.Amount, l.Currency), l.Currency, formatAmount(c.balance, c.header.Currency)})
+}
+
+func (c *csvWriter) Close() error {
+	h := c.header
+	if err := c.w.Write([]string{h.To.Format(time.RFC3339), "", "closing_balance", "", "", "", h.Currency, formatAmount(h.Closing, h.Currency)}); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,129 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+const ofxTime = "20060102150405"
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+type ofxTransaction struct {
+	XMLName  xml.Name `xml:"STMTTRN"`
+	TrnType  string   `xml:"TRNTYPE"`
+	DtPosted string   `xml:"DTPOSTED"`
+	TrnAmt   string   `xml:"TRNAMT"`
+	FitId    string   `xml:"FITID"`
+	Name     string   `xml:"NAME,omitempty"`
+	Memo     string   `xml:"MEMO,omitempty"`
+}
+
+// ofxWriter writes an OFX 2.2 bank statement response.
+type ofxWriter struct {
+	w      io.Writer
+	enc    *xml.Encoder
+	header Header
+}
+
+func newOFX(w io.Writer) *ofxWriter {
+	return &ofxWriter{w: w, enc: xml.NewEncoder(w)}
+}
+
+func (o *ofxWriter) Header(h Header) error {
+	o.header = h
+	if _, err := io.WriteString(o.w, ofxHeader); err != nil {
+		return err
+	}
+	if err := start(o.enc, "OFX", "BANKMSGSRSV1", "STMTTRNRS"); err != nil {
+		return err
+	}
+	if err := o.enc.EncodeElement(h.ID, xml.StartElement{Name: xml.Name{Local: "TRNUID"}}); err != nil {
+		return err
+	}
+	status := struct {
+		Code     int    `xml:"CODE"`
+		Severity string `xml:"SEVERITY"`
+	}{0, "INFO"}
+	if err := o.enc.EncodeElement(status, xml.StartElement{Name: xml.Name{Local: "STATUS"}}); err != nil {
+		return err
+	}
+	if err := start(o.enc, "STMTRS"); err != nil {
+		return err
+	}
+	if err := o.enc.EncodeElement(h.Currency, xml.StartElement{Name: xml.Name{Local: "CURDEF"}}); err != nil {
+		return err
+	}
+	acct := struct {
+		BankId   string `xml:"BANKID"`
+		AcctId   string `xml:"ACCTID"`
+		AcctType string `xml:"ACCTTYPE"`
+	}{h.BankId, h.Iban, "CHECKING"}
+	if err := o.enc.EncodeElement(acct, xml.StartElement{Name: xml.Name{Local: "BANKACCTFROM"}}); err != nil {
+		return err
+	}
+	if err := start(o.enc, "BANKTRANLIST"); err != nil {
+		return err
+	}
+	if err := o.enc.EncodeElement(h.From.UTC().Format(ofxTime), xml.StartElement{Name: xml.Name{Local: "DTSTART"}}); err != nil {
+		return err
+	}
+	return o.enc.EncodeElement(h.To.UTC().Format(ofxTime), xml.StartElement{Name: xml.Name{Local: "DTEND"}})
+}
+
+func (o *ofxWriter) Line(l Line) error {
+	trnType := "CREDIT"
+	if l.Amount.IsNegative() {
+		trnType = "DEBIT"
+	}
+	if l.Kind == "fee" {
+		trnType = "FEE"
+	}
+	return o.enc.Encode(ofxTransaction{
+		TrnType:  trnType,
+		DtPosted: l.BookedAt.UTC().Format(ofxTime),
+		TrnAmt:   formatAmount(l.Amount, l.Currency),
+		FitId:    l.ID,
+		Name:     l.Counterparty,
+		Memo:     l.Description,
+	})
+}
+
+func (o *ofxWriter) Close() error {
+	h := o.header
+	if err := end(o.enc, "BANKTRANLIST"); err != nil {
+		return err
+	}
+	bal := struct {
+		BalAmt string `xml:"BALAMT"`
+		DtAsOf string `xml:"DTASOF"`
+	}{formatAmount(h.Closing, h.Currency), h.To.UTC().Format(ofxTime)}
+	if err := o.enc.EncodeElement(bal, xml.StartElement{Name: xml.Name{Local: "LEDGERBAL"}}); err != nil {
+		return err
+	}
+	if err := end(o.enc, "STMTRS", "STMTTRNRS", "BANKMSGSRSV1", "OFX"); err != nil {
+		return err
+	}
+	return o.enc.Flush()
+}
+
+// start opens the given elements in order, end closes them in the given order.
+func start(enc *xml.Encoder, names ...string) error {
+	for _, n := range names {
+		if err := enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: n}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func end(enc *xml.Encoder, names ...string) error {
+	for _, n := range names {
+		if err := enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: n}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package statement
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/9ssi7/bank/pkg/currency"
+	"github.com/shopspring/decimal"
+)
+
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatOFX     Format = "ofx"
+	FormatCAMT053 Format = "camt053"
+)
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatOFX:
+		return "application/x-ofx"
+	default:
+		return "application/xml"
+	}
+}
+
+func (f Format) Extension() string {
+	switch f {
+	case FormatCSV:
+		return "csv"
+	case FormatOFX:
+		return "ofx"
+	default:
+		return "xml"
+	}
+}
+
+// Header describes the statement, balances are signed: negative means the account owes the bank.
+// From is inclusive and To is exclusive.
+type Header struct {
+	ID        string
+	BankId    string
+	Iban      string
+	Owner     string
+	Currency  string
+	From      time.Time
+	To        time.Time
+	Opening   decimal.Decimal
+	Closing   decimal.Decimal
+	CreatedAt time.Time
+}
+
+// Line is a booked transaction, Amount is negative for money leaving the account.
+type Line struct {
+	ID           string
+	BookedAt     time.Time
+	Amount       decimal.Decimal
+	Currency     string
+	Kind         string
+	Description  string
+	Counterparty string
+}
+
+// Writer streams a statement: the header once, the lines in booking order, then Close.
+type Writer interface {
+	Header(h Header) error
+	Line(l Line) error
+	Close() error
+}
+
+func New(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSV(w), nil
+	case FormatOFX:
+		return newOFX(w), nil
+	case FormatCAMT053:
+		return newCAMT053(w), nil
+	}
+	return nil, errors.New("statement: unknown format " + string(format))
+}
+
+// formatAmount writes the amount with the minor unit of its currency.
+func formatAmount(amount decimal.Decimal, code string) string {
+	unit, ok := currency.MinorUnit(code)
+	if !ok {
+		return amount.String()
+	}
+	return amount.StringFixed(unit)
+}
@@ -0,0 +1,128 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func testStatement(t *testing.T, format Format) string {
+	t.Helper()
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	w, err := New(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Header(Header{
+		ID:        "stmt-1",
+		Iban:      "TR000000000000000000000000",
+		Owner:     "John & Doe",
+		Currency:  "EUR",
+		From:      from,
+		To:        from.AddDate(0, 1, 0),
+		Opening:   decimal.RequireFromString("100"),
+		Closing:   decimal.RequireFromString("69.5"),
+		CreatedAt: from,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	lines := []Line{
+		{ID: "tx-1", BookedAt: from.Add(time.Hour), Amount: decimal.RequireFromString("-30"), Currency: "EUR", Kind: "transfer", Description: "Rent <January>", Counterparty: "Jane Doe"},
+		{ID: "tx-2", BookedAt: from.Add(time.Hour), Amount: decimal.RequireFromString("-0.5"), Currency: "EUR", Kind: "fee", Description: "Process Fee"},
+	}
+	for _, l := range lines {
+		if err := w.Line(l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestCSV(t *testing.T) {
+	out := testStatement(t, FormatCSV)
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("got %d rows, want 5", len(rows))
+	}
+	if rows[1][2] != "opening_balance" || rows[1][7] != "100.00" {
+		t.Errorf("opening row = %v", rows[1])
+	}
+	if rows[3][7] != "69.50" {
+		t.Errorf("running balance = %s, want 69.50", rows[3][7])
+	}
+	if rows[4][2] != "closing_balance" || rows[4][7] != "69.50" {
+		t.Errorf("closing row = %v", rows[4])
+	}
+}
+
+func TestOFX(t *testing.T) {
+	out := testStatement(t, FormatOFX)
+	if !strings.HasPrefix(out, "<?xml") {
+		t.Fatalf("missing xml declaration")
+	}
+	var doc struct {
+		Transactions []ofxTransaction `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKTRANLIST>STMTTRN"`
+		Balance      string           `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>LEDGERBAL>BALAMT"`
+	}
+	if err := xml.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("invalid xml: %v", err)
+	}
+	if len(doc.Transactions) != 2 {
+		t.Fatalf("got %d transactions, want 2", len(doc.Transactions))
+	}
+	if doc.Transactions[0].TrnType != "DEBIT" || doc.Transactions[0].TrnAmt != "-30.00" || doc.Transactions[0].Memo != "Rent <January>" {
+		t.Errorf("transaction = %+v", doc.Transactions[0])
+	}
+	if doc.Transactions[1].TrnType != "FEE" {
+		t.Errorf("fee type = %s, want FEE", doc.Transactions[1].TrnType)
+	}
+	if doc.Balance != "69.50" {
+		t.Errorf("ledger balance = %s, want 69.50", doc.Balance)
+	}
+}
+
+func TestCAMT053(t *testing.T) {
+	out := testStatement(t, FormatCAMT053)
+	var doc struct {
+		XMLName  xml.Name      `xml:"urn:iso:std:iso:20022:tech:xsd:camt.053.001.02 Document"`
+		Owner    string        `xml:"BkToCstmrStmt>Stmt>Acct>Ownr>Nm"`
+		Balances []camtBalance `xml:"BkToCstmrStmt>Stmt>Bal"`
+		Entries  []camtEntry   `xml:"BkToCstmrStmt>Stmt>Ntry"`
+	}
+	if err := xml.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("invalid xml: %v", err)
+	}
+	if doc.Owner != "John & Doe" {
+		t.Errorf("owner = %q", doc.Owner)
+	}
+	if len(doc.Balances) != 2 || doc.Balances[0].Code != "OPBD" || doc.Balances[1].Code != "CLBD" {
+		t.Fatalf("balances = %+v", doc.Balances)
+	}
+	if doc.Balances[1].Amt.Value != "69.50" || doc.Balances[1].Dt != "2024-01-31" {
+		t.Errorf("closing balance = %+v", doc.Balances[1])
+	}
+	if len(doc.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(doc.Entries))
+	}
+	if doc.Entries[0].CdtDbtInd != "DBIT" || doc.Entries[0].Amt.Value != "30.00" || doc.Entries[0].Amt.Ccy != "EUR" {
+		t.Errorf("entry = %+v", doc.Entries[0])
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := New("pdf", &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
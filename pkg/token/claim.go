@@ -21,6 +21,9 @@ type UserClaim struct {
 	Project   string `json:"project"`
 	IsAccess  bool   `json:"isAccess"`
 	IsRefresh bool   `json:"isRefresh"`
+
+	// Family is carried by refresh tokens, every token rotated out of one login shares it.
+	Family string `json:"family,omitempty"`
 }
 
 func (c *UserClaim) Valid() error {
@@ -11,8 +11,7 @@ import (
 )
 
 type Jwt struct {
-	publicKey  *rsa.PublicKey
-	privateKey *rsa.PrivateKey
+	keys       *Keyset
 	signMethod string
 }
 
@@ -20,19 +19,41 @@ type JwtConfig struct {
 	PublicKey  []byte
 	PrivateKey []byte
 	SignMethod string
+
+	// Store shares the rotated keys between the instances, none keeps them in memory only.
+	Store KeyStore
+
+	// KeyEncryptionKey seals the private keys put in the Store, it is 32 bytes for AES-256.
+	KeyEncryptionKey []byte
+
+	// Logger is told about the stored keys that are skipped because they can not be opened.
+	Logger func(log string)
+
+	// Retain is how long a key verifies after a newer one took over signing, it defaults to RefreshTokenDuration.
+	Retain time.Duration
 }
 
+// NewJwt returns a Jwt signing with the given key pair until the keyset rotates, the pair keeps verifying
+// the tokens signed before kids were put in the headers.
 func NewJwt(config JwtConfig) (*Jwt, error) {
 	if config.SignMethod == "" {
 		config.SignMethod = "RS256"
 	}
+	if config.Retain == 0 {
+		config.Retain = RefreshTokenDuration
+	}
 	priv, pub, err := parseKeys(config)
 	if err != nil {
 		return nil, err
 	}
+	keys, err := NewKeyset(config.Retain, config.Store, config.KeyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	keys.logger = config.Logger
+	keys.AddFallback(priv, pub)
 	return &Jwt{
-		publicKey:  pub,
-		privateKey: priv,
+		keys:       keys,
 		signMethod: config.SignMethod,
 	}, nil
 }
@@ -66,15 +87,16 @@ func parsePublicKey(publicKey []byte) (*rsa.PublicKey, error) {
 }
 
 func (j *Jwt) Sign(p *UserClaim) (string, error) {
-	token := jwt.New(jwt.GetSigningMethod(j.signMethod))
-	token.Claims = p
-	return token.SignedString(j.privateKey)
+	return j.SignWithJWtClaims(p)
 }
 
+// SignWithJWtClaims signs with the signing key of the keyset and names it in the kid header.
 func (j *Jwt) SignWithJWtClaims(p jwt.Claims) (string, error) {
+	key := j.keys.Signing()
 	token := jwt.New(jwt.GetSigningMethod(j.signMethod))
+	token.Header["kid"] = key.Kid
 	token.Claims = p
-	return token.SignedString(j.privateKey)
+	return token.SignedString(key.private)
 }
 
 func (j *Jwt) Parse(ctx context.Context, t string, options ...jwt.ParserOption) (*jwt.Token, error) {
@@ -82,10 +104,16 @@ func (j *Jwt) Parse(ctx context.Context, t string, options ...jwt.ParserOption)
 		if err := j.customLogic(token); err != nil {
 			return nil, err
 		}
-		return j.publicKey, nil
+		kid, _ := token.Header["kid"].(string)
+		return j.keys.Find(ctx, kid)
 	}, options...)
 }
 
+// Keys returns the keyset the tokens are signed and verified with.
+func (j *Jwt) Keys() *Keyset {
+	return j.keys
+}
+
 func (j *Jwt) Verify(ctx context.Context, t string) (bool, error) {
 	token, err := j.Parse(ctx, t)
 	if err != nil {
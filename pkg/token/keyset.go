@@ -0,0 +1,356 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/9ssi7/bank/pkg/seal"
+)
+
+// KeyBits is the size of the rsa keys a rotation generates.
+const KeyBits = 2048
+
+// resyncAfter keeps tokens with made up kids from reaching the store on every call.
+const resyncAfter = 10 * time.Second
+
+// staleAfter is how long an instance signs with what it knows before it looks for a newer key.
+const staleAfter = time.Minute
+
+var ErrUnknownKid = errors.New("unknown kid")
+
+// ErrNoUsableKey is returned by Sync when none of the stored keys could be opened and the keyset has no other key.
+var ErrNoUsableKey = errors.New("no usable key")
+
+// ErrKeyEncryptionKey is returned when the keys are to be stored without a key encryption key of 32 bytes.
+var ErrKeyEncryptionKey = errors.New("a 32 byte key encryption key is required to store the keys")
+
+// Key is one rsa key pair of the keyset, the kid is the RFC 7638 thumbprint of its public key.
+type Key struct {
+	Kid       string
+	CreatedAt time.Time
+
+	private *rsa.PrivateKey
+	public  *rsa.PublicKey
+}
+
+// StoredKey is a key as the KeyStore keeps it, the private key is PKCS #1 PEM sealed with AES-GCM under
+// the key encryption key, the nonce in front. The kid is authenticated along with it.
+type StoredKey struct {
+	Kid        string    `json:"kid"`
+	PrivateKey []byte    `json:"private_key"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// KeyStore shares the rotated keys between the instances, so a token signed by one verifies on all.
+type KeyStore interface {
+	LoadKeys(ctx context.Context) ([]StoredKey, error)
+	SaveKey(ctx context.Context, key StoredKey) error
+	DeleteKey(ctx context.Context, kid string) error
+}
+
+// Keyset signs with its newest key and verifies with all of them. A key stops signing once a newer one is
+// added and is dropped the retention after that, so the retention must outlive the tokens it signed.
+type Keyset struct {
+	mu       sync.RWMutex
+	keys     []*Key
+	fallback *Key
+	store    KeyStore
+	kek      *seal.Sealer
+	retain   time.Duration
+	synced   time.Time
+	logger   func(log string)
+}
+
+// NewKeyset returns a keyset sharing its keys through the store, sealed with the key encryption key.
+// The key encryption key is only needed along with a store.
+func NewKeyset(retain time.Duration, store KeyStore, kek []byte) (*Keyset, error) {
+	k := &Keyset{retain: retain, store: store}
+	if store == nil {
+		return k, nil
+	}
+	if len(kek) != 32 {
+		return nil, ErrKeyEncryptionKey
+	}
+	var err error
+	k.kek, err = seal.New(kek)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// AddFallback adds the key the tokens without a kid are verified with, those were signed before the keyset had kids.
+func (k *Keyset) AddFallback(private *rsa.PrivateKey, public *rsa.PublicKey) *Key {
+	key := newKey(private, public, time.Time{})
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.fallback = key
+	k.add(key)
+	return key
+}
+
+// Signing returns the newest key, which is the one tokens are signed with.
+func (k *Keyset) Signing() *Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if len(k.keys) == 0 {
+		return nil
+	}
+	return k.keys[0]
+}
+
+// Find returns the public key of the kid, an empty kid gives the fallback key.
+// A kid the keyset does not know makes it look into the store once, another instance may have rotated.
+func (k *Keyset) Find(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	if key := k.find(kid); key != nil {
+		return key.public, nil
+	}
+	if kid == "" || k.store == nil || !k.resyncDue() {
+		return nil, ErrUnknownKid
+	}
+	if err := k.Sync(ctx); err != nil {
+		return nil, err
+	}
+	if key := k.find(kid); key != nil {
+		return key.public, nil
+	}
+	return nil, ErrUnknownKid
+}
+
+func (k *Keyset) find(kid string) *Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if kid == "" {
+		return k.fallback
+	}
+	for _, key := range k.keys {
+		if key.Kid == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+func (k *Keyset) resyncDue() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return time.Since(k.synced) > resyncAfter
+}
+
+// Fresh syncs the keyset when it was last synced longer than staleAfter ago, so an instance
+// that does not rotate itself moves on to the key another one rotated in.
+func (k *Keyset) Fresh(ctx context.Context) error {
+	if k.store == nil {
+		return nil
+	}
+	k.mu.RLock()
+	stale := time.Since(k.synced) > staleAfter
+	k.mu.RUnlock()
+	if !stale {
+		return nil
+	}
+	return k.Sync(ctx)
+}
+
+// Sync merges the keys of the store into the keyset. A stored key that can not be opened is logged
+// and skipped, Sync fails only when that leaves the keyset without a key to sign with.
+func (k *Keyset) Sync(ctx context.Context) error {
+	if k.store == nil {
+		return nil
+	}
+	stored, err := k.store.LoadKeys(ctx)
+	if err != nil {
+		return err
+	}
+	keys := make([]*Key, 0, len(stored))
+	var skipped []error
+	for _, s := range stored {
+		key, err := s.parse(k.kek)
+		if err != nil {
+			err = fmt.Errorf("skipping stored key %s: %w", s.Kid, err)
+			k.log(err.Error())
+			skipped = append(skipped, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, key := range keys {
+		k.add(key)
+	}
+	if len(k.keys) == 0 && len(skipped) > 0 {
+		return errors.Join(append([]error{ErrNoUsableKey}, skipped...)...)
+	}
+	k.synced = time.Now()
+	return nil
+}
+
+func (k *Keyset) log(msg string) {
+	if k.logger != nil {
+		k.logger(msg)
+	}
+}
+
+// Rotate generates a new signing key once the newest key of the store is older than every,
+// and drops the keys retired for longer than the retention. The key of the files has no age,
+// it is replaced on the first call.
+func (k *Keyset) Rotate(ctx context.Context, every time.Duration) error {
+	if err := k.Sync(ctx); err != nil {
+		return err
+	}
+	now := time.Now()
+	if newest := k.Signing(); newest != nil && !newest.CreatedAt.IsZero() && now.Sub(newest.CreatedAt) < every {
+		return k.prune(ctx, now)
+	}
+	private, err := rsa.GenerateKey(rand.Reader, KeyBits)
+	if err != nil {
+		return err
+	}
+	key := newKey(private, &private.PublicKey, now)
+	if k.store != nil {
+		stored, err := key.stored(k.kek)
+		if err != nil {
+			return err
+		}
+		if err := k.store.SaveKey(ctx, stored); err != nil {
+			return err
+		}
+	}
+	k.mu.Lock()
+	k.add(key)
+	k.mu.Unlock()
+	return k.prune(ctx, now)
+}
+
+func (k *Keyset) prune(ctx context.Context, now time.Time) error {
+	k.mu.Lock()
+	if len(k.keys) == 0 {
+		k.mu.Unlock()
+		return nil
+	}
+	var dropped []*Key
+	kept := k.keys[:1]
+	for i := 1; i < len(k.keys); i++ {
+		if now.Sub(k.keys[i-1].CreatedAt) > k.retain {
+			dropped = append(dropped, k.keys[i])
+			continue
+		}
+		kept = append(kept, k.keys[i])
+	}
+	k.keys = kept
+	k.mu.Unlock()
+	for _, key := range dropped {
+		if key == k.fallback || k.store == nil {
+			continue
+		}
+		if err := k.store.DeleteKey(ctx, key.Kid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add keeps the keys newest first, ties broken by kid so every instance signs with the same key.
+func (k *Keyset) add(key *Key) {
+	for _, existing := range k.keys {
+		if existing.Kid == key.Kid {
+			return
+		}
+	}
+	k.keys = append(k.keys, key)
+	sort.SliceStable(k.keys, func(i, j int) bool {
+		if k.keys[i].CreatedAt.Equal(k.keys[j].CreatedAt) {
+			return k.keys[i].Kid > k.keys[j].Kid
+		}
+		return k.keys[i].CreatedAt.After(k.keys[j].CreatedAt)
+	})
+}
+
+// JWK is the public part of a key as RFC 7517 puts it.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the public keys the tokens in circulation may be signed with.
+func (k *Keyset) JWKS(alg string) JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	set := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		n, e := encodePublic(key.public)
+		set.Keys = append(set.Keys, JWK{Kty: "RSA", Use: "sig", Alg: alg, Kid: key.Kid, N: n, E: e})
+	}
+	return set
+}
+
+func newKey(private *rsa.PrivateKey, public *rsa.PublicKey, createdAt time.Time) *Key {
+	return &Key{
+		Kid:       thumbprint(public),
+		CreatedAt: createdAt,
+		private:   private,
+		public:    public,
+	}
+}
+
+func (k *Key) stored(kek *seal.Sealer) (StoredKey, error) {
+	plain := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k.private)})
+	sealed, err := kek.Seal(plain, []byte(k.Kid))
+	if err != nil {
+		return StoredKey{}, err
+	}
+	return StoredKey{
+		Kid:        k.Kid,
+		CreatedAt:  k.CreatedAt,
+		PrivateKey: sealed,
+	}, nil
+}
+
+func (s StoredKey) parse(kek *seal.Sealer) (*Key, error) {
+	plain, err := kek.Open(s.PrivateKey, []byte(s.Kid))
+	if err != nil {
+		return nil, err
+	}
+	private, err := parsePrivateKey(plain)
+	if err != nil {
+		return nil, err
+	}
+	key := newKey(private, &private.PublicKey, s.CreatedAt)
+	if key.Kid != s.Kid {
+		return nil, errors.New("stored key does not match its kid")
+	}
+	return key, nil
+}
+
+func encodePublic(public *rsa.PublicKey) (string, string) {
+	n := base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	return n, e
+}
+
+// thumbprint is the RFC 7638 thumbprint of the key, the members in lexicographic order without spaces.
+func thumbprint(public *rsa.PublicKey) string {
+	n, e := encodePublic(public)
+	sum := sha256.Sum256([]byte(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
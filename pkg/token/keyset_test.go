@@ -0,0 +1,266 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+var testKek = []byte("0123456789abcdef0123456789abcdef")
+
+type memoryKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]StoredKey
+}
+
+func newMemoryKeyStore() *memoryKeyStore {
+	return &memoryKeyStore{keys: map[string]StoredKey{}}
+}
+
+func (s *memoryKeyStore) LoadKeys(ctx context.Context) ([]StoredKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]StoredKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (s *memoryKeyStore) SaveKey(ctx context.Context, key StoredKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.Kid] = key
+	return nil
+}
+
+func (s *memoryKeyStore) DeleteKey(ctx context.Context, kid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, kid)
+	return nil
+}
+
+func testClaim() *UserClaim {
+	c := &UserClaim{User: User{ID: uuid.New()}}
+	c.SetExpireIn(time.Hour)
+	return c
+}
+
+func TestJwt_KidHeader(t *testing.T) {
+	j, err := NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := j.Sign(testClaim())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := j.Parse(context.Background(), signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["kid"] != j.Keys().Signing().Kid {
+		t.Errorf("kid = %v, want %v", token.Header["kid"], j.Keys().Signing().Kid)
+	}
+}
+
+func TestJwt_WithoutKid(t *testing.T) {
+	j, err := NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := jwt.NewWithClaims(jwt.SigningMethodRS256, testClaim())
+	signed, err := legacy.SignedString(j.Keys().Signing().private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.VerifyAndParse(context.Background(), signed); err != nil {
+		t.Errorf("token without kid should verify with the key of the files: %v", err)
+	}
+}
+
+func TestKeyset_Rotate(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryKeyStore()
+	a, err := NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey, Store: store, KeyEncryptionKey: testKek})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey, Store: store, KeyEncryptionKey: testKek})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := a.Sign(testClaim())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallback := a.Keys().Signing().Kid
+
+	if err := a.Keys().Rotate(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	rotated := a.Keys().Signing().Kid
+	if rotated == fallback {
+		t.Fatal("Rotate() did not replace the key of the files")
+	}
+	if err := a.Keys().Rotate(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if a.Keys().Signing().Kid != rotated {
+		t.Error("Rotate() replaced a key younger than the interval")
+	}
+	if len(a.Keys().JWKS("RS256").Keys) != 2 {
+		t.Errorf("JWKS() has %d keys, want 2", len(a.Keys().JWKS("RS256").Keys))
+	}
+
+	after, err := a.Sign(testClaim())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.VerifyAndParse(ctx, before); err != nil {
+		t.Errorf("token of the retired key should still verify: %v", err)
+	}
+	if _, err := b.VerifyAndParse(ctx, after); err != nil {
+		t.Errorf("another instance should pick the rotated key from the store: %v", err)
+	}
+}
+
+func TestKeyset_Prune(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryKeyStore()
+	j, err := NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey, Store: store, KeyEncryptionKey: testKek, Retain: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Keys().Rotate(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	first := j.Keys().Signing().Kid
+	time.Sleep(5 * time.Millisecond)
+	if err := j.Keys().Rotate(ctx, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := j.Keys().Rotate(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range j.Keys().JWKS("RS256").Keys {
+		if k.Kid == first {
+			t.Error("Rotate() kept a key retired for longer than the retention")
+		}
+	}
+	if _, ok := store.keys[first]; ok {
+		t.Error("Rotate() kept a pruned key in the store")
+	}
+}
+
+func TestKeyset_Sealed(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryKeyStore()
+	a, err := NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey, Store: store, KeyEncryptionKey: testKek})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Keys().Rotate(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	stored := store.keys[a.Keys().Signing().Kid]
+	if bytes.Contains(stored.PrivateKey, []byte("PRIVATE KEY")) {
+		t.Error("Rotate() stored the private key in the clear")
+	}
+
+	other, err := NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey, Store: store, KeyEncryptionKey: []byte("fedcba9876543210fedcba9876543210")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Keys().Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Keys().Find(ctx, stored.Kid); !errors.Is(err, ErrUnknownKid) {
+		t.Error("Sync() opened a key sealed with another key encryption key")
+	}
+
+	stored.Kid = "swapped"
+	store.keys[stored.Kid] = stored
+	b, err := NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey, Store: store, KeyEncryptionKey: testKek})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Keys().Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Keys().Find(ctx, stored.Kid); !errors.Is(err, ErrUnknownKid) {
+		t.Error("Sync() opened a sealed key stored under another kid")
+	}
+}
+
+func TestKeyset_SyncSkipsCorruptKey(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryKeyStore()
+	a, err := NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey, Store: store, KeyEncryptionKey: testKek})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Keys().Rotate(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	good := a.Keys().Signing().Kid
+	store.keys["corrupt"] = StoredKey{Kid: "corrupt", PrivateKey: []byte("not a sealed key"), CreatedAt: time.Now().Add(time.Minute)}
+
+	var logged []string
+	b, err := NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey, Store: store, KeyEncryptionKey: testKek, Logger: func(l string) { logged = append(logged, l) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Keys().Sync(ctx); err != nil {
+		t.Fatalf("Sync() with one corrupt stored key = %v, want nil", err)
+	}
+	if got := b.Keys().Signing().Kid; got != good {
+		t.Errorf("Signing() = %v, want %v", got, good)
+	}
+	if len(logged) != 1 {
+		t.Errorf("Sync() logged %d lines, want 1 for the corrupt key", len(logged))
+	}
+
+	keys, err := NewKeyset(time.Hour, store, testKek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(store.keys, good)
+	if err := keys.Sync(ctx); !errors.Is(err, ErrNoUsableKey) {
+		t.Errorf("Sync() with only a corrupt stored key = %v, want %v", err, ErrNoUsableKey)
+	}
+}
+
+func TestKeyset_KeyEncryptionKeyRequired(t *testing.T) {
+	_, err := NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey, Store: newMemoryKeyStore()})
+	if !errors.Is(err, ErrKeyEncryptionKey) {
+		t.Errorf("NewJwt() with a store and no key encryption key = %v, want %v", err, ErrKeyEncryptionKey)
+	}
+	_, err = NewJwt(JwtConfig{PrivateKey: testPrivateKey, PublicKey: testPublicKey, Store: newMemoryKeyStore(), KeyEncryptionKey: []byte("short")})
+	if !errors.Is(err, ErrKeyEncryptionKey) {
+		t.Errorf("NewJwt() with a short key encryption key = %v, want %v", err, ErrKeyEncryptionKey)
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	// RFC 7638 section 3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+	if got, want := thumbprint(pub), "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("thumbprint() = %v, want %v", got, want)
+	}
+}
@@ -2,8 +2,12 @@ package token
 
 import (
 	"context"
+	"errors"
 	"os"
 	"time"
+
+	"github.com/9ssi7/bank/pkg/seal"
+	"github.com/google/uuid"
 )
 
 type Service struct {
@@ -16,18 +20,52 @@ type Config struct {
 	PrivateKeyFile string
 	Project        string
 	SignMethod     string
+
+	// RotateEvery is the age the signing key is replaced at by Rotate, zero keeps the key of the files.
+	RotateEvery time.Duration
+
+	// Store shares the rotated keys between the instances.
+	Store KeyStore
+
+	// KeyEncryptionKeyFile holds the base64 of the 32 byte key the keys are sealed with in the Store.
+	KeyEncryptionKeyFile string
+
+	// Logger is told about the stored keys the keyset skipped.
+	Logger func(log string)
+
+	// Denylist is where Revoke puts the jti of a token, VerifyAndParse refuses those.
+	Denylist Denylist
+}
+
+// Denylist keeps the ids of the revoked tokens until the tokens would have expired anyway.
+type Denylist interface {
+	Deny(ctx context.Context, jti string, until time.Time) error
+	IsDenied(ctx context.Context, jti string) (bool, error)
 }
 
+var ErrRevoked = errors.New("token revoked")
+
 const (
 	AccessTokenDuration  time.Duration = time.Hour * 24
 	RefreshTokenDuration time.Duration = time.Hour * 24 * 30
 )
 
 func New(cnf Config) (*Service, error) {
+	var kek []byte
+	if cnf.KeyEncryptionKeyFile != "" {
+		var err error
+		kek, err = seal.ReadKeyFile(cnf.KeyEncryptionKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
 	j, err := NewJwt(JwtConfig{
-		PublicKey:  readFile(cnf.PublicKeyFile),
-		PrivateKey: readFile(cnf.PrivateKeyFile),
-		SignMethod: cnf.SignMethod,
+		PublicKey:        readFile(cnf.PublicKeyFile),
+		PrivateKey:       readFile(cnf.PrivateKeyFile),
+		SignMethod:       cnf.SignMethod,
+		Store:            cnf.Store,
+		KeyEncryptionKey: kek,
+		Logger:           cnf.Logger,
 	})
 	if err != nil {
 		return nil, err
@@ -38,6 +76,44 @@ func New(cnf Config) (*Service, error) {
 	}, nil
 }
 
+// Sync loads the keys other instances rotated in, the keyset is otherwise only synced when a kid is unknown.
+func (t *Service) Sync(ctx context.Context) error {
+	return t.jwt.Keys().Sync(ctx)
+}
+
+// Rotate replaces the signing key once it is RotateEvery old, tokens signed by the keys before keep
+// verifying until they expire.
+func (t *Service) Rotate(ctx context.Context) error {
+	if t.cnf.RotateEvery == 0 {
+		return t.Sync(ctx)
+	}
+	return t.jwt.Keys().Rotate(ctx, t.cnf.RotateEvery)
+}
+
+// JWKS returns the public keys for the services verifying the tokens on their own.
+func (t *Service) JWKS() JWKS {
+	return t.jwt.Keys().JWKS(t.jwt.signMethod)
+}
+
+// Revoke denies the token until it expires, tokens signed before they carried a jti can not be revoked.
+func (t *Service) Revoke(ctx context.Context, token string) error {
+	if t.cnf.Denylist == nil {
+		return nil
+	}
+	claims, err := t.Parse(ctx, token)
+	if errors.Is(err, ErrUnknownKid) {
+		// The key was pruned, nothing it signed verifies anymore.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if claims == nil || claims.RegisteredClaims.ID == "" || claims.RegisteredClaims.ExpiresAt == nil {
+		return nil
+	}
+	return t.cnf.Denylist.Deny(ctx, claims.RegisteredClaims.ID, claims.RegisteredClaims.ExpiresAt.Time)
+}
+
 func readFile(name string) []byte {
 	f, err := os.ReadFile(name)
 	if err != nil {
@@ -47,23 +123,34 @@ func readFile(name string) []byte {
 }
 
 func (t *Service) GenerateAccessToken(ctx context.Context, u User) (string, error) {
+	if err := t.jwt.Keys().Fresh(ctx); err != nil {
+		return "", err
+	}
 	claims := &UserClaim{
 		User:      u,
 		Project:   t.cnf.Project,
 		IsAccess:  true,
 		IsRefresh: false,
 	}
+	claims.RegisteredClaims.ID = uuid.NewString()
 	claims.SetExpireIn(AccessTokenDuration)
 	return t.generate(claims)
 }
 
-func (t *Service) GenerateRefreshToken(ctx context.Context, u User) (string, error) {
+// GenerateRefreshToken signs a refresh token of the given family, each token gets an id of its own
+// so that two rotations within the same second never give the same token.
+func (t *Service) GenerateRefreshToken(ctx context.Context, u User, family string) (string, error) {
+	if err := t.jwt.Keys().Fresh(ctx); err != nil {
+		return "", err
+	}
 	claims := &UserClaim{
 		User:      u,
 		Project:   t.cnf.Project,
 		IsAccess:  false,
 		IsRefresh: true,
+		Family:    family,
 	}
+	claims.RegisteredClaims.ID = uuid.NewString()
 	claims.SetExpireIn(RefreshTokenDuration)
 	return t.generate(claims)
 }
@@ -84,6 +171,18 @@ func (t *Service) Verify(ctx context.Context, token string) (bool, error) {
 	return t.jwt.Verify(ctx, token)
 }
 
+// VerifyAndParse also refuses the tokens on the denylist.
 func (t *Service) VerifyAndParse(ctx context.Context, token string) (*UserClaim, error) {
-	return t.jwt.VerifyAndParse(ctx, token)
+	claims, err := t.jwt.VerifyAndParse(ctx, token)
+	if err != nil || claims == nil || t.cnf.Denylist == nil || claims.RegisteredClaims.ID == "" {
+		return claims, err
+	}
+	denied, err := t.cnf.Denylist.IsDenied(ctx, claims.RegisteredClaims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if denied {
+		return nil, ErrRevoked
+	}
+	return claims, nil
 }
@@ -2,8 +2,10 @@ package token
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -81,7 +83,8 @@ func TestToken(t *testing.T) {
 			Name:  "Test User",
 			Email: "test@example.com",
 		}
-		refreshToken, err := service.GenerateRefreshToken(context.Background(), user)
+		family := uuid.NewString()
+		refreshToken, err := service.GenerateRefreshToken(context.Background(), user, family)
 		if err != nil {
 			t.Errorf("GenerateRefreshToken() error = %v", err)
 			return
@@ -89,6 +92,20 @@ func TestToken(t *testing.T) {
 		if refreshToken == "" {
 			t.Fatal("GenerateRefreshToken() returned an empty token")
 		}
+		claims, err := service.Parse(context.Background(), refreshToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claims.Family != family {
+			t.Errorf("GenerateRefreshToken() family = %v, want %v", claims.Family, family)
+		}
+		again, err := service.GenerateRefreshToken(context.Background(), user, family)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again == refreshToken {
+			t.Error("GenerateRefreshToken() gave the same token twice")
+		}
 	})
 
 	t.Run("TokenParse", func(t *testing.T) {
@@ -163,3 +180,49 @@ func TestToken(t *testing.T) {
 		readFile("nonexistent_file.txt")
 	})
 }
+
+type memoryDenylist map[string]time.Time
+
+func (d memoryDenylist) Deny(ctx context.Context, jti string, until time.Time) error {
+	d[jti] = until
+	return nil
+}
+
+func (d memoryDenylist) IsDenied(ctx context.Context, jti string) (bool, error) {
+	_, ok := d[jti]
+	return ok, nil
+}
+
+func TestToken_Revoke(t *testing.T) {
+	if err := os.WriteFile(testConfig.PublicKeyFile, testPublicKey, 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(testConfig.PublicKeyFile)
+	if err := os.WriteFile(testConfig.PrivateKeyFile, testPrivateKey, 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(testConfig.PrivateKeyFile)
+	cnf := testConfig
+	cnf.Denylist = memoryDenylist{}
+	service, err := New(cnf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := service.GenerateAccessToken(context.Background(), User{ID: uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := service.GenerateAccessToken(context.Background(), User{ID: uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Revoke(context.Background(), revoked); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.VerifyAndParse(context.Background(), revoked); !errors.Is(err, ErrRevoked) {
+		t.Errorf("VerifyAndParse() error = %v, want %v", err, ErrRevoked)
+	}
+	if _, err := service.VerifyAndParse(context.Background(), kept); err != nil {
+		t.Errorf("VerifyAndParse() error = %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the sha1 seed of the test vectors of RFC 6238, the codes are the last 6 of their 8 digits.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d) error = %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code() accepted an invalid secret")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	prev, _ := Code(rfcSecret, step-1)
+	if got, ok := Match(rfcSecret, prev, now, 0); !ok || got != step-1 {
+		t.Errorf("Match() = %d, %v, want the previous step", got, ok)
+	}
+	if _, ok := Match(rfcSecret, prev, now, step-1); ok {
+		t.Error("Match() accepted a used code")
+	}
+	old, _ := Code(rfcSecret, step-2)
+	if _, ok := Match(rfcSecret, old, now, 0); ok {
+		t.Error("Match() accepted a code outside the skew")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("GenerateSecret() length = %d, want 32", len(secret))
+	}
+	if _, err := Code(secret, 1); err != nil {
+		t.Errorf("Code() of a generated secret error = %v", err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Bank", "john@doe.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Bank:john@doe.com?") {
+		t.Errorf("URI() = %s", uri)
+	}
+	for _, p := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=Bank", "digits=6", "period=30"} {
+		if !strings.Contains(uri, p) {
+			t.Errorf("URI() = %s, missing %s", uri, p)
+		}
+	}
+}
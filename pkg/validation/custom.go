@@ -5,6 +5,8 @@ import (
 	"regexp"
 
 	"github.com/9ssi7/bank/pkg/currency"
+	"github.com/9ssi7/bank/pkg/iban"
+	"github.com/9ssi7/bank/pkg/money"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
@@ -20,18 +22,35 @@ func validateUUID(field reflect.Value) interface{} {
 	return nil
 }
 
+// validateAmount accepts non negative amounts that fit the widest minor unit,
+// amount=Currency checks the scale against the currency held by the sibling field instead.
 func validateAmount(fl validator.FieldLevel) bool {
 	d, err := decimal.NewFromString(fl.Field().String())
 	if err != nil {
 		return false
 	}
-	return d.GreaterThanOrEqual(decimal.Zero)
+	if field := fl.Param(); field != "" {
+		code := reflect.Indirect(fl.Parent()).FieldByName(field)
+		if !code.IsValid() || code.Kind() != reflect.String {
+			return false
+		}
+		_, err = money.New(d, code.String())
+		return err == nil
+	}
+	if d.IsNegative() || !d.Equal(d.Truncate(money.MaxScale)) {
+		return false
+	}
+	return d.LessThan(decimal.New(1, money.MaxDigits))
 }
 
 func validateCurrency(fl validator.FieldLevel) bool {
 	return currency.IsValid(fl.Field().String())
 }
 
+func validateIban(fl validator.FieldLevel) bool {
+	return iban.Validate(fl.Field().String())
+}
+
 func validateUserName(fl validator.FieldLevel) bool {
 	matched, _ := regexp.MatchString(userNameRegexp, fl.Field().String())
 	return matched
@@ -9,8 +9,9 @@ import (
 )
 
 type testFieldLevel struct {
-	field reflect.Value
-	param string
+	field  reflect.Value
+	parent reflect.Value
+	param  string
 }
 
 // ExtractType implements validator.FieldLevel.
@@ -55,12 +56,12 @@ func (t testFieldLevel) GetTag() string {
 
 // Param implements validator.FieldLevel.
 func (t testFieldLevel) Param() string {
-	panic("unimplemented")
+	return t.param
 }
 
 // Parent implements validator.FieldLevel.
 func (t testFieldLevel) Parent() reflect.Value {
-	panic("unimplemented")
+	return t.parent
 }
 
 // StructFieldName implements validator.FieldLevel.
@@ -106,6 +107,8 @@ func TestValidateAmount(t *testing.T) {
 		{"0", true},
 		{"-10", false},
 		{"abc", false}, // Invalid format
+		{"1.2345", true},
+		{"1.23456", false}, // More decimals than any currency
 	}
 
 	for _, tt := range tests {
@@ -116,6 +119,32 @@ func TestValidateAmount(t *testing.T) {
 	}
 }
 
+func TestValidateAmountWithCurrency(t *testing.T) {
+	type req struct {
+		Amount   string
+		Currency string
+	}
+	tests := []struct {
+		req   req
+		valid bool
+	}{
+		{req{"100.50", "USD"}, true},
+		{req{"100.505", "USD"}, false},
+		{req{"100", "JPY"}, true},
+		{req{"100.5", "JPY"}, false},
+		{req{"1.234", "KWD"}, true},
+		{req{"100", "XYZ"}, false},
+	}
+
+	for _, tt := range tests {
+		parent := reflect.ValueOf(&tt.req)
+		fl := testFieldLevel{field: reflect.ValueOf(tt.req.Amount), parent: parent, param: "Currency"}
+		if validateAmount(fl) != tt.valid {
+			t.Errorf("validateAmount(%q, %q) = %v, want %v", tt.req.Amount, tt.req.Currency, !tt.valid, tt.valid)
+		}
+	}
+}
+
 func TestValidateCurrency(t *testing.T) {
 	tests := []struct {
 		code  string
@@ -135,6 +164,26 @@ func TestValidateCurrency(t *testing.T) {
 	}
 }
 
+func TestValidateIban(t *testing.T) {
+	tests := []struct {
+		iban  string
+		valid bool
+	}{
+		{"TR330006100519786457841326", true},
+		{"DE89370400440532013000", true},
+		{"GB29NWBK60161331926819", true},
+		{"GB29NWBK60161331926818", false}, // Invalid checksum
+		{"US021000021", false},            // Unknown country
+	}
+
+	for _, tt := range tests {
+		fl := makeTestFieldLevel(reflect.ValueOf(tt.iban))
+		if validateIban(fl) != tt.valid {
+			t.Errorf("validateIban(%q) = %v, want %v", tt.iban, !tt.valid, tt.valid)
+		}
+	}
+}
+
 func TestValidateUserName(t *testing.T) {
 	validUsernames := []string{"john_doe", "jane.doe123", "user_42"}
 	invalidUsernames := []string{"", "john doe", "user@example.com", "user*name"}
@@ -30,6 +30,7 @@ func New() *Srv {
 	_ = v.RegisterValidation("phone", validatePhone)
 	_ = v.RegisterValidation("currency", validateCurrency)
 	_ = v.RegisterValidation("amount", validateAmount)
+	_ = v.RegisterValidation("iban", validateIban)
 	return &Srv{validator: v, uni: ut.New(tr.New(), en.New())}
 }
 
@@ -0,0 +1,116 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var errCbor = errors.New("webauthn: malformed cbor")
+
+// maxCborDepth bounds the nesting of decoded items, authenticator data never nests deeply.
+const maxCborDepth = 16
+
+// decodeCbor decodes the first item of b and returns it along with the number of bytes it took.
+// Only what attestation objects and cose keys use is supported: integers, byte and text strings,
+// arrays, maps and the simple values. Integers are int64, map keys are int64 or string.
+func decodeCbor(b []byte) (interface{}, int, error) {
+	return decodeCborItem(b, 0)
+}
+
+func decodeCborItem(b []byte, depth int) (interface{}, int, error) {
+	if depth > maxCborDepth || len(b) == 0 {
+		return nil, 0, errCbor
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	arg, n, err := cborArg(b, info)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, 0, errCbor
+		}
+		return int64(arg), n, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, 0, errCbor
+		}
+		return -1 - int64(arg), n, nil
+	case 2, 3:
+		if arg > uint64(len(b)-n) {
+			return nil, 0, errCbor
+		}
+		end := n + int(arg)
+		if major == 3 {
+			return string(b[n:end]), end, nil
+		}
+		return append([]byte(nil), b[n:end]...), end, nil
+	case 4:
+		if arg > uint64(len(b)) {
+			return nil, 0, errCbor
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			v, m, err := decodeCborItem(b[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, v)
+			n += m
+		}
+		return items, n, nil
+	case 5:
+		if arg > uint64(len(b)) {
+			return nil, 0, errCbor
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			k, kn, err := decodeCborItem(b[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += kn
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, 0, errCbor
+			}
+			v, vn, err := decodeCborItem(b[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += vn
+			m[k] = v
+		}
+		return m, n, nil
+	case 7:
+		switch info {
+		case 20:
+			return false, n, nil
+		case 21:
+			return true, n, nil
+		case 22, 23:
+			return nil, n, nil
+		}
+	}
+	return nil, 0, errCbor
+}
+
+// cborArg reads the argument of the head of an item, indefinite lengths are not supported.
+func cborArg(b []byte, info byte) (uint64, int, error) {
+	switch {
+	case info < 24:
+		return uint64(info), 1, nil
+	case info == 24 && len(b) >= 2:
+		return uint64(b[1]), 2, nil
+	case info == 25 && len(b) >= 3:
+		return uint64(binary.BigEndian.Uint16(b[1:3])), 3, nil
+	case info == 26 && len(b) >= 5:
+		return uint64(binary.BigEndian.Uint32(b[1:5])), 5, nil
+	case info == 27 && len(b) >= 9:
+		return binary.BigEndian.Uint64(b[1:9]), 9, nil
+	}
+	return 0, 0, errCbor
+}
@@ -0,0 +1,323 @@
+// Package webauthn verifies the registration and assertion ceremonies of passkeys for a relying party.
+// Attestation statements are not checked, the relying party asks for none, so only the attested credential
+// data is read from them. Who may register a credential is left to the caller.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// Timeout is how long a ceremony may take, challenges should not outlive it.
+const Timeout = 5 * time.Minute
+
+var (
+	ErrInvalid       = errors.New("webauthn: invalid ceremony")
+	ErrSignCount     = errors.New("webauthn: signature counter went backwards, the credential may be cloned")
+	errAuthDataShort = fmt.Errorf("%w: authenticator data too short", ErrInvalid)
+)
+
+// Config names the relying party, Origins are the exact origins the ceremonies may come from.
+type Config struct {
+	RPID    string
+	RPName  string
+	Origins []string
+}
+
+// URLEncoded is binary data that travels as unpadded base64url, as the json forms of the ceremonies use.
+type URLEncoded []byte
+
+func (u URLEncoded) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(u))
+}
+
+func (u *URLEncoded) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	d, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*u = d
+	return nil
+}
+
+// NewChallenge returns a random challenge for a ceremony.
+func NewChallenge() (URLEncoded, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          URLEncoded `json:"id"`
+	Name        string     `json:"name"`
+	DisplayName string     `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string     `json:"type"`
+	ID   URLEncoded `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are handed to navigator.credentials.create.
+type CreationOptions struct {
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              URLEncoded             `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are handed to navigator.credentials.get, without allowed credentials the
+// authenticator offers the discoverable ones of the relying party.
+type RequestOptions struct {
+	Challenge        URLEncoded             `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout"`
+	UserVerification string                 `json:"userVerification"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+}
+
+// NewCreationOptions asks for a discoverable, user verified credential, excluding the ones the user has.
+func (c *Config) NewCreationOptions(user UserEntity, challenge URLEncoded, exclude [][]byte) *CreationOptions {
+	descs := make([]CredentialDescriptor, 0, len(exclude))
+	for _, id := range exclude {
+		descs = append(descs, CredentialDescriptor{Type: "public-key", ID: id})
+	}
+	return &CreationOptions{
+		RP:        RelyingParty{ID: c.RPID, Name: c.RPName},
+		User:      user,
+		Challenge: challenge,
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:                Timeout.Milliseconds(),
+		ExcludeCredentials:     descs,
+		AuthenticatorSelection: AuthenticatorSelection{ResidentKey: "required", UserVerification: "required"},
+		Attestation:            "none",
+	}
+}
+
+func (c *Config) NewRequestOptions(challenge URLEncoded) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		RPID:             c.RPID,
+		Timeout:          Timeout.Milliseconds(),
+		UserVerification: "required",
+		AllowCredentials: []CredentialDescriptor{},
+	}
+}
+
+// RegistrationResponse is the json form of the credential navigator.credentials.create resolves to.
+type RegistrationResponse struct {
+	ID       string     `json:"id"`
+	RawID    URLEncoded `json:"rawId"`
+	Type     string     `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncoded `json:"clientDataJSON"`
+		AttestationObject URLEncoded `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the json form of the credential navigator.credentials.get resolves to.
+type AssertionResponse struct {
+	ID       string     `json:"id"`
+	RawID    URLEncoded `json:"rawId"`
+	Type     string     `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncoded `json:"clientDataJSON"`
+		AuthenticatorData URLEncoded `json:"authenticatorData"`
+		Signature         URLEncoded `json:"signature"`
+		UserHandle        URLEncoded `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential is what a relying party keeps of a registered credential, PublicKey is cose encoded.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+	AAGUID    []byte
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type authData struct {
+	flags     byte
+	signCount uint32
+	aaguid    []byte
+	credId    []byte
+	publicKey []byte
+}
+
+// VerifyRegistration checks a registration against the challenge it was started with and returns the new credential.
+func (c *Config) VerifyRegistration(challenge []byte, res *RegistrationResponse) (*Credential, error) {
+	if res.Type != "public-key" {
+		return nil, fmt.Errorf("%w: credential type %q", ErrInvalid, res.Type)
+	}
+	if err := c.checkClientData(res.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+	v, _, err := decodeCbor(res.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	att, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: attestation object is not a map", ErrInvalid)
+	}
+	raw, ok := att["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: attestation object without authenticator data", ErrInvalid)
+	}
+	ad, err := c.parseAuthData(raw)
+	if err != nil {
+		return nil, err
+	}
+	if ad.flags&flagAttested == 0 {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrInvalid)
+	}
+	if !bytes.Equal(ad.credId, res.RawID) {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrInvalid)
+	}
+	if _, err := parsePublicKey(ad.publicKey); err != nil {
+		return nil, err
+	}
+	return &Credential{
+		ID:        ad.credId,
+		PublicKey: ad.publicKey,
+		SignCount: ad.signCount,
+		AAGUID:    ad.aaguid,
+	}, nil
+}
+
+// VerifyAssertion checks an assertion of the credential against the challenge it was started with,
+// it returns the signature counter to keep for the credential.
+func (c *Config) VerifyAssertion(challenge []byte, cred *Credential, res *AssertionResponse) (uint32, error) {
+	if res.Type != "public-key" {
+		return 0, fmt.Errorf("%w: credential type %q", ErrInvalid, res.Type)
+	}
+	if !bytes.Equal(cred.ID, res.RawID) {
+		return 0, fmt.Errorf("%w: credential id mismatch", ErrInvalid)
+	}
+	if err := c.checkClientData(res.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	ad, err := c.parseAuthData(res.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	key, err := parsePublicKey(cred.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	hash := sha256.Sum256(res.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), res.Response.AuthenticatorData...), hash[:]...)
+	if !key.verify(signed, res.Response.Signature) {
+		return 0, fmt.Errorf("%w: signature mismatch", ErrInvalid)
+	}
+	// authenticators without a counter always report zero
+	if (ad.signCount != 0 || cred.SignCount != 0) && ad.signCount <= cred.SignCount {
+		return 0, ErrSignCount
+	}
+	return ad.signCount, nil
+}
+
+func (c *Config) checkClientData(raw []byte, typ string, challenge []byte) error {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("%w: client data: %v", ErrInvalid, err)
+	}
+	if cd.Type != typ {
+		return fmt.Errorf("%w: client data type %q", ErrInvalid, cd.Type)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(cd.Challenge, "="))
+	if err != nil || !bytes.Equal(got, challenge) {
+		return fmt.Errorf("%w: challenge mismatch", ErrInvalid)
+	}
+	if cd.CrossOrigin || !slices.Contains(c.Origins, cd.Origin) {
+		return fmt.Errorf("%w: origin %q not allowed", ErrInvalid, cd.Origin)
+	}
+	return nil
+}
+
+// parseAuthData reads the authenticator data and checks it was made for the relying party
+// with the user present and verified.
+func (c *Config) parseAuthData(b []byte) (*authData, error) {
+	if len(b) < 37 {
+		return nil, errAuthDataShort
+	}
+	rpIdHash := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(b[:32], rpIdHash[:]) {
+		return nil, fmt.Errorf("%w: relying party mismatch", ErrInvalid)
+	}
+	ad := &authData{flags: b[32], signCount: binary.BigEndian.Uint32(b[33:37])}
+	if ad.flags&flagUserPresent == 0 || ad.flags&flagUserVerified == 0 {
+		return nil, fmt.Errorf("%w: user not present or not verified", ErrInvalid)
+	}
+	if ad.flags&flagAttested == 0 {
+		return ad, nil
+	}
+	rest := b[37:]
+	if len(rest) < 18 {
+		return nil, errAuthDataShort
+	}
+	ad.aaguid = append([]byte(nil), rest[:16]...)
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLen {
+		return nil, errAuthDataShort
+	}
+	ad.credId = append([]byte(nil), rest[:idLen]...)
+	_, n, err := decodeCbor(rest[idLen:])
+	if err != nil {
+		return nil, err
+	}
+	ad.publicKey = append([]byte(nil), rest[idLen:idLen+n]...)
+	return ad, nil
+}
@@ -0,0 +1,191 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+)
+
+var testConfig = &Config{RPID: "bank.example", RPName: "Bank", Origins: []string{"https://bank.example"}}
+
+// encodeCbor encodes the few types the tests need, map keys are sorted for a stable output.
+func encodeCbor(v interface{}) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 256:
+			return []byte{major<<5 | 24, byte(n)}
+		default:
+			b := []byte{major<<5 | 25, 0, 0}
+			binary.BigEndian.PutUint16(b[1:], uint16(n))
+			return b
+		}
+	}
+	switch x := v.(type) {
+	case int:
+		if x < 0 {
+			return head(1, uint64(-1-x))
+		}
+		return head(0, uint64(x))
+	case []byte:
+		return append(head(2, uint64(len(x))), x...)
+	case string:
+		return append(head(3, uint64(len(x))), x...)
+	case map[interface{}]interface{}:
+		keys := make([]interface{}, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(encodeCbor(keys[i])) < string(encodeCbor(keys[j])) })
+		b := head(5, uint64(len(x)))
+		for _, k := range keys {
+			b = append(b, encodeCbor(k)...)
+			b = append(b, encodeCbor(x[k])...)
+		}
+		return b
+	}
+	panic("unsupported type")
+}
+
+type authenticator struct {
+	key    *ecdsa.PrivateKey
+	credId []byte
+	count  uint32
+}
+
+func newAuthenticator(t *testing.T) *authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &authenticator{key: key, credId: []byte("credential-1")}
+}
+
+func (a *authenticator) authData(rpId string, flags byte, attested bool) []byte {
+	h := sha256.Sum256([]byte(rpId))
+	b := append(h[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[33:], a.count)
+	if attested {
+		b = append(b, make([]byte, 16)...)
+		b = append(b, byte(len(a.credId)>>8), byte(len(a.credId)))
+		b = append(b, a.credId...)
+		b = append(b, encodeCbor(map[interface{}]interface{}{
+			1: 2, 3: -7, -1: 1,
+			-2: a.key.X.FillBytes(make([]byte, 32)),
+			-3: a.key.Y.FillBytes(make([]byte, 32)),
+		})...)
+	}
+	return b
+}
+
+func clientDataJSON(typ string, challenge []byte, origin string) []byte {
+	b, _ := json.Marshal(clientData{Type: typ, Challenge: base64.RawURLEncoding.EncodeToString(challenge), Origin: origin})
+	return b
+}
+
+func (a *authenticator) register(challenge []byte, origin string) *RegistrationResponse {
+	res := &RegistrationResponse{ID: base64.RawURLEncoding.EncodeToString(a.credId), RawID: a.credId, Type: "public-key"}
+	res.Response.ClientDataJSON = clientDataJSON("webauthn.create", challenge, origin)
+	res.Response.AttestationObject = encodeCbor(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": a.authData(testConfig.RPID, flagUserPresent|flagUserVerified|flagAttested, true),
+	})
+	return res
+}
+
+func (a *authenticator) assert(t *testing.T, challenge []byte) *AssertionResponse {
+	a.count++
+	res := &AssertionResponse{ID: base64.RawURLEncoding.EncodeToString(a.credId), RawID: a.credId, Type: "public-key"}
+	res.Response.ClientDataJSON = clientDataJSON("webauthn.get", challenge, testConfig.Origins[0])
+	res.Response.AuthenticatorData = a.authData(testConfig.RPID, flagUserPresent|flagUserVerified, false)
+	hash := sha256.Sum256(res.Response.ClientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), res.Response.AuthenticatorData...), hash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Response.Signature = sig
+	return res
+}
+
+func TestCeremonies(t *testing.T) {
+	a := newAuthenticator(t)
+	challenge, _ := NewChallenge()
+	cred, err := testConfig.VerifyRegistration(challenge, a.register(challenge, testConfig.Origins[0]))
+	if err != nil {
+		t.Fatalf("VerifyRegistration() error = %v", err)
+	}
+	if string(cred.ID) != string(a.credId) {
+		t.Fatalf("VerifyRegistration() id = %s", cred.ID)
+	}
+
+	challenge, _ = NewChallenge()
+	count, err := testConfig.VerifyAssertion(challenge, cred, a.assert(t, challenge))
+	if err != nil {
+		t.Fatalf("VerifyAssertion() error = %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("VerifyAssertion() count = %d, want 1", count)
+	}
+	cred.SignCount = count
+
+	other, _ := NewChallenge()
+	if _, err := testConfig.VerifyAssertion(other, cred, a.assert(t, challenge)); !errors.Is(err, ErrInvalid) {
+		t.Errorf("VerifyAssertion() of another challenge error = %v", err)
+	}
+	res := a.assert(t, challenge)
+	res.Response.Signature[len(res.Response.Signature)-1] ^= 1
+	if _, err := testConfig.VerifyAssertion(challenge, cred, res); !errors.Is(err, ErrInvalid) {
+		t.Errorf("VerifyAssertion() of a broken signature error = %v", err)
+	}
+	a.count = 0
+	if _, err := testConfig.VerifyAssertion(challenge, cred, a.assert(t, challenge)); !errors.Is(err, ErrSignCount) {
+		t.Errorf("VerifyAssertion() of a replayed counter error = %v", err)
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	a := newAuthenticator(t)
+	challenge, _ := NewChallenge()
+	if _, err := testConfig.VerifyRegistration(challenge, a.register(challenge, "https://evil.example")); !errors.Is(err, ErrInvalid) {
+		t.Errorf("VerifyRegistration() of a foreign origin error = %v", err)
+	}
+	other, _ := NewChallenge()
+	if _, err := testConfig.VerifyRegistration(other, a.register(challenge, testConfig.Origins[0])); !errors.Is(err, ErrInvalid) {
+		t.Errorf("VerifyRegistration() of another challenge error = %v", err)
+	}
+	res := a.register(challenge, testConfig.Origins[0])
+	res.RawID = []byte("another")
+	if _, err := testConfig.VerifyRegistration(challenge, res); !errors.Is(err, ErrInvalid) {
+		t.Errorf("VerifyRegistration() of another credential id error = %v", err)
+	}
+	foreign := &Config{RPID: "evil.example", Origins: testConfig.Origins}
+	if _, err := foreign.VerifyRegistration(challenge, a.register(challenge, testConfig.Origins[0])); !errors.Is(err, ErrInvalid) {
+		t.Errorf("VerifyRegistration() for another relying party error = %v", err)
+	}
+}
+
+func TestDecodeCbor(t *testing.T) {
+	for _, b := range [][]byte{{}, {0x5a, 0xff, 0xff, 0xff, 0xff}, {0x9f}, {0xa1, 0x40, 0x01}} {
+		if _, _, err := decodeCbor(b); err == nil {
+			t.Errorf("decodeCbor(%x) accepted malformed input", b)
+		}
+	}
+	v, n, err := decodeCbor(encodeCbor(map[interface{}]interface{}{1: -7, "a": []byte{1}}))
+	if err != nil || n != 7 {
+		t.Fatalf("decodeCbor() = %v, %d, %v", v, n, err)
+	}
+	m := v.(map[interface{}]interface{})
+	if m[int64(1)] != int64(-7) {
+		t.Errorf("decodeCbor() map = %v", m)
+	}
+}
@@ -3,10 +3,12 @@ package repository_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 
 	"github.com/9ssi7/bank/internal/domain/account"
 	"github.com/9ssi7/bank/internal/repository"
+	"github.com/9ssi7/bank/pkg/iban"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -21,6 +23,7 @@ func testAccountRepo(ctx context.Context, db *sql.DB, trc trace.Tracer, t *testi
 			Name:     "test",
 			Owner:    "test 0",
 			Currency: "TRY",
+			Iban:     iban.New(),
 		})
 		err := repo.Save(ctx, trc, account.SaveOpts{Acount: acc})
 		if err != nil {
@@ -38,6 +41,7 @@ func testAccountRepo(ctx context.Context, db *sql.DB, trc trace.Tracer, t *testi
 			Name:     "test",
 			Owner:    "test 0",
 			Currency: "TRY",
+			Iban:     iban.New(),
 		})
 		err := repo.Save(ctx, trc, account.SaveOpts{Acount: acc})
 		if err != nil {
@@ -52,4 +56,104 @@ func testAccountRepo(ctx context.Context, db *sql.DB, trc trace.Tracer, t *testi
 			t.Fatalf("Account owner is not updated")
 		}
 	})
+	t.Run("FindByIdsForUpdate", func(t *testing.T) {
+		ids := make([]uuid.UUID, 0, 2)
+		for i := 0; i < 2; i++ {
+			acc := account.New(account.Config{
+				UserId:   uuid.New(),
+				Name:     "test",
+				Owner:    "test 0",
+				Currency: "TRY",
+				Iban:     iban.New(),
+			})
+			if err := repo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
+				t.Fatalf("Could not save account: %s", err)
+			}
+			ids = append(ids, acc.ID)
+		}
+		accounts, err := repo.FindByIdsForUpdate(ctx, trc, account.FindByIdsForUpdateOpts{IDs: ids})
+		if err != nil {
+			t.Fatalf("Could not find accounts: %s", err)
+		}
+		if len(accounts) != 2 {
+			t.Fatalf("Found %d accounts, want 2", len(accounts))
+		}
+		if accounts[0].ID.String() > accounts[1].ID.String() {
+			t.Fatalf("Accounts are not ordered by id")
+		}
+	})
+	t.Run("VersionConflict", func(t *testing.T) {
+		acc := account.New(account.Config{
+			UserId:   uuid.New(),
+			Name:     "test",
+			Owner:    "test 0",
+			Currency: "TRY",
+			Iban:     iban.New(),
+		})
+		if err := repo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
+			t.Fatalf("Could not save account: %s", err)
+		}
+		stale := *acc
+		acc.Owner = "test 1"
+		if err := repo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
+			t.Fatalf("Could not update account: %s", err)
+		}
+		stale.Owner = "test 2"
+		err := repo.Save(ctx, trc, account.SaveOpts{Acount: &stale})
+		if !errors.Is(err, account.ErrVersionConflict) {
+			t.Fatalf("Save() error = %v, want version conflict", err)
+		}
+	})
+	t.Run("FindByUserIdAndIban", func(t *testing.T) {
+		acc := account.New(account.Config{
+			UserId:   uuid.New(),
+			Name:     "test",
+			Owner:    "test 0",
+			Currency: "TRY",
+			Iban:     iban.New(),
+		})
+		if err := repo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
+			t.Fatalf("Could not save account: %s", err)
+		}
+		found, err := repo.FindByUserIdAndIban(ctx, trc, account.FindByUserIdAndIbanOpts{UserId: acc.UserId, Iban: acc.Iban})
+		if err != nil {
+			t.Fatalf("Could not find account: %s", err)
+		}
+		if found.ID != acc.ID {
+			t.Fatalf("Found account %s, want %s", found.ID, acc.ID)
+		}
+		other, err := repo.FindByUserIdAndIban(ctx, trc, account.FindByUserIdAndIbanOpts{UserId: uuid.New(), Iban: acc.Iban})
+		if err != nil {
+			t.Fatalf("Could not query account: %s", err)
+		}
+		if other.ID != uuid.Nil {
+			t.Fatalf("Found account of another user")
+		}
+	})
+	t.Run("IbanTaken", func(t *testing.T) {
+		acc := account.New(account.Config{
+			UserId:   uuid.New(),
+			Name:     "test",
+			Owner:    "test 0",
+			Currency: "TRY",
+			Iban:     iban.New(),
+		})
+		if err := repo.Save(ctx, trc, account.SaveOpts{Acount: acc}); err != nil {
+			t.Fatalf("Could not save account: %s", err)
+		}
+		twin := account.New(account.Config{
+			UserId:   uuid.New(),
+			Name:     "test",
+			Owner:    "test 1",
+			Currency: "TRY",
+			Iban:     acc.Iban,
+		})
+		err := repo.Save(ctx, trc, account.SaveOpts{Acount: twin})
+		if !errors.Is(err, account.ErrIbanTaken) {
+			t.Fatalf("Save() error = %v, want iban taken", err)
+		}
+		if twin.ID != uuid.Nil {
+			t.Fatalf("Account id is kept after a failed insert")
+		}
+	})
 }
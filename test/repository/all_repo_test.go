@@ -4,12 +4,13 @@ import (
 	"context"
 	"testing"
 
+	"github.com/9ssi7/bank/test/sqltest"
 	"github.com/9ssi7/bank/test/tracertest"
 )
 
 func TestAllRepositories(t *testing.T) {
 	ctx := context.Background()
-	db, cancel := createSqlTesting(t)
+	db, cancel := sqltest.CreateSqlTesting(t)
 	defer cancel()
 
 	tracer := tracertest.CreateTracerTesting()
@@ -21,4 +22,32 @@ func TestAllRepositories(t *testing.T) {
 	t.Run("TransactionRepo", func(t *testing.T) {
 		testTransactionRepo(ctx, db, tracer, t)
 	})
+
+	t.Run("LedgerRepo", func(t *testing.T) {
+		testLedgerRepo(ctx, db, tracer, t)
+	})
+
+	t.Run("OutboxRepo", func(t *testing.T) {
+		testOutboxRepo(ctx, db, tracer, t)
+	})
+
+	t.Run("FxRepo", func(t *testing.T) {
+		testFxRepo(ctx, db, tracer, t)
+	})
+
+	t.Run("ScheduledTransferRepo", func(t *testing.T) {
+		testScheduledTransferRepo(ctx, db, tracer, t)
+	})
+
+	t.Run("HoldRepo", func(t *testing.T) {
+		testHoldRepo(ctx, db, tracer, t)
+	})
+
+	t.Run("TotpRepo", func(t *testing.T) {
+		testTotpRepo(ctx, db, tracer, t)
+	})
+
+	t.Run("PasskeyRepo", func(t *testing.T) {
+		testPasskeyRepo(ctx, db, tracer, t)
+	})
 }
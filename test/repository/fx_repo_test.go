@@ -0,0 +1,32 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/9ssi7/bank/internal/domain/fx"
+	"github.com/9ssi7/bank/internal/repository"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func testFxRepo(ctx context.Context, db *sql.DB, trc trace.Tracer, t *testing.T) {
+	repo := repository.NewFxSqlRepo(db)
+
+	t.Run("Save", func(t *testing.T) {
+		rate := fx.New(fx.Config{
+			Base:   "EUR",
+			Quote:  "USD",
+			Rate:   decimal.RequireFromString("1.0842"),
+			Source: "test",
+		})
+		if err := repo.Save(ctx, trc, fx.SaveOpts{Rate: rate}); err != nil {
+			t.Fatalf("Could not save rate: %s", err)
+		}
+		if rate.ID == uuid.Nil {
+			t.Fatalf("Rate id is empty")
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/account"
+	"github.com/9ssi7/bank/internal/repository"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func testHoldRepo(ctx context.Context, db *sql.DB, trc trace.Tracer, t *testing.T) {
+	repo := repository.NewHoldSqlRepo(db)
+	newHold := func(accountId uuid.UUID, amount int64, expiresAt time.Time) *account.Hold {
+		return account.NewHold(account.HoldConfig{
+			AccountId:   accountId,
+			Amount:      decimal.NewFromInt(amount),
+			Fee:         decimal.NewFromInt(1),
+			Currency:    "TRY",
+			Description: "Hotel reservation",
+			ExpiresAt:   expiresAt,
+		})
+	}
+
+	t.Run("SaveAndFind", func(t *testing.T) {
+		h := newHold(uuid.New(), 100, time.Now().Add(time.Hour))
+		if err := repo.Save(ctx, trc, account.HoldSaveOpts{Hold: h}); err != nil {
+			t.Fatalf("Could not save hold: %s", err)
+		}
+		found, err := repo.FindByAccountIdAndId(ctx, trc, account.HoldFindByAccountIdAndIdOpts{AccountId: h.AccountId, ID: h.ID})
+		if err != nil {
+			t.Fatalf("Could not find hold: %s", err)
+		}
+		if !found.Amount.Equal(h.Amount) || !found.IsActive() {
+			t.Fatalf("Found hold does not match the saved one")
+		}
+		if _, err := repo.FindByAccountIdAndId(ctx, trc, account.HoldFindByAccountIdAndIdOpts{AccountId: uuid.New(), ID: h.ID}); err == nil {
+			t.Fatalf("Hold found for another account")
+		}
+	})
+
+	t.Run("Reserved", func(t *testing.T) {
+		accountId := uuid.New()
+		active := newHold(accountId, 100, time.Now().Add(time.Hour))
+		voided := newHold(accountId, 50, time.Now().Add(time.Hour))
+		voided.Void()
+		for _, h := range []*account.Hold{active, voided} {
+			if err := repo.Save(ctx, trc, account.HoldSaveOpts{Hold: h}); err != nil {
+				t.Fatalf("Could not save hold: %s", err)
+			}
+		}
+		reserved, err := repo.Reserved(ctx, trc, account.HoldReservedOpts{AccountId: accountId})
+		if err != nil {
+			t.Fatalf("Could not sum holds: %s", err)
+		}
+		if !reserved.Equal(decimal.NewFromInt(101)) {
+			t.Fatalf("Reserved %s, want 101", reserved)
+		}
+	})
+
+	t.Run("ListExpired", func(t *testing.T) {
+		accountId := uuid.New()
+		expired := newHold(accountId, 100, time.Now().Add(-time.Minute))
+		pending := newHold(accountId, 100, time.Now().Add(time.Hour))
+		for _, h := range []*account.Hold{expired, pending} {
+			if err := repo.Save(ctx, trc, account.HoldSaveOpts{Hold: h}); err != nil {
+				t.Fatalf("Could not save hold: %s", err)
+			}
+		}
+		holds, err := repo.ListExpired(ctx, trc, account.HoldListExpiredOpts{Now: time.Now(), Limit: 100})
+		if err != nil {
+			t.Fatalf("Could not list expired holds: %s", err)
+		}
+		found := false
+		for _, h := range holds {
+			if h.ID == pending.ID {
+				t.Fatalf("Pending hold listed as expired")
+			}
+			found = found || h.ID == expired.ID
+		}
+		if !found {
+			t.Fatalf("Expired hold not listed")
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/9ssi7/bank/internal/domain/account"
+	"github.com/9ssi7/bank/internal/repository"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func testLedgerRepo(ctx context.Context, db *sql.DB, trc trace.Tracer, t *testing.T) {
+	repo := repository.NewLedgerSqlRepo(db)
+
+	t.Run("Save", func(t *testing.T) {
+		accountId := uuid.New()
+		entry := account.NewJournalEntry(account.JournalEntryConfig{
+			TransactionId: uuid.New(),
+			Description:   "test",
+		})
+		entry.Debit(account.LedgerCashAccountId, decimal.NewFromInt(100), "TRY")
+		entry.Credit(accountId, decimal.NewFromInt(100), "TRY")
+		err := repo.Save(ctx, trc, account.LedgerSaveOpts{Entry: entry})
+		if err != nil {
+			t.Fatalf("Could not save journal entry: %s", err)
+		}
+		if entry.ID == uuid.Nil {
+			t.Fatalf("Journal entry id is empty")
+		}
+	})
+
+	t.Run("Balance", func(t *testing.T) {
+		accountId := uuid.New()
+		deposit := account.NewJournalEntry(account.JournalEntryConfig{TransactionId: uuid.New(), Description: "deposit"})
+		deposit.Debit(account.LedgerCashAccountId, decimal.NewFromInt(100), "TRY")
+		deposit.Credit(accountId, decimal.NewFromInt(100), "TRY")
+		withdrawal := account.NewJournalEntry(account.JournalEntryConfig{TransactionId: uuid.New(), Description: "withdrawal"})
+		withdrawal.Debit(accountId, decimal.NewFromInt(40), "TRY")
+		withdrawal.Credit(account.LedgerCashAccountId, decimal.NewFromInt(40), "TRY")
+		for _, e := range []*account.JournalEntry{deposit, withdrawal} {
+			if err := repo.Save(ctx, trc, account.LedgerSaveOpts{Entry: e}); err != nil {
+				t.Fatalf("Could not save journal entry: %s", err)
+			}
+		}
+		balance, err := repo.Balance(ctx, trc, account.LedgerBalanceOpts{AccountId: accountId})
+		if err != nil {
+			t.Fatalf("Could not get balance: %s", err)
+		}
+		if !balance.Equal(decimal.NewFromInt(60)) {
+			t.Fatalf("Balance = %s, want 60", balance)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/outbox"
+	"github.com/9ssi7/bank/internal/repository"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func testOutboxRepo(ctx context.Context, db *sql.DB, trc trace.Tracer, t *testing.T) {
+	repo := repository.NewOutboxSqlRepo(db)
+
+	t.Run("Save", func(t *testing.T) {
+		m := outbox.New(outbox.Config{Subject: "test", Payload: []byte(`{"test":true}`)})
+		if err := repo.Save(ctx, trc, outbox.SaveOpts{Message: m}); err != nil {
+			t.Fatalf("Could not save message: %s", err)
+		}
+		if m.ID == uuid.Nil {
+			t.Fatalf("Message id is empty")
+		}
+	})
+
+	t.Run("Claim", func(t *testing.T) {
+		failed := outbox.New(outbox.Config{Subject: "test", Payload: []byte(`{}`)})
+		sent := outbox.New(outbox.Config{Subject: "test", Payload: []byte(`{}`)})
+		exhausted := outbox.New(outbox.Config{Subject: "test", Payload: []byte(`{}`)})
+		for _, m := range []*outbox.Message{failed, sent, exhausted} {
+			if err := repo.Save(ctx, trc, outbox.SaveOpts{Message: m}); err != nil {
+				t.Fatalf("Could not save message: %s", err)
+			}
+		}
+		failed.Fail(errors.New("nats down"), 10)
+		sent.MarkSent()
+		exhausted.Fail(errors.New("nats down"), 1)
+		for _, m := range []*outbox.Message{failed, sent, exhausted} {
+			if err := repo.Save(ctx, trc, outbox.SaveOpts{Message: m}); err != nil {
+				t.Fatalf("Could not update message: %s", err)
+			}
+		}
+		opts := outbox.ClaimOpts{Limit: 100, MaxAttempts: 10, ClaimFor: time.Minute}
+		messages, err := repo.Claim(ctx, trc, opts)
+		if err != nil {
+			t.Fatalf("Could not claim pending messages: %s", err)
+		}
+		foundFailed := false
+		for _, m := range messages {
+			if m.ID == sent.ID {
+				t.Fatalf("Sent message is still pending")
+			}
+			if m.ID == exhausted.ID {
+				t.Fatalf("Exhausted message is still pending")
+			}
+			if m.ID == failed.ID {
+				foundFailed = m.Attempts == 1
+			}
+		}
+		if !foundFailed {
+			t.Fatalf("Failed message is not pending with one attempt")
+		}
+		again, err := repo.Claim(ctx, trc, opts)
+		if err != nil {
+			t.Fatalf("Could not claim pending messages: %s", err)
+		}
+		for _, m := range again {
+			if m.ID == failed.ID {
+				t.Fatalf("Claimed message was claimed again")
+			}
+		}
+	})
+
+	t.Run("Renew", func(t *testing.T) {
+		m := outbox.New(outbox.Config{Subject: "test", Payload: []byte(`{}`)})
+		if err := repo.Save(ctx, trc, outbox.SaveOpts{Message: m}); err != nil {
+			t.Fatalf("Could not save message: %s", err)
+		}
+		claim := func(d time.Duration) *outbox.Message {
+			messages, err := repo.Claim(ctx, trc, outbox.ClaimOpts{Limit: 100, MaxAttempts: 10, ClaimFor: d})
+			if err != nil {
+				t.Fatalf("Could not claim pending messages: %s", err)
+			}
+			for _, c := range messages {
+				if c.ID == m.ID {
+					return c
+				}
+			}
+			t.Fatalf("Message was not claimed")
+			return nil
+		}
+		first := claim(time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		held, err := repo.Renew(ctx, trc, outbox.RenewOpts{Message: first, ClaimFor: time.Minute})
+		if err != nil {
+			t.Fatalf("Could not renew claim: %s", err)
+		}
+		if !held {
+			t.Fatalf("Lapsed claim no other relay took was not renewed")
+		}
+		if _, err := repo.Renew(ctx, trc, outbox.RenewOpts{Message: first, ClaimFor: time.Millisecond}); err != nil {
+			t.Fatalf("Could not renew claim: %s", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		second := claim(time.Minute)
+		held, err = repo.Renew(ctx, trc, outbox.RenewOpts{Message: first, ClaimFor: time.Minute})
+		if err != nil {
+			t.Fatalf("Could not renew claim: %s", err)
+		}
+		if held {
+			t.Fatalf("Claim taken by another relay was renewed")
+		}
+		held, err = repo.Renew(ctx, trc, outbox.RenewOpts{Message: second, ClaimFor: time.Minute})
+		if err != nil {
+			t.Fatalf("Could not renew claim: %s", err)
+		}
+		if !held {
+			t.Fatalf("Claim of the relay holding the message was not renewed")
+		}
+		second.MarkSent()
+		if err := repo.Save(ctx, trc, outbox.SaveOpts{Message: second}); err != nil {
+			t.Fatalf("Could not update message: %s", err)
+		}
+		held, err = repo.Renew(ctx, trc, outbox.RenewOpts{Message: second, ClaimFor: time.Minute})
+		if err != nil {
+			t.Fatalf("Could not renew claim: %s", err)
+		}
+		if held {
+			t.Fatalf("Claim of a sent message was renewed")
+		}
+	})
+}
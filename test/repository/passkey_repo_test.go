@@ -0,0 +1,79 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/auth"
+	"github.com/9ssi7/bank/internal/repository"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func testPasskeyRepo(ctx context.Context, db *sql.DB, trc trace.Tracer, t *testing.T) {
+	repo := repository.NewPasskeySqlRepo(db)
+	newPasskey := func(userId uuid.UUID) *auth.Passkey {
+		return auth.NewPasskey(auth.PasskeyConfig{
+			UserId:       userId,
+			Name:         "MacBook",
+			CredentialId: []byte(uuid.NewString()),
+			PublicKey:    []byte{0xa1, 0x01, 0x02},
+		})
+	}
+
+	t.Run("SaveAndFind", func(t *testing.T) {
+		p := newPasskey(uuid.New())
+		if err := repo.Save(ctx, trc, auth.PasskeySaveOpts{Passkey: p}); err != nil {
+			t.Fatalf("Could not save passkey: %s", err)
+		}
+		p.Use(7, time.Now())
+		if err := repo.Save(ctx, trc, auth.PasskeySaveOpts{Passkey: p}); err != nil {
+			t.Fatalf("Could not update passkey: %s", err)
+		}
+		found, err := repo.FindByCredentialId(ctx, trc, auth.PasskeyFindByCredentialIdOpts{CredentialId: p.CredentialId})
+		if err != nil {
+			t.Fatalf("Could not find passkey: %s", err)
+		}
+		if found.ID != p.ID || found.SignCount != 7 || found.LastUsedAt == nil {
+			t.Fatalf("Found passkey does not match the saved one")
+		}
+		missing, err := repo.FindByCredentialId(ctx, trc, auth.PasskeyFindByCredentialIdOpts{CredentialId: []byte("missing")})
+		if err != nil {
+			t.Fatalf("Could not find passkey: %s", err)
+		}
+		if missing.ID != uuid.Nil {
+			t.Fatalf("Passkey found for an unknown credential")
+		}
+	})
+
+	t.Run("ListByUserId", func(t *testing.T) {
+		userId := uuid.New()
+		for i := 0; i < 2; i++ {
+			if err := repo.Save(ctx, trc, auth.PasskeySaveOpts{Passkey: newPasskey(userId)}); err != nil {
+				t.Fatalf("Could not save passkey: %s", err)
+			}
+		}
+		passkeys, err := repo.ListByUserId(ctx, trc, auth.PasskeyListByUserIdOpts{UserId: userId})
+		if err != nil {
+			t.Fatalf("Could not list passkeys: %s", err)
+		}
+		if len(passkeys) != 2 {
+			t.Fatalf("Listed %d passkeys, want 2", len(passkeys))
+		}
+	})
+
+	t.Run("CredentialTaken", func(t *testing.T) {
+		p := newPasskey(uuid.New())
+		if err := repo.Save(ctx, trc, auth.PasskeySaveOpts{Passkey: p}); err != nil {
+			t.Fatalf("Could not save passkey: %s", err)
+		}
+		dup := newPasskey(uuid.New())
+		dup.CredentialId = p.CredentialId
+		if err := repo.Save(ctx, trc, auth.PasskeySaveOpts{Passkey: dup}); !errors.Is(err, auth.ErrPasskeyTaken) {
+			t.Fatalf("Save() error = %v, want passkey taken", err)
+		}
+	})
+}
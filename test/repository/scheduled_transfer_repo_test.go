@@ -0,0 +1,100 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/schedule"
+	"github.com/9ssi7/bank/internal/repository"
+	"github.com/9ssi7/bank/pkg/list"
+	"github.com/9ssi7/bank/pkg/ptr"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func testScheduledTransferRepo(ctx context.Context, db *sql.DB, trc trace.Tracer, t *testing.T) {
+	repo := repository.NewScheduledTransferSqlRepo(db)
+	newTransfer := func(userId uuid.UUID, accountId uuid.UUID, next time.Time) *schedule.Transfer {
+		return schedule.New(schedule.Config{
+			UserId:      userId,
+			AccountId:   accountId,
+			Amount:      decimal.NewFromInt(100),
+			ToIban:      "TR000000000000000000000000",
+			ToOwner:     "John Doe",
+			Description: "Rent",
+			Cron:        "0 9 1 * *",
+			NextRunAt:   next,
+		})
+	}
+
+	t.Run("SaveAndFind", func(t *testing.T) {
+		userId, accountId := uuid.New(), uuid.New()
+		tr := newTransfer(userId, accountId, time.Now().Add(time.Hour))
+		if err := repo.Save(ctx, trc, schedule.SaveOpts{Transfer: tr}); err != nil {
+			t.Fatalf("Could not save scheduled transfer: %s", err)
+		}
+		found, err := repo.FindByAccountIdAndId(ctx, trc, schedule.FindByAccountIdAndIdOpts{UserId: userId, AccountId: accountId, ID: tr.ID})
+		if err != nil {
+			t.Fatalf("Could not find scheduled transfer: %s", err)
+		}
+		if found.Cron != tr.Cron || !found.Amount.Equal(tr.Amount) {
+			t.Fatalf("Found scheduled transfer does not match the saved one")
+		}
+		if _, err := repo.FindByAccountIdAndId(ctx, trc, schedule.FindByAccountIdAndIdOpts{UserId: uuid.New(), AccountId: accountId, ID: tr.ID}); err == nil {
+			t.Fatalf("Scheduled transfer found for another user")
+		}
+		res, err := repo.ListByAccountId(ctx, trc, schedule.ListByAccountIdOpts{UserId: userId, AccountId: accountId, Pagi: &list.PagiRequest{Limit: ptr.Int(10), Page: ptr.Int(1)}})
+		if err != nil {
+			t.Fatalf("Could not list scheduled transfers: %s", err)
+		}
+		if res.Total != 1 {
+			t.Fatalf("Expected 1 scheduled transfer, got %d", res.Total)
+		}
+	})
+
+	t.Run("FindForUpdate", func(t *testing.T) {
+		userId, accountId := uuid.New(), uuid.New()
+		tr := newTransfer(userId, accountId, time.Now().Add(time.Hour))
+		tr.Claim(time.Now().Add(time.Minute))
+		if err := repo.Save(ctx, trc, schedule.SaveOpts{Transfer: tr}); err != nil {
+			t.Fatalf("Could not save scheduled transfer: %s", err)
+		}
+		found, err := repo.FindByAccountIdAndIdForUpdate(ctx, trc, schedule.FindByAccountIdAndIdOpts{UserId: userId, AccountId: accountId, ID: tr.ID})
+		if err != nil {
+			t.Fatalf("Could not find scheduled transfer: %s", err)
+		}
+		if !found.IsClaimed(time.Now()) {
+			t.Fatalf("Found scheduled transfer lost its claim")
+		}
+	})
+
+	t.Run("ListDue", func(t *testing.T) {
+		userId, accountId := uuid.New(), uuid.New()
+		due := newTransfer(userId, accountId, time.Now().Add(-time.Minute))
+		later := newTransfer(userId, accountId, time.Now().Add(time.Hour))
+		cancelled := newTransfer(userId, accountId, time.Now().Add(-time.Minute))
+		cancelled.Cancel()
+		for _, tr := range []*schedule.Transfer{due, later, cancelled} {
+			if err := repo.Save(ctx, trc, schedule.SaveOpts{Transfer: tr}); err != nil {
+				t.Fatalf("Could not save scheduled transfer: %s", err)
+			}
+		}
+		transfers, err := repo.ListDue(ctx, trc, schedule.ListDueOpts{Now: time.Now(), Limit: 100})
+		if err != nil {
+			t.Fatalf("Could not list due transfers: %s", err)
+		}
+		foundDue := false
+		for _, tr := range transfers {
+			if tr.ID == later.ID || tr.ID == cancelled.ID {
+				t.Fatalf("Transfer %s is not due", tr.ID)
+			}
+			foundDue = foundDue || tr.ID == due.ID
+		}
+		if !foundDue {
+			t.Fatalf("Due transfer is not listed")
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/auth"
+	"github.com/9ssi7/bank/internal/repository"
+	"github.com/9ssi7/bank/pkg/seal"
+	"github.com/9ssi7/bank/pkg/totp"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func testTotpRepo(ctx context.Context, db *sql.DB, trc trace.Tracer, t *testing.T) {
+	sealer, err := seal.New([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("Could not create sealer: %s", err)
+	}
+	repo := repository.NewTotpSqlRepo(db, sealer)
+
+	t.Run("SaveAndFind", func(t *testing.T) {
+		secret, _ := totp.GenerateSecret()
+		tp := auth.NewTotp(uuid.New(), secret)
+		if err := repo.Save(ctx, trc, auth.TotpSaveOpts{Totp: tp}); err != nil {
+			t.Fatalf("Could not save totp: %s", err)
+		}
+		tp.LastStep = 42
+		tp.Confirm(time.Now())
+		for i := 0; i < auth.TotpMaxFailures; i++ {
+			tp.Fail(time.Now())
+		}
+		if err := repo.Save(ctx, trc, auth.TotpSaveOpts{Totp: tp}); err != nil {
+			t.Fatalf("Could not update totp: %s", err)
+		}
+		found, err := repo.FindByUserId(ctx, trc, auth.TotpFindByUserIdOpts{UserId: tp.UserId})
+		if err != nil {
+			t.Fatalf("Could not find totp: %s", err)
+		}
+		if found.Secret != secret || found.LastStep != 42 || !found.IsConfirmed() || !found.IsLocked(time.Now()) {
+			t.Fatalf("Found totp does not match the saved one")
+		}
+		var stored sql.NullString
+		var sealed []byte
+		if err := db.QueryRowContext(ctx, "SELECT secret, secret_sealed FROM user_totps WHERE user_id = $1", tp.UserId).Scan(&stored, &sealed); err != nil {
+			t.Fatalf("Could not read stored secret: %s", err)
+		}
+		if stored.Valid || len(sealed) == 0 || strings.Contains(string(sealed), secret) {
+			t.Fatalf("Totp secret is stored in the clear")
+		}
+		missing, err := repo.FindByUserId(ctx, trc, auth.TotpFindByUserIdOpts{UserId: uuid.New()})
+		if err != nil {
+			t.Fatalf("Could not find totp: %s", err)
+		}
+		if missing.UserId != uuid.Nil {
+			t.Fatalf("Totp found for another user")
+		}
+	})
+
+	t.Run("PlainSecret", func(t *testing.T) {
+		secret, _ := totp.GenerateSecret()
+		userId := uuid.New()
+		if _, err := db.ExecContext(ctx, "INSERT INTO user_totps (user_id, secret) VALUES ($1, $2)", userId, secret); err != nil {
+			t.Fatalf("Could not insert plain totp: %s", err)
+		}
+		found, err := repo.FindByUserId(ctx, trc, auth.TotpFindByUserIdOpts{UserId: userId})
+		if err != nil {
+			t.Fatalf("Could not find totp: %s", err)
+		}
+		if found.Secret != secret {
+			t.Fatalf("Plain secret was not read")
+		}
+		if err := repo.Save(ctx, trc, auth.TotpSaveOpts{Totp: found}); err != nil {
+			t.Fatalf("Could not update totp: %s", err)
+		}
+		var stored sql.NullString
+		if err := db.QueryRowContext(ctx, "SELECT secret FROM user_totps WHERE user_id = $1", userId).Scan(&stored); err != nil {
+			t.Fatalf("Could not read stored secret: %s", err)
+		}
+		if stored.Valid {
+			t.Fatalf("Plain secret was kept after saving")
+		}
+	})
+
+	t.Run("RecoveryCodes", func(t *testing.T) {
+		userId := uuid.New()
+		codes, err := auth.NewRecoveryCodes()
+		if err != nil {
+			t.Fatalf("Could not generate recovery codes: %s", err)
+		}
+		hashes := make([]string, len(codes))
+		for i, c := range codes {
+			hashes[i] = auth.HashRecoveryCode(c)
+		}
+		if err := repo.SaveRecoveryCodes(ctx, trc, auth.TotpSaveRecoveryCodesOpts{UserId: userId, Hashes: hashes}); err != nil {
+			t.Fatalf("Could not save recovery codes: %s", err)
+		}
+		opts := auth.TotpUseRecoveryCodeOpts{UserId: userId, Hash: hashes[0]}
+		if used, err := repo.UseRecoveryCode(ctx, trc, opts); err != nil || !used {
+			t.Fatalf("UseRecoveryCode() = %v, %v, want true", used, err)
+		}
+		if used, err := repo.UseRecoveryCode(ctx, trc, opts); err != nil || used {
+			t.Fatalf("UseRecoveryCode() of a used code = %v, %v, want false", used, err)
+		}
+		if err := repo.Delete(ctx, trc, auth.TotpDeleteOpts{UserId: userId}); err != nil {
+			t.Fatalf("Could not delete totp: %s", err)
+		}
+		if used, err := repo.UseRecoveryCode(ctx, trc, auth.TotpUseRecoveryCodeOpts{UserId: userId, Hash: hashes[1]}); err != nil || used {
+			t.Fatalf("UseRecoveryCode() after delete = %v, %v, want false", used, err)
+		}
+	})
+}
@@ -3,7 +3,9 @@ package repository_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/9ssi7/bank/internal/domain/account"
 	"github.com/9ssi7/bank/internal/repository"
@@ -61,4 +63,174 @@ func testTransactionRepo(ctx context.Context, db *sql.DB, trc trace.Tracer, t *t
 			t.Fatalf("Could not filter transaction: %s", err)
 		}
 	})
+	t.Run("LinkedFee", func(t *testing.T) {
+		accountId := uuid.New()
+		tx := account.NewTransaction(account.TransactionConfig{
+			SenderId:    accountId,
+			ReceiverId:  accountId,
+			Amount:      decimal.NewFromFloat(100),
+			Description: "test",
+			Kind:        account.TransactionKindWithdrawal,
+		})
+		if err := repo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx}); err != nil {
+			t.Fatalf("Could not save transaction: %s", err)
+		}
+		feeTx := account.NewTransaction(account.TransactionConfig{
+			SenderId:    accountId,
+			ReceiverId:  accountId,
+			Amount:      decimal.NewFromFloat(1),
+			Description: "Process Fee",
+			Kind:        account.TransactionKindFee,
+			ParentId:    &tx.ID,
+		})
+		if err := repo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: feeTx}); err != nil {
+			t.Fatalf("Could not save fee transaction: %s", err)
+		}
+		res, err := repo.Filter(ctx, trc, account.TransactionFilterOpts{
+			AccountId: accountId,
+			Pagi:      &list.PagiRequest{Limit: ptr.Int(10), Page: ptr.Int(1)},
+			Filters:   &account.TransactionFilters{Kind: account.TransactionKindFee.String()},
+		})
+		if err != nil {
+			t.Fatalf("Could not filter transaction: %s", err)
+		}
+		for _, e := range res.List {
+			if e.ID == feeTx.ID && (e.ParentId == nil || *e.ParentId != tx.ID) {
+				t.Fatalf("Fee transaction is not linked to %s", tx.ID)
+			}
+		}
+	})
+	t.Run("Stream", func(t *testing.T) {
+		accountId := uuid.New()
+		for i := 0; i < 3; i++ {
+			tx := account.NewTransaction(account.TransactionConfig{
+				SenderId:    accountId,
+				ReceiverId:  accountId,
+				Amount:      decimal.NewFromInt(int64(i + 1)),
+				Description: "test",
+				Kind:        account.TransactionKindDeposit,
+			})
+			if err := repo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx}); err != nil {
+				t.Fatalf("Could not save transaction: %s", err)
+			}
+		}
+		streamed := 0
+		err := repo.Stream(ctx, trc, account.TransactionStreamOpts{
+			AccountId: accountId,
+			From:      time.Now().Add(-time.Hour),
+			To:        time.Now().Add(time.Hour),
+		}, func(tx *account.Transaction) error {
+			streamed++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Could not stream transactions: %s", err)
+		}
+		if streamed != 3 {
+			t.Fatalf("Expected 3 transactions, got %d", streamed)
+		}
+	})
+	t.Run("Reversal", func(t *testing.T) {
+		tx := account.NewTransaction(account.TransactionConfig{
+			SenderId:    uuid.New(),
+			ReceiverId:  uuid.New(),
+			Amount:      decimal.NewFromFloat(100),
+			Currency:    "TRY",
+			Description: "test",
+			Kind:        account.TransactionKindTransfer,
+		})
+		if err := repo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx}); err != nil {
+			t.Fatalf("Could not save transaction: %s", err)
+		}
+		feeTx := account.NewTransaction(account.TransactionConfig{
+			SenderId:    tx.SenderId,
+			ReceiverId:  tx.SenderId,
+			Amount:      decimal.NewFromFloat(1),
+			Currency:    "TRY",
+			Description: "Process Fee",
+			Kind:        account.TransactionKindFee,
+			ParentId:    &tx.ID,
+		})
+		if err := repo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: feeTx}); err != nil {
+			t.Fatalf("Could not save fee transaction: %s", err)
+		}
+		children, err := repo.ListByParentId(ctx, trc, account.TransactionListByParentIdOpts{ParentId: tx.ID})
+		if err != nil {
+			t.Fatalf("Could not list fee transactions: %s", err)
+		}
+		if len(children) != 1 || children[0].ID != feeTx.ID {
+			t.Fatalf("Found %d linked transactions, want the fee", len(children))
+		}
+		reversal := tx.Reverse("reversal")
+		if err := repo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: reversal}); err != nil {
+			t.Fatalf("Could not save reversal: %s", err)
+		}
+		found, err := repo.FindByReferenceId(ctx, trc, account.TransactionFindByReferenceIdOpts{ReferenceId: tx.ID})
+		if err != nil {
+			t.Fatalf("Could not find reversal: %s", err)
+		}
+		if found.ID != reversal.ID || found.SenderId != tx.ReceiverId {
+			t.Fatalf("Found reversal %s, want %s", found.ID, reversal.ID)
+		}
+		err = repo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx.Reverse("again")})
+		if !errors.Is(err, account.ErrAlreadyReversed) {
+			t.Fatalf("Save() error = %v, want already reversed", err)
+		}
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		accountId := uuid.New()
+		now := time.Now().UTC()
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		save := func(kind account.TransactionKind, amount float64, createdAt time.Time) *account.Transaction {
+			tx := account.NewTransaction(account.TransactionConfig{
+				SenderId:    accountId,
+				ReceiverId:  uuid.New(),
+				Amount:      decimal.NewFromFloat(amount),
+				Currency:    "EUR",
+				Description: "test",
+				Kind:        kind,
+			})
+			tx.CreatedAt = createdAt
+			if err := repo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: tx}); err != nil {
+				t.Fatalf("Could not save transaction: %s", err)
+			}
+			return tx
+		}
+		save(account.TransactionKindTransfer, 100, now)
+		save(account.TransactionKindWithdrawal, 50, now)
+		save(account.TransactionKindFee, 1, now)
+		save(account.TransactionKindTransfer, 30, month.Add(-time.Hour))
+		if day.After(month) {
+			save(account.TransactionKindTransfer, 20, day.Add(-time.Minute))
+		}
+		reversed := save(account.TransactionKindTransfer, 70, now)
+		if err := repo.Save(ctx, trc, account.TransactionSaveOpts{Transaction: reversed.Reverse("test")}); err != nil {
+			t.Fatalf("Could not save reversal: %s", err)
+		}
+		holdRepo := repository.NewHoldSqlRepo(db)
+		active := account.NewHold(account.HoldConfig{AccountId: accountId, Amount: decimal.NewFromInt(25), Currency: "EUR", Description: "test", ExpiresAt: now.Add(time.Hour)})
+		voided := account.NewHold(account.HoldConfig{AccountId: accountId, Amount: decimal.NewFromInt(40), Currency: "EUR", Description: "test", ExpiresAt: now.Add(time.Hour)})
+		voided.Void()
+		for _, h := range []*account.Hold{active, voided} {
+			if err := holdRepo.Save(ctx, trc, account.HoldSaveOpts{Hold: h}); err != nil {
+				t.Fatalf("Could not save hold: %s", err)
+			}
+		}
+		usage, err := repo.Usage(ctx, trc, account.TransactionUsageOpts{AccountId: accountId, Currency: "EUR", Day: day, Month: month})
+		if err != nil {
+			t.Fatalf("Could not get usage: %s", err)
+		}
+		if usage.DailyCount != 3 || !usage.DailyAmount.Equal(decimal.NewFromFloat(175)) {
+			t.Fatalf("Daily usage mismatch: %d %s", usage.DailyCount, usage.DailyAmount)
+		}
+		monthly := 3
+		if day.After(month) {
+			monthly = 4
+		}
+		if usage.MonthlyCount != monthly {
+			t.Fatalf("Monthly count mismatch: %d", usage.MonthlyCount)
+		}
+	})
 }
@@ -1,4 +1,4 @@
-package repository_test
+package sqltest
 
 import (
 	"context"
@@ -15,7 +15,8 @@ import (
 
 type CancelFunc func()
 
-func createSqlTesting(t *testing.T) (*sql.DB, CancelFunc) {
+// CreateSqlTesting starts a postgres container with the migrations run, the cancel func stops it.
+func CreateSqlTesting(t *testing.T) (*sql.DB, CancelFunc) {
 	ctx := context.Background()
 	req := testcontainers.ContainerRequest{
 		Image:        "postgres:16.4",
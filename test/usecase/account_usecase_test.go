@@ -0,0 +1,170 @@
+package usecase_test
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/9ssi7/bank/internal/domain/account"
+	"github.com/9ssi7/bank/internal/repository"
+	"github.com/9ssi7/bank/internal/usecase"
+	"github.com/9ssi7/bank/pkg/iban"
+	"github.com/9ssi7/bank/pkg/pain"
+	"github.com/9ssi7/bank/test/sqltest"
+	"github.com/9ssi7/bank/test/tracertest"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func newAccountUseCase(t *testing.T, db *sql.DB) *usecase.AccountUseCase {
+	ibans, err := iban.NewGenerator("TR", "00061")
+	if err != nil {
+		t.Fatalf("Could not create iban generator: %s", err)
+	}
+	return &usecase.AccountUseCase{
+		OutboxRepo:      repository.NewOutboxSqlRepo(db),
+		AccountRepo:     repository.NewAccountSqlRepo(db),
+		TransactionRepo: repository.NewTransactionSqlRepo(db),
+		LedgerRepo:      repository.NewLedgerSqlRepo(db),
+		HoldRepo:        repository.NewHoldSqlRepo(db),
+		UserRepo:        repository.NewUserSqlRepo(db),
+		FxRepo:          repository.NewFxSqlRepo(db),
+		Ibans:           ibans,
+	}
+}
+
+// createAccounts opens n TRY accounts for the user, each credited with 1000.
+func createAccounts(ctx context.Context, t *testing.T, u *usecase.AccountUseCase, userId uuid.UUID, n int) []*account.Account {
+	trc := tracertest.CreateTracerTesting()
+	accs := make([]*account.Account, n)
+	for i := range accs {
+		id, err := u.Create(ctx, trc, usecase.AccountCreateOpts{UserId: userId, Name: "Main", Owner: "John Doe", Currency: "TRY"})
+		if err != nil {
+			t.Fatalf("Could not create account: %s", err)
+		}
+		if err := u.Credit(ctx, trc, usecase.AccountCreditOpts{UserId: userId, AccountId: *id, Amount: "1000"}); err != nil {
+			t.Fatalf("Could not credit account: %s", err)
+		}
+		accs[i], err = u.AccountRepo.FindById(ctx, trc, account.FindByIdOpts{ID: *id})
+		if err != nil {
+			t.Fatalf("Could not find account: %s", err)
+		}
+	}
+	return accs
+}
+
+func TestAccountUseCase_ConcurrentTransfers(t *testing.T) {
+	ctx := context.Background()
+	db, cancel := sqltest.CreateSqlTesting(t)
+	defer cancel()
+	trc := tracertest.CreateTracerTesting()
+
+	u := newAccountUseCase(t, db)
+	userId := uuid.New()
+	accs := createAccounts(ctx, t, u, userId, 2)
+	ids := []uuid.UUID{accs[0].ID, accs[1].ID}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 40; i++ {
+		from, to := accs[i%2], accs[(i+1)%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := u.TransferMoney(ctx, trc, usecase.AccountTransferMoneyOpts{
+				UserId:    userId,
+				AccountId: from.ID,
+				Amount:    "10",
+				ToIban:    to.Iban,
+				ToOwner:   to.Owner,
+				Desc:      "Concurrent transfer",
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Transfer failed: %s", err)
+		}
+	}
+
+	total := decimal.Zero
+	for _, id := range ids {
+		acc, err := u.AccountRepo.FindById(ctx, trc, account.FindByIdOpts{ID: id})
+		if err != nil {
+			t.Fatalf("Could not find account: %s", err)
+		}
+		ledger, err := u.LedgerRepo.Balance(ctx, trc, account.LedgerBalanceOpts{AccountId: id})
+		if err != nil {
+			t.Fatalf("Could not read ledger balance: %s", err)
+		}
+		if !acc.Balance.Equal(ledger) || !acc.AvailableBalance.Equal(ledger) {
+			t.Errorf("Account %s balance = %s, available = %s, want the ledger sum %s", id, acc.Balance, acc.AvailableBalance, ledger)
+		}
+		total = total.Add(ledger)
+	}
+	if !total.Equal(decimal.NewFromInt(2000)) {
+		t.Errorf("Ledger sum of the accounts = %s, want 2000", total)
+	}
+}
+
+func TestAccountUseCase_ConcurrentAtomicBatches(t *testing.T) {
+	ctx := context.Background()
+	db, cancel := sqltest.CreateSqlTesting(t)
+	defer cancel()
+	trc := tracertest.CreateTracerTesting()
+
+	u := newAccountUseCase(t, db)
+	userId := uuid.New()
+	accs := createAccounts(ctx, t, u, userId, 4)
+	payment := func(from, to *account.Account) pain.Payment {
+		return pain.Payment{DebtorIban: from.Iban, CreditorIban: to.Iban, CreditorName: to.Owner, Amount: "10", Remittance: "Batch"}
+	}
+	// the batches cross the same pairs of accounts in opposite order,
+	// locked line by line each would hold the pair the other one waits for
+	batches := []*pain.Batch{
+		{MsgId: "forward", Payments: []pain.Payment{payment(accs[0], accs[1]), payment(accs[2], accs[3])}},
+		{MsgId: "backward", Payments: []pain.Payment{payment(accs[3], accs[2]), payment(accs[1], accs[0])}},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	rejected := make(chan pain.Status, 40)
+	for i := 0; i < 20; i++ {
+		batch := batches[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses, err := u.BulkTransfer(ctx, trc, usecase.AccountBulkTransferOpts{UserId: userId, Batch: batch, Atomic: true})
+			errs <- err
+			for _, s := range statuses {
+				if s.Status != pain.StatusAccepted {
+					rejected <- s
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	close(rejected)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Batch failed: %s", err)
+		}
+	}
+	for s := range rejected {
+		t.Errorf("Payment rejected: %s", s.Reason)
+	}
+	for _, acc := range accs {
+		found, err := u.AccountRepo.FindById(ctx, trc, account.FindByIdOpts{ID: acc.ID})
+		if err != nil {
+			t.Fatalf("Could not find account: %s", err)
+		}
+		if !found.Balance.Equal(decimal.NewFromInt(1000)) {
+			t.Errorf("Account %s balance = %s, want 1000", acc.ID, found.Balance)
+		}
+	}
+}
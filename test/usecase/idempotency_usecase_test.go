@@ -0,0 +1,89 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/idempotency"
+	"github.com/9ssi7/bank/internal/usecase"
+	"github.com/9ssi7/bank/test/tracertest"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// flakyIdempotencyRepo fails the first saves and keeps the ttl a key was last given.
+type flakyIdempotencyRepo struct {
+	failSaves int
+	saves     int
+	saved     *idempotency.Record
+	expireIn  time.Duration
+}
+
+func (r *flakyIdempotencyRepo) Start(ctx context.Context, t trace.Tracer, opts idempotency.StartOpts) (bool, error) {
+	return true, nil
+}
+
+func (r *flakyIdempotencyRepo) Find(ctx context.Context, t trace.Tracer, opts idempotency.FindOpts) (*idempotency.Record, bool, error) {
+	return r.saved, r.saved == nil, nil
+}
+
+func (r *flakyIdempotencyRepo) Save(ctx context.Context, t trace.Tracer, opts idempotency.SaveOpts) error {
+	r.saves++
+	if r.saves <= r.failSaves {
+		return errors.New("keyval down")
+	}
+	r.saved = opts.Record
+	return nil
+}
+
+func (r *flakyIdempotencyRepo) Delete(ctx context.Context, t trace.Tracer, opts idempotency.DeleteOpts) error {
+	return nil
+}
+
+func (r *flakyIdempotencyRepo) Expire(ctx context.Context, t trace.Tracer, opts idempotency.ExpireOpts) error {
+	r.expireIn = opts.In
+	return nil
+}
+
+func TestIdempotencyUseCase_Complete(t *testing.T) {
+	tests := []struct {
+		name       string
+		failSaves  int
+		wantErr    bool
+		wantSaved  bool
+		wantExpire bool
+	}{
+		{"saved", 0, false, true, false},
+		{"saved after a retry", 2, false, true, false},
+		{"not saved expires the key", 10, true, false, true},
+	}
+
+	trc := tracertest.CreateTracerTesting()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &flakyIdempotencyRepo{failSaves: tt.failSaves}
+			u := &usecase.IdempotencyUseCase{Repo: repo}
+			err := u.Complete(context.Background(), trc, usecase.IdempotencyCompleteOpts{
+				UserId:   uuid.New(),
+				Key:      "key",
+				BodyHash: "hash",
+				Status:   201,
+				Body:     []byte(`{}`),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Complete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (repo.saved != nil) != tt.wantSaved {
+				t.Errorf("Complete() saved = %v, want %v", repo.saved != nil, tt.wantSaved)
+			}
+			if expired := repo.expireIn > 0; expired != tt.wantExpire {
+				t.Errorf("Complete() expired the key = %v, want %v", expired, tt.wantExpire)
+			}
+			if tt.wantExpire && repo.expireIn >= 24*time.Hour {
+				t.Errorf("Complete() left the key for %s", repo.expireIn)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package usecase_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/9ssi7/bank/internal/domain/outbox"
+	"github.com/9ssi7/bank/internal/repository"
+	"github.com/9ssi7/bank/internal/usecase"
+	"github.com/9ssi7/bank/test/sqltest"
+	"github.com/9ssi7/bank/test/tracertest"
+)
+
+// takeoverEventSrv lets the claim of the batch lapse while the first message is published
+// and has another relay claim the rest of it.
+type takeoverEventSrv struct {
+	t         *testing.T
+	db        *sql.DB
+	repo      outbox.Repo
+	published []string
+	taken     []*outbox.Message
+}
+
+func (s *takeoverEventSrv) Publish(ctx context.Context, sub string, data interface{}) error {
+	s.published = append(s.published, sub)
+	if len(s.published) > 1 {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, "UPDATE outbox SET claimed_until = $1 WHERE sent_at IS NULL", time.Now().Add(-time.Second)); err != nil {
+		s.t.Fatalf("Could not lapse the claims: %s", err)
+	}
+	var err error
+	s.taken, err = s.repo.Claim(ctx, tracertest.CreateTracerTesting(), outbox.ClaimOpts{Limit: 100, MaxAttempts: 10, ClaimFor: time.Minute})
+	if err != nil {
+		s.t.Fatalf("Could not claim pending messages: %s", err)
+	}
+	return nil
+}
+
+func TestOutboxUseCase_RelayClaimLapsesMidBatch(t *testing.T) {
+	ctx := context.Background()
+	db, cancel := sqltest.CreateSqlTesting(t)
+	defer cancel()
+	trc := tracertest.CreateTracerTesting()
+
+	repo := repository.NewOutboxSqlRepo(db)
+	for _, sub := range []string{"Test.First", "Test.Second"} {
+		m := outbox.New(outbox.Config{Subject: sub, Payload: []byte(`{}`)})
+		if err := repo.Save(ctx, trc, outbox.SaveOpts{Message: m}); err != nil {
+			t.Fatalf("Could not save message: %s", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	srv := &takeoverEventSrv{t: t, db: db, repo: repo}
+	u := &usecase.OutboxUseCase{EventSrv: srv, Repo: repo}
+
+	sent, err := u.Relay(ctx, trc, usecase.OutboxRelayOpts{Limit: 100, MaxAttempts: 10})
+	if err != nil {
+		t.Fatalf("Could not relay messages: %s", err)
+	}
+	if sent != 1 || len(srv.published) != 1 || srv.published[0] != "Test.First" {
+		t.Fatalf("Relay sent %d and published %v, want only Test.First", sent, srv.published)
+	}
+	if len(srv.taken) != 2 {
+		t.Fatalf("Other relay claimed %d messages, want both", len(srv.taken))
+	}
+	for _, m := range srv.taken {
+		held, err := repo.Renew(ctx, trc, outbox.RenewOpts{Message: m, ClaimFor: time.Minute})
+		if err != nil {
+			t.Fatalf("Could not renew claim: %s", err)
+		}
+		if want := m.Subject == "Test.Second"; held != want {
+			t.Errorf("Claim of %s held by the other relay = %v, want %v", m.Subject, held, want)
+		}
+	}
+}